@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/agent"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/recording"
+)
+
+var (
+	agentPrompt     string
+	agentPromptFile string
+	agentWorktree   string
+	agentCheckpoint bool
+	agentRecord     bool
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent <claude|gemini|codex> [-- agent args...]",
+	Short: "Run an AI agent headless inside the rig container",
+	Long: `Runs an AI agent non-interactively inside the rig container.
+
+The container is created or started as with 'rig up', then the agent runs
+with the given prompt in its "skip permissions" mode - the container is the
+sandbox. Output is streamed to the terminal and a transcript is saved under
+.rig/runs/<timestamp>/. rig exits with the agent's exit code.
+
+Arguments after -- are passed through to the agent CLI.
+
+With --record, the output is also saved with its timing as an asciicast
+recording under .rig/recordings/, to play back with 'rig replay'.
+
+Examples:
+  rig agent claude --prompt-file task.md
+  rig agent gemini --prompt "Fix the failing tests"
+  cat task.md | rig agent codex --prompt-file -
+  rig agent claude --worktree feature-x --prompt-file task.md`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runAgent,
+}
+
+func init() {
+	agentCmd.Flags().StringVarP(&agentPrompt, "prompt", "p", "", "Prompt to send to the agent")
+	agentCmd.Flags().StringVarP(&agentPromptFile, "prompt-file", "f", "", "File containing the prompt (- for stdin)")
+	agentCmd.Flags().StringVarP(&agentWorktree, "worktree", "w", "", "Run in the container of a worktree created with 'rig worktree add'")
+	agentCmd.Flags().BoolVar(&agentCheckpoint, "checkpoint", false, "Record a git checkpoint of the workspace first")
+	agentCmd.Flags().BoolVar(&agentRecord, "record", false, "Record the output under .rig/recordings")
+	rootCmd.AddCommand(agentCmd)
+}
+
+func runAgent(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	agentName := args[0]
+	if !agent.IsSupported(agentName) {
+		return fmt.Errorf("unsupported agent: %s (supported: %s)", agentName, strings.Join(agent.Supported(), ", "))
+	}
+
+	prompt, err := readAgentPrompt()
+	if err != nil {
+		return err
+	}
+
+	command, err := agent.Command(agentName, prompt, args[1:])
+	if err != nil {
+		return err
+	}
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	sess, err := startSession(ctx, dockerClient, sessionOptions{
+		worktree:   agentWorktree,
+		checkpoint: agentCheckpoint,
+		purpose:    "rig agent " + agentName,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Prepare transcript directory
+	started := time.Now()
+	runDir := agent.RunDir(sess.cwd, started)
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		return fmt.Errorf("creating run directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, agent.PromptFile), []byte(prompt), 0644); err != nil {
+		return fmt.Errorf("writing prompt: %w", err)
+	}
+	output, err := os.Create(filepath.Join(runDir, agent.OutputFile))
+	if err != nil {
+		return fmt.Errorf("creating output log: %w", err)
+	}
+	defer output.Close()
+
+	stdout := io.MultiWriter(os.Stdout, output)
+	stderr := io.MultiWriter(os.Stderr, output)
+	if agentRecord {
+		rec, path, err := startRecording(sess.cwd, "agent-"+agentName, "rig agent "+agentName, started)
+		if err != nil {
+			return err
+		}
+		defer finishRecording(rec, sess.cwd, path)
+		// The agent has no TTY, so its output has bare newlines
+		stdout = io.MultiWriter(stdout, recording.CRLF(rec))
+		stderr = io.MultiWriter(stderr, recording.CRLF(rec))
+	}
+
+	fmt.Printf("Running %s in container %s...\n", agentName, sess.containerName)
+	exitCode, err := dockerClient.Exec(ctx, sess.containerID, docker.ExecOptions{
+		Cmd:        command,
+		WorkingDir: sess.startDir(),
+		Stdout:     stdout,
+		Stderr:     stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("running agent: %w", err)
+	}
+
+	if err := agent.WriteResult(runDir, agent.Result{
+		Agent:    agentName,
+		Command:  command,
+		Started:  started,
+		Finished: time.Now(),
+		ExitCode: exitCode,
+	}); err != nil {
+		return err
+	}
+
+	relDir, err := filepath.Rel(sess.cwd, runDir)
+	if err != nil {
+		relDir = runDir
+	}
+	fmt.Fprintf(os.Stderr, "Transcript saved to %s\n", relDir)
+
+	if exitCode != 0 {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return &exitError{code: exitCode}
+	}
+	return nil
+}
+
+// readAgentPrompt returns the prompt from --prompt or --prompt-file
+func readAgentPrompt() (string, error) {
+	if agentPrompt != "" && agentPromptFile != "" {
+		return "", fmt.Errorf("--prompt and --prompt-file are mutually exclusive")
+	}
+	if agentPrompt != "" {
+		return agentPrompt, nil
+	}
+	if agentPromptFile == "" {
+		return "", fmt.Errorf("a prompt is required (use --prompt or --prompt-file)")
+	}
+
+	var data []byte
+	var err error
+	if agentPromptFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(agentPromptFile)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading prompt: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/git"
+	"github.com/wfaler/rig/internal/project"
+)
+
+var (
+	checkpointWorktree string
+	checkpointMessage  string
+)
+
+var checkpointCmd = &cobra.Command{
+	Use:   "checkpoint",
+	Short: "Manage git checkpoints of the workspace",
+	Long: `Checkpoints are snapshots of the workspace, including untracked files,
+stored as commits on hidden refs (refs/rig/checkpoints/<id>). Creating or
+restoring one never changes the current branch or the git index. Ignored
+files and the .rig directory are not included.
+
+Set checkpoints: true in .rig.yml, or pass --checkpoint to 'rig up' or
+'rig agent', to record one automatically before each session.`,
+}
+
+var checkpointCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Record a checkpoint of the workspace",
+	Args:  cobra.NoArgs,
+	RunE:  runCheckpointCreate,
+}
+
+var checkpointListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List checkpoints, newest first",
+	Args:    cobra.NoArgs,
+	RunE:    runCheckpointList,
+}
+
+var checkpointRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Restore the workspace to a checkpoint",
+	Long: `Restores the workspace files to a checkpoint and removes files created
+since. The current state is saved as a new checkpoint first, so a restore
+can itself be undone.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCheckpointRestore,
+}
+
+var checkpointRemoveCmd = &cobra.Command{
+	Use:   "rm <id>...",
+	Short: "Delete checkpoints",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runCheckpointRemove,
+}
+
+func init() {
+	checkpointCmd.PersistentFlags().StringVarP(&checkpointWorktree, "worktree", "w", "", "Use a worktree created with 'rig worktree add'")
+	checkpointCreateCmd.Flags().StringVarP(&checkpointMessage, "message", "m", "", "Checkpoint description")
+	checkpointCmd.AddCommand(checkpointCreateCmd)
+	checkpointCmd.AddCommand(checkpointListCmd)
+	checkpointCmd.AddCommand(checkpointRestoreCmd)
+	checkpointCmd.AddCommand(checkpointRemoveCmd)
+	rootCmd.AddCommand(checkpointCmd)
+}
+
+// checkpointDir returns the directory whose working tree checkpoint commands act on
+func checkpointDir() (string, error) {
+	cwd, err := projectDir()
+	if err != nil {
+		return "", err
+	}
+	if checkpointWorktree == "" {
+		return cwd, nil
+	}
+	dir := project.WorktreeDir(cwd, checkpointWorktree)
+	if _, err := os.Stat(dir); err != nil {
+		return "", fmt.Errorf("worktree for branch %s not found", checkpointWorktree)
+	}
+	return dir, nil
+}
+
+func runCheckpointCreate(cmd *cobra.Command, args []string) error {
+	dir, err := checkpointDir()
+	if err != nil {
+		return err
+	}
+	message := checkpointMessage
+	if message == "" {
+		message = "rig checkpoint"
+	}
+	cp, err := git.CreateCheckpoint(dir, message, time.Now())
+	if err != nil {
+		return fmt.Errorf("creating checkpoint: %w", err)
+	}
+	fmt.Printf("Created checkpoint %s\n", cp.ID)
+	return nil
+}
+
+func runCheckpointList(cmd *cobra.Command, args []string) error {
+	dir, err := checkpointDir()
+	if err != nil {
+		return err
+	}
+	checkpoints, err := git.ListCheckpoints(dir)
+	if err != nil {
+		return fmt.Errorf("listing checkpoints: %w", err)
+	}
+	if len(checkpoints) == 0 {
+		fmt.Println("No checkpoints")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tCOMMIT\tMESSAGE")
+	for _, cp := range checkpoints {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", cp.ID, cp.Created.Local().Format("2006-01-02 15:04:05"), cp.Commit[:12], cp.Message)
+	}
+	return w.Flush()
+}
+
+func runCheckpointRestore(cmd *cobra.Command, args []string) error {
+	dir, err := checkpointDir()
+	if err != nil {
+		return err
+	}
+	backup, err := git.RestoreCheckpoint(dir, args[0], time.Now())
+	if err != nil {
+		return fmt.Errorf("restoring checkpoint: %w", err)
+	}
+	fmt.Printf("Restored checkpoint %s (previous state saved as %s)\n", args[0], backup.ID)
+	return nil
+}
+
+func runCheckpointRemove(cmd *cobra.Command, args []string) error {
+	dir, err := checkpointDir()
+	if err != nil {
+		return err
+	}
+	for _, id := range args {
+		if err := git.DeleteCheckpoint(dir, id); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted checkpoint %s\n", id)
+	}
+	return nil
+}
+
+// recordCheckpoint saves a checkpoint of dir before a session. Failures,
+// such as dir not being a git repository, are reported but not fatal.
+func recordCheckpoint(dir, purpose string) {
+	message := "rig checkpoint"
+	if purpose != "" {
+		message += " before " + purpose
+	}
+	cp, err := git.CreateCheckpoint(dir, message, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not record checkpoint: %v\n", err)
+		return
+	}
+	fmt.Printf("Recorded checkpoint %s (restore with 'rig checkpoint restore %s')\n", cp.ID, cp.ID)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/dockerproxy"
+)
+
+// childLabelEnv exposes the child label inside the container when the raw
+// socket is mounted, e.g. docker run --label "$RIG_PARENT_LABEL"
+const childLabelEnv = "RIG_PARENT_LABEL"
+
+// childLabel returns the label filter ("key=value") matching resources
+// created from within a rig container
+func childLabel(containerName string) string {
+	return dockerproxy.ParentLabel + "=" + containerName
+}
+
+// removeChildren removes containers and networks created from within a rig
+// container, and its volumes too if withVolumes is set. Containers go first
+// so networks and volumes are no longer in use.
+func removeChildren(ctx context.Context, dockerClient docker.DockerClient, containerName string, withVolumes bool) error {
+	label := childLabel(containerName)
+
+	containers, err := dockerClient.ListContainersByLabel(ctx, label)
+	if err != nil {
+		return err
+	}
+	for _, id := range containers {
+		fmt.Printf("Removing child container %s...\n", shortID(id))
+		if err := dockerClient.RemoveContainer(ctx, id, true); err != nil {
+			return fmt.Errorf("removing child container: %w", err)
+		}
+	}
+
+	networks, err := dockerClient.ListNetworksByLabel(ctx, label)
+	if err != nil {
+		return err
+	}
+	for _, id := range networks {
+		fmt.Printf("Removing child network %s...\n", shortID(id))
+		if err := dockerClient.RemoveNetwork(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	if !withVolumes {
+		return nil
+	}
+	volumes, err := dockerClient.ListVolumesByLabel(ctx, label)
+	if err != nil {
+		return err
+	}
+	for _, name := range volumes {
+		fmt.Printf("Removing child volume %s...\n", name)
+		if err := dockerClient.RemoveVolume(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shortID abbreviates a Docker ID the way the docker CLI does
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -3,7 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
+	"slices"
 
 	"github.com/spf13/cobra"
 	"github.com/wfaler/rig/internal/docker"
@@ -17,9 +17,18 @@ var destroyCmd = &cobra.Command{
 
 If [name] is provided, destroys the container and images for that project.
 Otherwise, destroys the container and images for the current directory.
+[name] is a project name as shown by 'rig list'; its path hash may be left
+out when only one project matches.
 
-This is a destructive operation - the container state will be lost and
-images will need to be rebuilt on next 'rig up'.`,
+The containers of the project's worktrees are removed along with their
+sidecars and workspace copies; the worktrees themselves are kept.
+
+Containers, networks and volumes created from within the rig container
+(e.g. by tests or agents through the Docker socket) are removed as well.
+
+This is a destructive operation - the container state will be lost,
+including changes in an isolated workspace that were not applied with
+'rig review apply', and images will need to be rebuilt on next 'rig up'.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runDestroy,
 }
@@ -31,22 +40,6 @@ func init() {
 func runDestroy(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	var projectName string
-	if len(args) > 0 {
-		// Use provided name
-		projectName = args[0]
-	} else {
-		// Get current working directory
-		cwd, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("getting current directory: %w", err)
-		}
-		projectName = project.GetProjectName(cwd)
-	}
-
-	containerName := project.ContainerName(projectName)
-	imageName := project.ImageName(projectName)
-
 	// Create Docker client
 	dockerClient, err := docker.New()
 	if err != nil {
@@ -54,41 +47,70 @@ func runDestroy(cmd *cobra.Command, args []string) error {
 	}
 	defer dockerClient.Close()
 
-	// Find and remove container
-	containerID, err := dockerClient.FindContainer(ctx, containerName)
+	projectName, err := resolveProjectArg(ctx, dockerClient, args)
 	if err != nil {
-		return fmt.Errorf("finding container: %w", err)
+		return err
+	}
+	// The project's main container and those of its worktrees, which
+	// still run its image
+	containers, err := dockerClient.ListProjectContainers(ctx, projectName)
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 {
+		fmt.Printf("No container found for project %s\n", projectName)
 	}
 
-	if containerID != "" {
-		// Check if running and stop first
-		running, err := dockerClient.IsContainerRunning(ctx, containerID)
-		if err != nil {
-			return fmt.Errorf("checking container status: %w", err)
+	// Clean up after the main container even if it is already gone
+	containerNames := []string{project.ContainerName(projectName)}
+	for _, ctr := range containers {
+		if err := removeContainer(ctx, dockerClient, ctr); err != nil {
+			return err
+		}
+		if !slices.Contains(containerNames, ctr.Name) {
+			containerNames = append(containerNames, ctr.Name)
 		}
-		if running {
-			fmt.Printf("Stopping container %s...\n", containerName)
-			if err := dockerClient.StopContainer(ctx, containerID); err != nil {
-				return fmt.Errorf("stopping container: %w", err)
-			}
-			// Wait for container to fully stop before removing
-			_ = dockerClient.WaitContainer(ctx, containerID)
+	}
+
+	for _, containerName := range containerNames {
+		if err := removeChildren(ctx, dockerClient, containerName, true); err != nil {
+			return err
 		}
 
-		fmt.Printf("Removing container %s...\n", containerName)
-		if err := dockerClient.RemoveContainer(ctx, containerID, true); err != nil {
-			return fmt.Errorf("removing container: %w", err)
+		// Remove the sidecar proxies, network and volume, if any
+		if err := removeSidecars(ctx, dockerClient, containerName); err != nil {
+			return err
+		}
+		if err := removeWorkspaceCopy(ctx, dockerClient, containerName); err != nil {
+			return err
 		}
-	} else {
-		fmt.Printf("No container found for project %s\n", projectName)
 	}
 
-	// Remove all images with this project name
-	fmt.Printf("Removing images matching %s...\n", imageName)
-	if err := dockerClient.RemoveImagesByName(ctx, imageName); err != nil {
+	// Remove all images of this project
+	fmt.Printf("Removing images of %s...\n", projectName)
+	if err := dockerClient.RemoveImagesByLabel(ctx, project.Filter(project.ProjectLabel, projectName)); err != nil {
 		fmt.Printf("Note: %v\n", err)
 	}
 
 	fmt.Printf("Project %s destroyed.\n", projectName)
 	return nil
 }
+
+// removeContainer stops a workspace container, running its pre_stop hooks,
+// and removes it
+func removeContainer(ctx context.Context, dockerClient docker.DockerClient, ctr docker.RigContainer) error {
+	if ctr.Running {
+		fmt.Printf("Stopping container %s...\n", ctr.Name)
+		if err := stopContainer(ctx, dockerClient, ctr.ID); err != nil {
+			return err
+		}
+		// Wait for container to fully stop before removing
+		_ = dockerClient.WaitContainer(ctx, ctr.ID)
+	}
+
+	fmt.Printf("Removing container %s...\n", ctr.Name)
+	if err := dockerClient.RemoveContainer(ctx, ctr.ID, true); err != nil {
+		return fmt.Errorf("removing container: %w", err)
+	}
+	return nil
+}
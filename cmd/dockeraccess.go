@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/dockerproxy"
+	"github.com/wfaler/rig/internal/project"
+	"github.com/wfaler/rig/internal/sidecar"
+)
+
+var (
+	dockerAccessLogFollow   bool
+	dockerAccessLogWorktree string
+)
+
+var dockerAccessCmd = &cobra.Command{
+	Use:   "docker-access",
+	Short: "Inspect the container's Docker API access",
+	Long: `Commands for inspecting docker_access enforcement.
+
+With docker_access: proxy in .rig.yml, the host Docker socket is not mounted
+into the container. Instead, a rig-managed sidecar exposes a filtering proxy
+socket that only allows the API calls testcontainers and similar tools need.
+Privileged containers, added capabilities, host network/PID/IPC namespaces,
+device mappings and bind mounts outside the workspace are rejected, and only
+containers, networks, volumes and images created through the proxy can be
+used or listed. Image names starting with rig- are reserved; the host's images
+can still be listed. With restricted egress, containers are kept on the egress
+network.`,
+}
+
+var dockerAccessLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show Docker API requests denied by the socket proxy",
+	Args:  cobra.NoArgs,
+	RunE:  runDockerAccessLog,
+}
+
+func init() {
+	dockerAccessLogCmd.Flags().BoolVarP(&dockerAccessLogFollow, "follow", "f", false, "Follow new denials")
+	dockerAccessLogCmd.Flags().StringVarP(&dockerAccessLogWorktree, "worktree", "w", "", "Show denials for a worktree's container")
+	dockerAccessCmd.AddCommand(dockerAccessLogCmd)
+	rootCmd.AddCommand(dockerAccessCmd)
+}
+
+func runDockerAccessLog(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	cwd, err := projectDir()
+	if err != nil {
+		return err
+	}
+	containerName := targetContainerName(cwd, dockerAccessLogWorktree)
+	proxyName := project.DockerProxyName(containerName)
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	proxyID, err := dockerClient.FindContainer(ctx, proxyName)
+	if err != nil {
+		return fmt.Errorf("finding container: %w", err)
+	}
+	if proxyID == "" {
+		fmt.Printf("No Docker proxy found for %s (is docker_access: proxy set in %s?)\n", containerName, configFileName)
+		return nil
+	}
+
+	// Denials are JSON lines on the proxy's stdout
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(dockerClient.Logs(ctx, proxyID, docker.LogsOptions{Follow: dockerAccessLogFollow}, pw, io.Discard))
+	}()
+
+	scanner := bufio.NewScanner(pr)
+	for scanner.Scan() {
+		var entry dockerproxy.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Action != dockerproxy.ActionDeny {
+			continue
+		}
+		fmt.Printf("%s  %s  %-6s  %s  (%s)\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			strings.ToUpper(entry.Action), entry.Method, entry.Path, entry.Reason)
+	}
+	return scanner.Err()
+}
+
+// startDockerProxy makes sure the Docker socket proxy for a container is up.
+// Containers started through it may bind-mount workspace, if set, and are
+// kept on network, if set. It returns the binds and environment the
+// container needs to reach the proxy socket instead of the host's.
+func startDockerProxy(ctx context.Context, dockerClient docker.DockerClient, containerName, workspace, network string, labels project.Labels) ([]string, map[string]string, error) {
+	proxyName := project.DockerProxyName(containerName)
+	socketVolume := project.DockerProxyVolumeName(containerName)
+	socketBind := fmt.Sprintf("%s:%s", socketVolume, dockerproxy.SocketDir)
+
+	if err := dockerClient.EnsureVolume(ctx, socketVolume, labels.Map("")); err != nil {
+		return nil, nil, err
+	}
+
+	imageRef, err := ensureSidecarImage(ctx, dockerClient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proxyID, err := dockerClient.FindContainer(ctx, proxyName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("finding container: %w", err)
+	}
+
+	// Recreate the proxy if the config or proxy image changed
+	if proxyID != "" {
+		current, err := dockerClient.GetContainerLabels(ctx, proxyID)
+		if err != nil {
+			return nil, nil, err
+		}
+		currentImage, err := dockerClient.GetContainerImage(ctx, proxyID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting container image: %w", err)
+		}
+		if current[project.ConfigHashLabel] != labels.ConfigHash || currentImage != imageRef {
+			if err := dockerClient.RemoveContainer(ctx, proxyID, true); err != nil {
+				return nil, nil, fmt.Errorf("removing old docker proxy: %w", err)
+			}
+			proxyID = ""
+		}
+	}
+
+	if proxyID == "" {
+		binds := []string{
+			fmt.Sprintf("%s:%s", dockerproxy.UpstreamSocket, dockerproxy.UpstreamSocket),
+			socketBind,
+		}
+		// The proxy resolves symlinks in bind sources, so it sees the
+		// workspace at its host path
+		if workspace != "" {
+			binds = append(binds, fmt.Sprintf("%s:%s:ro", workspace, workspace))
+		}
+
+		fmt.Printf("Creating docker proxy %s...\n", proxyName)
+		proxyID, err = dockerClient.CreateSidecar(ctx, docker.SidecarConfig{
+			ImageRef:      imageRef,
+			ContainerName: proxyName,
+			Command:       []string{sidecar.DockerProxyBinary},
+			Env: map[string]string{
+				dockerproxy.WorkspaceEnv: workspace,
+				dockerproxy.ParentEnv:    containerName,
+				dockerproxy.NetworkEnv:   network,
+			},
+			Binds: binds,
+			// The proxy only talks over unix sockets
+			NetworkMode: "none",
+			Labels:      labels.Map(project.RoleDockerProxy),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating docker proxy: %w", err)
+		}
+	}
+
+	running, err := dockerClient.IsContainerRunning(ctx, proxyID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("checking container status: %w", err)
+	}
+	if !running {
+		if err := dockerClient.StartContainer(ctx, proxyID); err != nil {
+			return nil, nil, fmt.Errorf("starting docker proxy: %w", err)
+		}
+	}
+
+	env := map[string]string{"DOCKER_HOST": "unix://" + dockerproxy.SocketPath}
+	return []string{socketBind}, env, nil
+}
+
+// stopDockerProxy stops a container's Docker socket proxy, if it has one
+func stopDockerProxy(ctx context.Context, dockerClient docker.DockerClient, containerName string) error {
+	return stopSidecar(ctx, dockerClient, project.DockerProxyName(containerName))
+}
+
+// removeDockerProxy removes a container's Docker socket proxy and its socket
+// volume, if any. The container itself must already be removed so the volume
+// is no longer in use.
+func removeDockerProxy(ctx context.Context, dockerClient docker.DockerClient, containerName string) error {
+	proxyName := project.DockerProxyName(containerName)
+	proxyID, err := dockerClient.FindContainer(ctx, proxyName)
+	if err != nil {
+		return fmt.Errorf("finding container: %w", err)
+	}
+	if proxyID != "" {
+		fmt.Printf("Removing docker proxy %s...\n", proxyName)
+		if err := dockerClient.RemoveContainer(ctx, proxyID, true); err != nil {
+			return fmt.Errorf("removing docker proxy: %w", err)
+		}
+	}
+	return dockerClient.RemoveVolume(ctx, project.DockerProxyVolumeName(containerName))
+}
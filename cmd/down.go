@@ -7,7 +7,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/wfaler/rig/internal/docker"
-	"github.com/wfaler/rig/internal/project"
+	"github.com/wfaler/rig/internal/supervisor"
 )
 
 var downCmd = &cobra.Command{
@@ -18,8 +18,19 @@ var downCmd = &cobra.Command{
 The container is stopped but not removed, so any state inside the container
 (installed packages, files outside /workspace, etc.) will be preserved.
 
+The shutdown.pre_stop hooks in .rig.yml run first. Supervised processes then
+receive shutdown.signal (default SIGTERM) and have shutdown.timeout seconds
+(default 10) to exit before the container is killed.
+
 If [name] is provided, stops the container with that project name.
-Otherwise, stops the container for the current directory.
+Otherwise, stops the container for the current directory. The containers
+of the project's worktrees are stopped as well.
+[name] is a project name as shown by 'rig list'; its path hash may be left
+out when only one project matches.
+
+With --with-children, containers and networks created from within the rig
+container (e.g. by tests or agents through the Docker socket) are removed
+too. Their volumes are kept; 'rig destroy' removes them.
 
 Use 'rig up' to start the container again.
 Use 'rig rebuild' if you want to completely remove and rebuild the container.`,
@@ -27,28 +38,16 @@ Use 'rig rebuild' if you want to completely remove and rebuild the container.`,
 	RunE: runDown,
 }
 
+var downWithChildren bool
+
 func init() {
+	downCmd.Flags().BoolVar(&downWithChildren, "with-children", false, "Also remove containers and networks created from within the container")
 	rootCmd.AddCommand(downCmd)
 }
 
 func runDown(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	var projectName string
-	if len(args) > 0 {
-		// Use provided name
-		projectName = args[0]
-	} else {
-		// Get current working directory
-		cwd, err := os.Getwd()
-		if err != nil {
-			return fmt.Errorf("getting current directory: %w", err)
-		}
-		projectName = project.GetProjectName(cwd)
-	}
-
-	containerName := project.ContainerName(projectName)
-
 	// Create Docker client
 	dockerClient, err := docker.New()
 	if err != nil {
@@ -56,40 +55,82 @@ func runDown(cmd *cobra.Command, args []string) error {
 	}
 	defer dockerClient.Close()
 
-	// Find existing container
-	containerID, err := dockerClient.FindContainer(ctx, containerName)
+	projectName, err := resolveProjectArg(ctx, dockerClient, args)
 	if err != nil {
-		return fmt.Errorf("finding container: %w", err)
+		return err
 	}
 
-	if containerID == "" {
+	// The project's main container and those of its worktrees
+	containers, err := dockerClient.ListProjectContainers(ctx, projectName)
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 {
 		fmt.Printf("No container found for project %s\n", projectName)
 		return nil
 	}
 
-	// Check if container is running
-	running, err := dockerClient.IsContainerRunning(ctx, containerID)
-	if err != nil {
-		return fmt.Errorf("checking container status: %w", err)
+	for _, ctr := range containers {
+		if err := downContainer(ctx, dockerClient, ctr); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	if !running {
-		fmt.Printf("Container %s is already stopped\n", containerName)
-		return nil
+// downContainer stops a workspace container, its sidecar proxies and, with
+// --with-children, removes its children
+func downContainer(ctx context.Context, dockerClient docker.DockerClient, ctr docker.RigContainer) error {
+	// Stop the container first: pre_stop hooks may still need its children
+	// and the Docker proxy
+	if ctr.Running {
+		fmt.Printf("Stopping container %s...\n", ctr.Name)
+		if err := stopContainer(ctx, dockerClient, ctr.ID); err != nil {
+			return err
+		}
+
+		// Wait for container to fully stop
+		if err := dockerClient.WaitContainer(ctx, ctr.ID); err != nil {
+			// Ignore wait errors - container may have already stopped
+			_ = err
+		}
 	}
 
-	// Stop the container
-	fmt.Printf("Stopping container %s...\n", containerName)
-	if err := dockerClient.StopContainer(ctx, containerID); err != nil {
-		return fmt.Errorf("stopping container: %w", err)
+	if downWithChildren {
+		if err := removeChildren(ctx, dockerClient, ctr.Name, false); err != nil {
+			return err
+		}
 	}
 
-	// Wait for container to fully stop
-	if err := dockerClient.WaitContainer(ctx, containerID); err != nil {
-		// Ignore wait errors - container may have already stopped
-		_ = err
+	// Stop the sidecar proxies along with the container
+	if err := stopSidecars(ctx, dockerClient, ctr.Name); err != nil {
+		return err
 	}
 
-	fmt.Printf("Container %s stopped. Run 'rig' to start it again.\n", containerName)
+	if !ctr.Running {
+		fmt.Printf("Container %s is already stopped\n", ctr.Name)
+		return nil
+	}
+	fmt.Printf("Container %s stopped. Run 'rig' to start it again.\n", ctr.Name)
+	return nil
+}
+
+// stopContainer runs a project container's pre_stop hooks, then stops it.
+// Failing hooks are reported but don't keep the container running.
+func stopContainer(ctx context.Context, dockerClient docker.DockerClient, containerID string) error {
+	code, err := dockerClient.Exec(ctx, containerID, docker.ExecOptions{
+		Cmd:    supervisor.PreStopCommand(),
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: running pre_stop hooks: %v\n", err)
+	} else if code != 0 {
+		fmt.Fprintf(os.Stderr, "Warning: pre_stop hook exited with code %d\n", code)
+	}
+
+	if err := dockerClient.StopContainer(ctx, containerID); err != nil {
+		return fmt.Errorf("stopping container: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/docker"
+)
+
+var (
+	execTty      bool
+	execNoTty    bool
+	execWorkdir  string
+	execEnv      []string
+	execUser     string
+	execWorktree string
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] -- <command> [args...]",
+	Short: "Run a command in the rig container",
+	Long: `Runs a command inside the rig container and exits with its exit status,
+for use in scripts, git hooks and CI.
+
+The container is created or started as with 'rig up'; rig's own progress
+messages go to stderr so stdout carries only the command's output.
+
+A TTY is allocated when stdin and stdout are both terminals, or with --tty.
+Without one (or with --no-tty), stdin can be piped in and the command's
+stdout and stderr stay separate.
+
+The command starts in the directory matching the current one, like 'rig up'.
+A relative --workdir is resolved against it.
+
+Examples:
+  rig exec -- go test ./...
+  rig exec --no-tty -- make lint > lint.txt
+  git diff | rig exec -- patch -p1 --dry-run
+  rig exec -e CI=true --workdir /tmp -- ./build.sh
+  rig exec --user root -- apt-get install -y jq`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExec,
+}
+
+func init() {
+	execCmd.Flags().BoolVarP(&execTty, "tty", "t", false, "Allocate a TTY")
+	execCmd.Flags().BoolVarP(&execNoTty, "no-tty", "T", false, "Don't allocate a TTY, even on a terminal")
+	execCmd.Flags().StringVar(&execWorkdir, "workdir", "", "Directory to run the command in")
+	execCmd.Flags().StringArrayVarP(&execEnv, "env", "e", nil, "Set an environment variable (KEY=value, or KEY to pass the host's value)")
+	execCmd.Flags().StringVarP(&execUser, "user", "u", "", "User to run the command as")
+	execCmd.Flags().StringVarP(&execWorktree, "worktree", "w", "", "Run in the container of a worktree created with 'rig worktree add'")
+	execCmd.MarkFlagsMutuallyExclusive("tty", "no-tty")
+	// Flags after the command belong to it: rig exec ls -la
+	execCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(execCmd)
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	env, err := execEnvironment(execEnv)
+	if err != nil {
+		return err
+	}
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	var sess *session
+	err = withStdoutOnStderr(func() error {
+		sess, err = startSession(ctx, dockerClient, sessionOptions{
+			worktree: execWorktree,
+			purpose:  "rig exec",
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	workdir := sess.startDir()
+	if execWorkdir != "" {
+		workdir = path.Join(workdir, execWorkdir)
+		if path.IsAbs(execWorkdir) {
+			workdir = execWorkdir
+		}
+	}
+
+	tty := execTty || (!execNoTty && term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stdout.Fd()))
+	exitCode, err := dockerClient.Exec(ctx, sess.containerID, docker.ExecOptions{
+		Cmd:        args,
+		WorkingDir: workdir,
+		Env:        env,
+		User:       execUser,
+		Tty:        tty,
+		Stdin:      os.Stdin,
+	})
+	if err != nil {
+		return fmt.Errorf("running command: %w", err)
+	}
+
+	if exitCode != 0 {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return &exitError{code: exitCode}
+	}
+	return nil
+}
+
+// execEnvironment turns --env values into KEY=value pairs; a bare KEY takes
+// the host's value, as with docker exec
+func execEnvironment(values []string) ([]string, error) {
+	env := make([]string, 0, len(values))
+	for _, v := range values {
+		key, _, hasValue := strings.Cut(v, "=")
+		if key == "" {
+			return nil, fmt.Errorf("invalid --env %q: expected KEY=value", v)
+		}
+		if !hasValue {
+			v = key + "=" + os.Getenv(key)
+		}
+		env = append(env, v)
+	}
+	return env, nil
+}
+
+// withStdoutOnStderr runs fn with os.Stdout pointing at stderr, keeping
+// progress messages out of output that may be piped
+func withStdoutOnStderr(fn func() error) error {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+	return fn()
+}
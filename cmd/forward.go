@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/egress"
+)
+
+var forwardWorktree string
+
+var forwardCmd = &cobra.Command{
+	Use:   "forward <port>|<host-port>:<container-port>",
+	Short: "Forward a local port into the container without publishing it",
+	Long: `Forwards a port on localhost to a port inside the running rig container.
+
+Each connection is relayed through 'docker exec', so this works even when the
+container has no network (rig up --offline or network: none), for example to
+reach code-server.
+
+Examples:
+  rig forward 8080
+  rig forward 9000:8080`,
+	Args: cobra.ExactArgs(1),
+	RunE: runForward,
+}
+
+func init() {
+	forwardCmd.Flags().StringVarP(&forwardWorktree, "worktree", "w", "", "Forward into a worktree's container")
+	rootCmd.AddCommand(forwardCmd)
+}
+
+func runForward(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	hostPort, containerPort, err := egress.ParseForwardSpec(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	containerID, containerName, err := runningContainer(ctx, dockerClient, forwardWorktree)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(hostPort)))
+	if err != nil {
+		return fmt.Errorf("listening on port %d: %w", hostPort, err)
+	}
+	defer listener.Close()
+
+	fmt.Printf("Forwarding http://localhost:%d to port %d in %s (Ctrl-C to stop)\n", hostPort, containerPort, containerName)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return fmt.Errorf("accepting connection: %w", err)
+		}
+		go func() {
+			defer conn.Close()
+			// socat bridges the exec's stdin/stdout to the port inside the container
+			_, err := dockerClient.Exec(ctx, containerID, docker.ExecOptions{
+				Cmd:    []string{"socat", "-", fmt.Sprintf("TCP:127.0.0.1:%d", containerPort)},
+				Stdin:  conn,
+				Stdout: conn,
+				Stderr: io.Discard,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "forward: %v\n", err)
+			}
+		}()
+	}
+}
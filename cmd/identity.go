@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wfaler/rig/internal/config"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/project"
+)
+
+// findProject returns the project directory for the current directory (the
+// nearest one at or above it with a .rig.yml) and the current directory's path
+// relative to it
+func findProject() (root, rel string, err error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", "", fmt.Errorf("getting current directory: %w", err)
+	}
+	root, err = project.FindRoot(cwd)
+	if err != nil {
+		return "", "", err
+	}
+	rel, err = filepath.Rel(root, cwd)
+	if err != nil {
+		return "", "", err
+	}
+	return root, filepath.ToSlash(rel), nil
+}
+
+// projectDir returns the project directory for the current directory, or the
+// current directory itself when no .rig.yml is found above it
+func projectDir() (string, error) {
+	root, _, err := findProject()
+	if errors.Is(err, project.ErrNoConfig) {
+		return os.Getwd()
+	}
+	return root, err
+}
+
+// resolveProjectName returns the project name for a directory, honoring the
+// name field of its .rig.yml
+func resolveProjectName(dir string) string {
+	name := ""
+	if cfg, err := config.Load(project.ConfigPath(dir)); err == nil {
+		name = cfg.Name
+	}
+	return project.GetProjectName(dir, name)
+}
+
+// resourceLabels describes a project's Docker resources: the project and its
+// directory, the directory mounted as workspace, and the config they were made from
+func resourceLabels(projectName, projectDir, workDir, configHash string, cfg *config.Config) project.Labels {
+	return project.Labels{
+		Project:    projectName,
+		ProjectDir: projectDir,
+		WorkDir:    workDir,
+		ConfigHash: configHash,
+		Version:    Version,
+		Profile:    cfg.GetSecurityProfile(),
+	}
+}
+
+// imageLabels labels a project's image, which worktrees share, with the
+// project directory and the .rig.yml it is built from
+func imageLabels(projectName, projectDir, configHash string, cfg *config.Config) (map[string]string, error) {
+	data, err := os.ReadFile(project.ConfigPath(projectDir))
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	labels := resourceLabels(projectName, projectDir, projectDir, configHash, cfg)
+	labels.Config = string(data)
+	return labels.Map(""), nil
+}
+
+// resolveProjectArg returns the project named on the command line, or the
+// current directory's project. A name may be given without its path hash
+// ("api" for "api-1a2b3c4d") as long as only one such project exists.
+func resolveProjectArg(ctx context.Context, dockerClient docker.DockerClient, args []string) (string, error) {
+	if len(args) == 0 {
+		dir, err := projectDir()
+		if err != nil {
+			return "", err
+		}
+		return resolveProjectName(dir), nil
+	}
+	name := args[0]
+
+	projects, err := labeledProjects(ctx, dockerClient)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := projects[name]; ok {
+		return name, nil
+	}
+
+	// Containers from older rig versions have no labels but can be named exactly
+	id, err := dockerClient.FindContainer(ctx, project.ContainerName(name))
+	if err != nil {
+		return "", fmt.Errorf("finding container: %w", err)
+	}
+	if id != "" {
+		return name, nil
+	}
+
+	var matches []string
+	for p := range projects {
+		if project.MatchesBaseName(p, name) {
+			matches = append(matches, p)
+		}
+	}
+	sort.Strings(matches)
+	switch len(matches) {
+	case 0:
+		return name, nil
+	case 1:
+		return matches[0], nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s matches several projects; use the full name:", name)
+	for _, m := range matches {
+		fmt.Fprintf(&b, "\n  %s  (%s)", m, projects[m])
+	}
+	return "", fmt.Errorf("%s", b.String())
+}
+
+// labeledProjects maps the project names of all labeled rig containers,
+// running or not, to their host directories
+func labeledProjects(ctx context.Context, dockerClient docker.DockerClient) (map[string]string, error) {
+	ids, err := dockerClient.ListContainersByLabel(ctx, project.ProjectLabel)
+	if err != nil {
+		return nil, err
+	}
+	projects := make(map[string]string, len(ids))
+	for _, id := range ids {
+		labels, err := dockerClient.GetContainerLabels(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		projects[labels[project.ProjectLabel]] = labels[project.PathLabel]
+	}
+	return projects, nil
+}
+
+// warnLegacyContainer points out a container that an older rig version
+// created for dir under its unhashed name, which is no longer used
+func warnLegacyContainer(ctx context.Context, dockerClient docker.DockerClient, dir, containerName string) {
+	legacyName := project.ContainerName(project.LegacyProjectName(dir))
+	if legacyName == containerName {
+		return
+	}
+	id, err := dockerClient.FindContainer(ctx, legacyName)
+	if err != nil || id == "" {
+		return
+	}
+	labels, err := dockerClient.GetContainerLabels(ctx, id)
+	if err != nil || labels[project.ProjectLabel] != "" {
+		return
+	}
+	// Only claim it if it mounts this directory; another api/ may own it
+	source, err := dockerClient.GetContainerMountSource(ctx, id, config.DefaultWorkspaceDir)
+	if err != nil || source != dir {
+		return
+	}
+	fmt.Printf("Note: container %s was created for this directory by an older rig version and is no longer used.\n", legacyName)
+	fmt.Printf("      Remove it with 'rig destroy %s'. Its images predate labels; list them with 'docker images %s'.\n",
+		project.LegacyProjectName(dir), project.ImageName(project.LegacyProjectName(dir)))
+}
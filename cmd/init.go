@@ -27,6 +27,10 @@ This creates a template configuration file that you can edit to specify:
 const emptyConfig = `# Rig configuration
 # See: https://github.com/wfaler/rig for documentation
 
+# Project name for images and containers (default: directory name plus a hash
+# of its path, so directories with the same name don't collide):
+# name: billing
+
 languages:
   # Example configurations:
   # node:
@@ -64,6 +68,82 @@ env: {}
   # API_KEY: "${API_KEY}"
   # DATABASE_URL: "postgres://localhost:5432/dev"
 
+# Access to the host Docker daemon: full (default, raw socket), proxy (filtering
+# socket proxy that rejects privileged containers and binds outside the project,
+# and labels what it creates so 'rig down --with-children' and 'rig destroy' can
+# clean up; see denials with 'rig docker-access log'), or none:
+# docker_access: proxy
+
+# Record a git checkpoint (incl. untracked files) before each session;
+# see 'rig checkpoint ls' and 'rig checkpoint restore <id>':
+# checkpoints: true
+
+# Let the container work on a copy of the project; review and apply its
+# changes with 'rig review' (default: none, the project directory is mounted):
+# isolation: review
+
+# Mount the project at its host path instead of /workspace, so bind mounts of
+# project files by containers started through the Docker socket work:
+# workspace_path: mirror
+
+# Extra directories (e.g. sibling repos) mounted at /workspace/<name>; code-server
+# opens them together as a multi-root workspace:
+# workspace_folders:
+#   - ../shared-lib
+#   - path: ../proto
+#     name: schemas
+
+# Hardened security: no sudo, all but a few capabilities dropped, no-new-privileges,
+# seccomp. Optionally a read-only root filesystem (tmpfs /tmp) and a custom seccomp profile:
+# security: hardened
+# security:
+#   profile: hardened
+#   read_only: true
+#   seccomp: seccomp.json
+
+# Run with no network at all and no Docker socket, e.g. for untrusted code
+# (same as 'rig up --offline'; use 'rig forward <port>' to reach code-server):
+# network: none
+
+# Restrict outbound network access to an allow-list (default: unrestricted).
+# Traffic goes through a rig-managed filtering proxy; see denials with 'rig net log'.
+# network:
+#   egress:                # or "egress: none" to deny everything
+#     - github.com
+#     - "*.npmjs.org"
+#     - 10.0.0.0/8
+
+# Long-running processes started with the container and restarted when they
+# crash; see 'rig ps', 'rig logs <name>' and 'rig restart <name>':
+# processes:
+#   web: npm run dev
+#   api:
+#     command: go run ./cmd/api
+#     working_dir: services/api
+#     env:
+#       PORT: "9000"
+#     restart: always      # always, on-failure (default) or never
+
+# Named tasks run in the container with 'rig task <name>' (list with 'rig task --list'):
+# tasks:
+#   test: go test ./...
+#   lint:
+#     command: golangci-lint run
+#     description: Run the linters
+#   ci:
+#     depends_on: [lint, test]
+
+# Keys that detach from 'rig up', leaving the session running for 'rig attach':
+# detach_keys: ctrl-p,ctrl-q
+
+# How 'rig down' stops the container: pre_stop hooks run first, then processes
+# get the signal and timeout seconds to exit before they are killed:
+# shutdown:
+#   timeout: 10          # default: 10
+#   signal: SIGTERM      # default: SIGTERM
+#   pre_stop:
+#     - ./scripts/flush-queue.sh
+
 # Default shell: zsh (default, with oh-my-zsh), bash, or fish
 # shell: zsh
 
@@ -114,6 +194,8 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("writing config: %w", err)
 	}
 
+	recordProject(project.GetProjectName(cwd, ""), cwd, "")
+
 	fmt.Printf("Created %s\n", project.ConfigFileName)
 	fmt.Println("Edit this file to configure your development environment, then run:")
 	fmt.Println("  rig")
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/jobs"
+)
+
+var (
+	runDetach    bool
+	runWorktree  string
+	jobsWorktree string
+	waitWorktree string
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [flags] -- <command> [args...]",
+	Short: "Run a command as a job in the rig container",
+	Long: `Runs a command as a job in the rig container, capturing its output to a
+log inside the container.
+
+The container is created or started as with 'rig up', and the job starts in
+the directory matching the current one. Without --detach, rig follows the
+job's output and exits with its exit code; Ctrl-C stops following but the
+job keeps running. With --detach, rig prints the job ID and returns at once.
+
+Examples:
+  rig run --detach -- go test ./...
+  rig run --detach -- npm run e2e
+  rig jobs
+  rig logs --follow a1b2c3
+  rig wait a1b2c3`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRun,
+}
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "List jobs started with 'rig run'",
+	Long: `Lists the jobs in the rig container, oldest first, with their state
+(running, exited, or lost if the container stopped while they ran), exit code,
+start time and command.`,
+	Args: cobra.NoArgs,
+	RunE: runJobs,
+}
+
+var waitCmd = &cobra.Command{
+	Use:   "wait <job>",
+	Short: "Wait for a job to finish",
+	Long:  `Blocks until a job finishes and exits with the job's exit code.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runWait,
+}
+
+func init() {
+	runCmd.Flags().BoolVarP(&runDetach, "detach", "d", false, "Start the job in the background and return")
+	runCmd.Flags().StringVarP(&runWorktree, "worktree", "w", "", "Run in the container of a worktree created with 'rig worktree add'")
+	// Flags after the command belong to it: rig run -d make -j4
+	runCmd.Flags().SetInterspersed(false)
+	jobsCmd.Flags().StringVarP(&jobsWorktree, "worktree", "w", "", "List jobs in the container of a worktree")
+	waitCmd.Flags().StringVarP(&waitWorktree, "worktree", "w", "", "Use the container of a worktree")
+	rootCmd.AddCommand(runCmd, jobsCmd, waitCmd)
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	sess, err := startSession(ctx, dockerClient, sessionOptions{
+		worktree: runWorktree,
+		purpose:  "rig run",
+	})
+	if err != nil {
+		return err
+	}
+
+	id, err := jobs.NewID()
+	if err != nil {
+		return err
+	}
+	job := jobs.Job{
+		ID:         id,
+		Command:    args,
+		WorkingDir: sess.startDir(),
+		Started:    time.Now().UTC(),
+	}
+	start, err := jobs.StartCommand(job)
+	if err != nil {
+		return err
+	}
+	if err := dockerClient.ExecDetached(ctx, sess.containerID, docker.ExecOptions{
+		Cmd:        start,
+		WorkingDir: job.WorkingDir,
+	}); err != nil {
+		return fmt.Errorf("starting job: %w", err)
+	}
+
+	if runDetach {
+		fmt.Printf("Started job %s\n", id)
+		fmt.Printf("Follow its output with 'rig logs -f %s' or wait for it with 'rig wait %s'\n", id, id)
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Job %s started (Ctrl-C stops following; the job keeps running)\n", id)
+	if _, err := dockerClient.Exec(ctx, sess.containerID, docker.ExecOptions{Cmd: jobs.LogsCommand(id, true)}); err != nil {
+		return fmt.Errorf("following job output: %w", err)
+	}
+	return waitForJob(ctx, cmd, dockerClient, sess.containerID, id)
+}
+
+func runJobs(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	containerID, _, err := runningContainer(ctx, dockerClient, jobsWorktree)
+	if err != nil {
+		return err
+	}
+	statuses, err := listJobs(ctx, dockerClient, containerID)
+	if err != nil {
+		return err
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("No jobs (start one with 'rig run --detach -- <command>')")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATE\tEXIT\tSTARTED\tCOMMAND")
+	for _, s := range statuses {
+		exit := "-"
+		if s.State == jobs.Exited {
+			exit = fmt.Sprint(s.ExitCode)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.ID, s.State, exit, s.Started.Local().Format("2006-01-02 15:04:05"), strings.Join(s.Command, " "))
+	}
+	w.Flush()
+
+	return nil
+}
+
+func runWait(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	containerID, _, err := runningContainer(ctx, dockerClient, waitWorktree)
+	if err != nil {
+		return err
+	}
+	if _, err := findJob(ctx, dockerClient, containerID, args[0]); err != nil {
+		return err
+	}
+	return waitForJob(ctx, cmd, dockerClient, containerID, args[0])
+}
+
+// waitForJob blocks until a job finishes and returns its exit code as an exitError
+func waitForJob(ctx context.Context, cmd *cobra.Command, dockerClient docker.DockerClient, containerID, id string) error {
+	output, err := execOutput(ctx, dockerClient, containerID, jobs.WaitCommand(id))
+	if err != nil {
+		return fmt.Errorf("waiting for job %s: %w", id, err)
+	}
+	exitCode, err := jobs.ParseExitCode(output)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", id, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Job %s exited with code %d\n", id, exitCode)
+	if exitCode != 0 {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return &exitError{code: exitCode}
+	}
+	return nil
+}
+
+// listJobs returns the jobs in a container, oldest first
+func listJobs(ctx context.Context, dockerClient docker.DockerClient, containerID string) ([]jobs.Status, error) {
+	output, err := execOutput(ctx, dockerClient, containerID, jobs.ListCommand())
+	if err != nil {
+		return nil, fmt.Errorf("listing jobs: %w", err)
+	}
+	return jobs.ParseList(output)
+}
+
+// findJob returns the job with the given ID in a container
+func findJob(ctx context.Context, dockerClient docker.DockerClient, containerID, id string) (jobs.Status, error) {
+	if !jobs.ValidID(id) {
+		return jobs.Status{}, fmt.Errorf("invalid job id: %s (see 'rig jobs')", id)
+	}
+	statuses, err := listJobs(ctx, dockerClient, containerID)
+	if err != nil {
+		return jobs.Status{}, err
+	}
+	for _, s := range statuses {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return jobs.Status{}, fmt.Errorf("no job %s (see 'rig jobs')", id)
+}
+
+// execOutput runs a command in a container and returns its standard output,
+// failing with its standard error if it exits non-zero
+func execOutput(ctx context.Context, dockerClient docker.DockerClient, containerID string, command []string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	code, err := dockerClient.Exec(ctx, containerID, docker.ExecOptions{
+		Cmd:    command,
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return "", err
+	}
+	if code != 0 {
+		return "", fmt.Errorf("%s exited with code %d: %s", command[0], code, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// runningContainer returns the ID and name of the current project's (or a
+// worktree's) container, which must be running
+func runningContainer(ctx context.Context, dockerClient docker.DockerClient, worktree string) (string, string, error) {
+	containerID, containerName, running, err := projectContainer(ctx, dockerClient, worktree)
+	if err != nil {
+		return "", "", err
+	}
+	if !running {
+		return "", "", fmt.Errorf("container %s is not running (run 'rig up' first)", containerName)
+	}
+	return containerID, containerName, nil
+}
+
+// projectContainer finds the container of the current project, or of one of
+// its worktrees. The ID is empty if there is none.
+func projectContainer(ctx context.Context, dockerClient docker.DockerClient, worktree string) (string, string, bool, error) {
+	cwd, err := projectDir()
+	if err != nil {
+		return "", "", false, err
+	}
+	containerName := targetContainerName(cwd, worktree)
+
+	containerID, err := dockerClient.FindContainer(ctx, containerName)
+	if err != nil {
+		return "", "", false, fmt.Errorf("finding container: %w", err)
+	}
+	running := false
+	if containerID != "" {
+		if running, err = dockerClient.IsContainerRunning(ctx, containerID); err != nil {
+			return "", "", false, fmt.Errorf("checking container status: %w", err)
+		}
+	}
+	return containerID, containerName, running, nil
+}
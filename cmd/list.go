@@ -8,6 +8,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/project"
 )
 
 var listCmd = &cobra.Command{
@@ -15,7 +16,9 @@ var listCmd = &cobra.Command{
 	Short: "List running rig containers",
 	Long: `Lists all running rig containers.
 
-Shows the container name, status, and image for each running rig container.`,
+Shows the container name, status, image and project directory for each
+running rig container. Names include a hash of the project path unless
+name: is set in .rig.yml, so same-named directories don't collide.`,
 	Aliases: []string{"ls"},
 	RunE:    runList,
 }
@@ -47,14 +50,19 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	// Print in table format
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "NAME\tSTATUS\tIMAGE")
+	fmt.Fprintln(w, "NAME\tSTATUS\tIMAGE\tPATH")
 	for _, c := range containers {
 		// Extract project name from container name (remove "rig-" prefix)
 		name := c.Name
 		if len(name) > 4 {
 			name = name[4:] // Remove "rig-" prefix for cleaner display
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\n", name, c.Status, c.Image)
+		// Containers from older rig versions have no path label
+		path := c.Labels[project.PathLabel]
+		if path == "" {
+			path = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, c.Status, c.Image, path)
 	}
 	w.Flush()
 
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/jobs"
+	"github.com/wfaler/rig/internal/logs"
+	"github.com/wfaler/rig/internal/supervisor"
+)
+
+var (
+	logsFollow   bool
+	logsSince    string
+	logsService  string
+	logsWorktree string
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [process|job]",
+	Short: "Show the output of the container, a process or a job",
+	Long: `Without arguments, prints the output of the rig container's command with
+the time of each line, stdout and stderr kept apart.
+
+Given a supervised process (see 'rig ps'), e.g. code-server, or a job started
+with 'rig run' (see 'rig jobs'), prints its output instead. Process output
+is stamped with the time of each line. The process can also be given with
+--service.
+
+All of these can be read after the container has stopped, until it is
+removed; with a read-only root filesystem, process and job output is lost
+on stop.
+
+With --follow, keeps printing new output: for a job until it finishes,
+otherwise until interrupted. --since limits the output to the lines written
+from a time on, given as a duration before now (10m, 2h) or a time
+(2026-10-18T15:04:05, 2026-10-18); it doesn't apply to jobs.
+
+Examples:
+  rig logs
+  rig logs --service code-server --since 10m
+  rig logs -f web`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLogs,
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new output")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "Only show output from this time on (e.g. 10m or 2026-10-18T15:04:05)")
+	logsCmd.Flags().StringVarP(&logsService, "service", "s", "", "Show the output of a supervised process, e.g. code-server")
+	logsCmd.Flags().StringVarP(&logsWorktree, "worktree", "w", "", "Use the container of a worktree")
+	rootCmd.AddCommand(logsCmd)
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	name := logsService
+	if len(args) > 0 {
+		if logsService != "" && logsService != args[0] {
+			return fmt.Errorf("give the process either as an argument or with --service, not both")
+		}
+		name = args[0]
+	}
+	var since time.Time
+	if logsSince != "" {
+		var err error
+		if since, err = logs.ParseSince(logsSince, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	containerID, containerName, running, err := projectContainer(ctx, dockerClient, logsWorktree)
+	if err != nil {
+		return err
+	}
+	if containerID == "" {
+		return fmt.Errorf("no container %s (run 'rig up' first)", containerName)
+	}
+
+	if name == "" {
+		return dockerClient.Logs(ctx, containerID, docker.LogsOptions{
+			Follow:     logsFollow,
+			Since:      since,
+			Timestamps: true,
+		}, os.Stdout, os.Stderr)
+	}
+
+	if !running {
+		return stoppedLogs(ctx, dockerClient, containerID, containerName, name, since)
+	}
+	command, process, err := logsCommand(ctx, dockerClient, containerID, name, logsFollow)
+	if err != nil {
+		return err
+	}
+	if !process && !since.IsZero() {
+		return fmt.Errorf("--since doesn't apply to jobs, whose output isn't timestamped")
+	}
+	if _, err := dockerClient.Exec(ctx, containerID, docker.ExecOptions{
+		Cmd:    command,
+		Stdout: processOutput(os.Stdout, since),
+	}); err != nil {
+		return fmt.Errorf("reading output of %s: %w", name, err)
+	}
+	return nil
+}
+
+// logsCommand returns the command printing the output of a process or, if no
+// process has the name, of a job, and whether it is a process
+func logsCommand(ctx context.Context, dockerClient docker.DockerClient, containerID, name string, follow bool) ([]string, bool, error) {
+	// Images from older rig versions have no supervisor, but may still have jobs
+	procs, _ := listProcesses(ctx, dockerClient, containerID)
+	for _, p := range procs {
+		if p.Name == name {
+			return supervisor.LogsCommand(name, follow), true, nil
+		}
+	}
+
+	if !jobs.ValidID(name) {
+		return nil, false, fmt.Errorf("no process or job %s (see 'rig ps' and 'rig jobs')", name)
+	}
+	if _, err := findJob(ctx, dockerClient, containerID, name); err != nil {
+		return nil, false, err
+	}
+	return jobs.LogsCommand(name, follow), false, nil
+}
+
+// stoppedLogs prints the log a process or job left in a stopped container.
+// Nothing new can be written to it, so there is nothing to follow.
+func stoppedLogs(ctx context.Context, dockerClient docker.DockerClient, containerID, containerName, name string, since time.Time) error {
+	if path.Base(name) != name || name == ".." {
+		return fmt.Errorf("invalid process or job name: %s", name)
+	}
+
+	archive, err := dockerClient.CopyFromContainer(ctx, containerID, supervisor.LogPath(name))
+	process := err == nil
+	if !process && jobs.ValidID(name) {
+		if !since.IsZero() {
+			return fmt.Errorf("--since doesn't apply to jobs, whose output isn't timestamped")
+		}
+		archive, err = dockerClient.CopyFromContainer(ctx, containerID, jobs.LogPath(name))
+	}
+	if err != nil {
+		return fmt.Errorf("no output of a process or job %s in the stopped container %s", name, containerName)
+	}
+	defer archive.Close()
+
+	if err := logs.CopyFile(processOutput(os.Stdout, since), archive); err != nil {
+		return fmt.Errorf("reading output of %s: %w", name, err)
+	}
+	return nil
+}
+
+// processOutput returns w, dropping the process log lines written before
+// since unless it is zero
+func processOutput(w io.Writer, since time.Time) io.Writer {
+	if since.IsZero() {
+		return w
+	}
+	return logs.Since(w, supervisor.TimeLayout, since)
+}
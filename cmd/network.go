@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/config"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/egress"
+	"github.com/wfaler/rig/internal/project"
+	"github.com/wfaler/rig/internal/sidecar"
+)
+
+const (
+	// egressProxyAlias is the proxy's hostname on a project's egress network
+	egressProxyAlias = "rig-egress-proxy"
+)
+
+var (
+	netLogFollow   bool
+	netLogWorktree string
+)
+
+var netCmd = &cobra.Command{
+	Use:   "net",
+	Short: "Inspect the container's network policy",
+	Long: `Commands for inspecting network.egress enforcement.
+
+When network.egress is set in .rig.yml, the container is attached to an
+internal Docker network with no route outside the host. Outbound HTTP and
+HTTPS traffic goes through a rig-managed filtering proxy, which only allows
+destinations on the allow-list and logs denied requests.`,
+}
+
+var netLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show requests denied by the egress proxy",
+	Args:  cobra.NoArgs,
+	RunE:  runNetLog,
+}
+
+func init() {
+	netLogCmd.Flags().BoolVarP(&netLogFollow, "follow", "f", false, "Follow new denials")
+	netLogCmd.Flags().StringVarP(&netLogWorktree, "worktree", "w", "", "Show denials for a worktree's container")
+	netCmd.AddCommand(netLogCmd)
+	rootCmd.AddCommand(netCmd)
+}
+
+func runNetLog(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	cwd, err := projectDir()
+	if err != nil {
+		return err
+	}
+	containerName := targetContainerName(cwd, netLogWorktree)
+	proxyName := project.EgressProxyName(containerName)
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	proxyID, err := dockerClient.FindContainer(ctx, proxyName)
+	if err != nil {
+		return fmt.Errorf("finding container: %w", err)
+	}
+	if proxyID == "" {
+		fmt.Printf("No egress proxy found for %s (is network.egress set in %s?)\n", containerName, configFileName)
+		return nil
+	}
+
+	// Denials are JSON lines on the proxy's stdout
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(dockerClient.Logs(ctx, proxyID, docker.LogsOptions{Follow: netLogFollow}, pw, io.Discard))
+	}()
+
+	scanner := bufio.NewScanner(pr)
+	for scanner.Scan() {
+		var entry egress.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Action != egress.ActionDeny {
+			continue
+		}
+		fmt.Printf("%s  %s  %-7s  %s  (from %s)\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			strings.ToUpper(entry.Action), entry.Method, entry.Host, entry.Client)
+	}
+	return scanner.Err()
+}
+
+// startEgressProxy makes sure the egress network and filtering proxy for a
+// container are up. It returns the network the container must join and the
+// proxy environment to set in it.
+func startEgressProxy(ctx context.Context, dockerClient docker.DockerClient, containerName string, cfg *config.Config, ports []string, labels project.Labels) (string, map[string]string, error) {
+	networkName := project.EgressNetworkName(containerName)
+	proxyName := project.EgressProxyName(containerName)
+
+	// Internal network: no route out except through the proxy
+	if err := dockerClient.EnsureNetwork(ctx, networkName, true, labels.Map("")); err != nil {
+		return "", nil, err
+	}
+
+	imageRef, err := ensureSidecarImage(ctx, dockerClient)
+	if err != nil {
+		return "", nil, err
+	}
+
+	proxyID, err := dockerClient.FindContainer(ctx, proxyName)
+	if err != nil {
+		return "", nil, fmt.Errorf("finding container: %w", err)
+	}
+
+	// Recreate the proxy if the config or proxy image changed
+	if proxyID != "" {
+		current, err := dockerClient.GetContainerLabels(ctx, proxyID)
+		if err != nil {
+			return "", nil, err
+		}
+		currentImage, err := dockerClient.GetContainerImage(ctx, proxyID)
+		if err != nil {
+			return "", nil, fmt.Errorf("getting container image: %w", err)
+		}
+		if current[project.ConfigHashLabel] != labels.ConfigHash || currentImage != imageRef {
+			if err := dockerClient.RemoveContainer(ctx, proxyID, true); err != nil {
+				return "", nil, fmt.Errorf("removing old egress proxy: %w", err)
+			}
+			proxyID = ""
+		}
+	}
+
+	if proxyID == "" {
+		// The proxy publishes the project's ports and relays them, since Docker
+		// can't publish ports of containers on internal networks
+		containerPorts := make([]string, 0, len(ports))
+		for _, spec := range ports {
+			parts := strings.Split(spec, ":")
+			containerPorts = append(containerPorts, parts[len(parts)-1])
+		}
+
+		fmt.Printf("Creating egress proxy %s...\n", proxyName)
+		proxyID, err = dockerClient.CreateSidecar(ctx, docker.SidecarConfig{
+			ImageRef:      imageRef,
+			ContainerName: proxyName,
+			Command:       []string{sidecar.EgressProxyBinary},
+			Env: map[string]string{
+				egress.AllowEnv:        strings.Join(cfg.GetEgressAllow(), ","),
+				egress.ForwardHostEnv:  containerName,
+				egress.ForwardPortsEnv: strings.Join(containerPorts, ","),
+			},
+			Ports:       ports,
+			NetworkMode: "bridge",
+			Labels:      labels.Map(project.RoleEgressProxy),
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("creating egress proxy: %w", err)
+		}
+		if err := dockerClient.ConnectNetwork(ctx, networkName, proxyID, []string{egressProxyAlias}); err != nil {
+			return "", nil, err
+		}
+	}
+
+	running, err := dockerClient.IsContainerRunning(ctx, proxyID)
+	if err != nil {
+		return "", nil, fmt.Errorf("checking container status: %w", err)
+	}
+	if !running {
+		if err := dockerClient.StartContainer(ctx, proxyID); err != nil {
+			return "", nil, fmt.Errorf("starting egress proxy: %w", err)
+		}
+	}
+
+	proxyURL := fmt.Sprintf("http://%s:%d", egressProxyAlias, egress.DefaultPort)
+	env := map[string]string{
+		"HTTP_PROXY":  proxyURL,
+		"HTTPS_PROXY": proxyURL,
+		"http_proxy":  proxyURL,
+		"https_proxy": proxyURL,
+		"NO_PROXY":    "localhost,127.0.0.1",
+		"no_proxy":    "localhost,127.0.0.1",
+	}
+	return networkName, env, nil
+}
+
+// stopEgressProxy stops a container's egress proxy, if it has one
+func stopEgressProxy(ctx context.Context, dockerClient docker.DockerClient, containerName string) error {
+	return stopSidecar(ctx, dockerClient, project.EgressProxyName(containerName))
+}
+
+// removeEgressProxy removes a container's egress proxy and network, if any.
+// The container itself must already be removed so the network can be deleted.
+func removeEgressProxy(ctx context.Context, dockerClient docker.DockerClient, containerName string) error {
+	proxyName := project.EgressProxyName(containerName)
+	proxyID, err := dockerClient.FindContainer(ctx, proxyName)
+	if err != nil {
+		return fmt.Errorf("finding container: %w", err)
+	}
+	if proxyID != "" {
+		fmt.Printf("Removing egress proxy %s...\n", proxyName)
+		if err := dockerClient.RemoveContainer(ctx, proxyID, true); err != nil {
+			return fmt.Errorf("removing egress proxy: %w", err)
+		}
+	}
+	return dockerClient.RemoveNetwork(ctx, project.EgressNetworkName(containerName))
+}
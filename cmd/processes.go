@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/supervisor"
+)
+
+var (
+	psWorktree      string
+	restartWorktree string
+)
+
+var psCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "List the supervised processes in the rig container",
+	Long: `Lists the processes declared under processes: in .rig.yml (and
+code-server when enabled), which start with the container and are restarted
+according to their restart policy.
+
+States: running, restarting (waiting to be restarted after exiting), exited
+(not restarted, per its policy) and stopped (not started since the container
+started).`,
+	Args: cobra.NoArgs,
+	RunE: runPs,
+}
+
+var restartCmd = &cobra.Command{
+	Use:   "restart <process>",
+	Short: "Restart a supervised process",
+	Long: `Restarts a process from 'rig ps': a running process is sent SIGTERM
+and started again; an exited process is started again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestart,
+}
+
+func init() {
+	psCmd.Flags().StringVarP(&psWorktree, "worktree", "w", "", "Use the container of a worktree")
+	restartCmd.Flags().StringVarP(&restartWorktree, "worktree", "w", "", "Use the container of a worktree")
+	rootCmd.AddCommand(psCmd, restartCmd)
+}
+
+func runPs(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	containerID, _, err := runningContainer(ctx, dockerClient, psWorktree)
+	if err != nil {
+		return err
+	}
+	procs, err := listProcesses(ctx, dockerClient, containerID)
+	if err != nil {
+		return err
+	}
+
+	if len(procs) == 0 {
+		fmt.Printf("No processes (declare them under processes: in %s)\n", configFileName)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATE\tPID\tRESTARTS\tLAST EXIT")
+	for _, p := range procs {
+		pid, exit := "-", "-"
+		if p.PID != 0 {
+			pid = fmt.Sprint(p.PID)
+		}
+		if p.ExitCode != nil {
+			exit = fmt.Sprint(*p.ExitCode)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", p.Name, p.State, pid, p.Restarts, exit)
+	}
+	w.Flush()
+
+	return nil
+}
+
+func runRestart(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	containerID, _, err := runningContainer(ctx, dockerClient, restartWorktree)
+	if err != nil {
+		return err
+	}
+	if _, err := execOutput(ctx, dockerClient, containerID, supervisor.RestartCommand(args[0])); err != nil {
+		return fmt.Errorf("restarting %s: %w", args[0], err)
+	}
+	fmt.Printf("Restarted %s\n", args[0])
+	return nil
+}
+
+// listProcesses returns the supervised processes in a container
+func listProcesses(ctx context.Context, dockerClient docker.DockerClient, containerID string) ([]supervisor.Status, error) {
+	output, err := execOutput(ctx, dockerClient, containerID, supervisor.ListCommand())
+	if err != nil {
+		return nil, fmt.Errorf("listing processes (is the image from an older rig? run 'rig rebuild'): %w", err)
+	}
+	return supervisor.ParseList(output)
+}
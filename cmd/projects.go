@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/project"
+	"github.com/wfaler/rig/internal/registry"
+)
+
+var projectsCmd = &cobra.Command{
+	Use:   "projects",
+	Short: "List projects rig has been used with",
+	Long: `Lists the projects recorded by 'rig up' and 'rig init', with their
+directory, when they were last used and the config hash their image was
+built from. A project is stale when its .rig.yml has changed since, so the
+next 'rig up' rebuilds the image.
+
+Projects whose directories no longer exist are pruned automatically.
+Start any listed project from anywhere with 'rig up <name>'.`,
+	Args: cobra.NoArgs,
+	RunE: runProjects,
+}
+
+func init() {
+	rootCmd.AddCommand(projectsCmd)
+}
+
+func runProjects(cmd *cobra.Command, args []string) error {
+	path, err := registry.DefaultPath()
+	if err != nil {
+		return err
+	}
+	reg, err := registry.Load(path)
+	if err != nil {
+		return err
+	}
+	if pruned := reg.Prune(); len(pruned) > 0 {
+		if err := reg.Save(); err != nil {
+			return err
+		}
+		for _, e := range pruned {
+			fmt.Printf("Pruned %s (%s no longer exists)\n", e.Name, e.Path)
+		}
+	}
+
+	if len(reg.Projects) == 0 {
+		fmt.Println("No projects (run 'rig up' in a project directory)")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPATH\tLAST USED\tIMAGE\tSTALE")
+	for _, e := range reg.Projects {
+		image, stale := "-", "-"
+		if e.ConfigHash != "" {
+			image = e.ConfigHash
+			stale = "no"
+			if hash, err := project.ComputeConfigHash(project.ConfigPath(e.Path)); err != nil || hash != e.ConfigHash {
+				stale = "yes"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Name, e.Path, e.LastUsed.Local().Format("2006-01-02 15:04"), image, stale)
+	}
+	w.Flush()
+
+	return nil
+}
+
+// recordProject notes a project in the registry. Failures only warn, since
+// the registry is a convenience and must never stop a session.
+func recordProject(name, dir, configHash string) {
+	path, err := registry.DefaultPath()
+	if err == nil {
+		err = registry.Update(path, func(r *registry.Registry) {
+			r.Touch(registry.Entry{Name: name, Path: dir, ConfigHash: configHash}, time.Now().UTC())
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: recording project: %v\n", err)
+	}
+}
+
+// registeredProject returns the directory of a project recorded in the registry
+func registeredProject(name string) (string, error) {
+	path, err := registry.DefaultPath()
+	if err != nil {
+		return "", err
+	}
+	reg, err := registry.Load(path)
+	if err != nil {
+		return "", err
+	}
+	reg.Prune()
+	entry, err := reg.Find(name)
+	if err != nil {
+		return "", err
+	}
+	if !project.ConfigExists(entry.Path) {
+		return "", fmt.Errorf("%s no longer has a %s", entry.Path, project.ConfigFileName)
+	}
+	return entry.Path, nil
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
@@ -33,9 +32,9 @@ func runRebuild(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
 	// Get current working directory
-	cwd, err := os.Getwd()
+	cwd, err := projectDir()
 	if err != nil {
-		return fmt.Errorf("getting current directory: %w", err)
+		return err
 	}
 
 	// Load config
@@ -54,14 +53,13 @@ func runRebuild(cmd *cobra.Command, args []string) error {
 	cfg.ExpandEnvVars()
 
 	// Generate project name and image reference
-	projectName := project.GetProjectName(cwd)
+	projectName := project.GetProjectName(cwd, cfg.Name)
 	configHash, err := project.ComputeConfigHash(configPath)
 	if err != nil {
 		return fmt.Errorf("computing config hash: %w", err)
 	}
 	imageRef := project.ImageRef(projectName, configHash)
 	containerName := project.ContainerName(projectName)
-	imageName := project.ImageName(projectName)
 
 	// Create Docker client
 	dockerClient, err := docker.New()
@@ -83,8 +81,8 @@ func runRebuild(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("checking container status: %w", err)
 		}
 		if running {
-			if err := dockerClient.StopContainer(ctx, containerID); err != nil {
-				return fmt.Errorf("stopping container: %w", err)
+			if err := stopContainer(ctx, dockerClient, containerID); err != nil {
+				return err
 			}
 			// Wait for container to fully stop before removing
 			_ = dockerClient.WaitContainer(ctx, containerID) // Ignore errors
@@ -94,22 +92,31 @@ func runRebuild(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Remove all images with this project name
-	fmt.Printf("Removing images matching %s...\n", imageName)
-	if err := dockerClient.RemoveImagesByName(ctx, imageName); err != nil {
+	// Remove the sidecar proxies so they are recreated with the new image
+	if err := removeSidecars(ctx, dockerClient, containerName); err != nil {
+		return err
+	}
+
+	// Remove all images of this project
+	fmt.Printf("Removing images of %s...\n", projectName)
+	if err := dockerClient.RemoveImagesByLabel(ctx, project.Filter(project.ProjectLabel, projectName)); err != nil {
 		// Don't fail if images don't exist
 		fmt.Printf("Note: %v\n", err)
 	}
 
 	// Generate Dockerfile
 	fmt.Printf("Building image %s...\n", imageRef)
-	dockerfileContent, err := dockerfile.Generate(cfg)
+	dockerfileContent, err := dockerfile.Generate(cfg, cfg.ContainerWorkspace(cwd))
 	if err != nil {
 		return fmt.Errorf("generating dockerfile: %w", err)
 	}
 
 	// Build image
-	if err := dockerClient.BuildImage(ctx, dockerfileContent, imageRef); err != nil {
+	buildLabels, err := imageLabels(projectName, cwd, configHash, cfg)
+	if err != nil {
+		return err
+	}
+	if err := dockerClient.BuildImage(ctx, dockerfileContent, imageRef, buildLabels); err != nil {
 		return fmt.Errorf("building image: %w", err)
 	}
 
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/recording"
+)
+
+var (
+	replaySpeed     float64
+	replayIdleLimit float64
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <recording>",
+	Short: "Play back a recorded session in the terminal",
+	Long: `Plays back a session recorded with 'rig up --record' or
+'rig agent --record'. Recordings are asciicast v2 files under
+.rig/recordings/, which also play in asciinema; a file name there can be
+given without its directory.
+
+Examples:
+  rig replay 20261018-150405-session-1.cast
+  rig replay --speed 4 --idle-limit 1 .rig/recordings/20261018-150405-agent-claude.cast`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReplay,
+}
+
+func init() {
+	replayCmd.Flags().Float64VarP(&replaySpeed, "speed", "s", 1, "Playback speed factor")
+	replayCmd.Flags().Float64Var(&replayIdleLimit, "idle-limit", 0, "Shorten pauses to at most this many seconds (0: keep them)")
+	rootCmd.AddCommand(replayCmd)
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	if replaySpeed <= 0 {
+		return fmt.Errorf("--speed must be greater than 0")
+	}
+	if replayIdleLimit < 0 {
+		return fmt.Errorf("--idle-limit must not be negative")
+	}
+
+	path := args[0]
+	if _, err := os.Stat(path); os.IsNotExist(err) && !filepath.IsAbs(path) {
+		if dir, err := projectDir(); err == nil {
+			candidate := filepath.Join(dir, recording.Dir, path)
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+			}
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening recording: %w", err)
+	}
+	defer f.Close()
+
+	return recording.Play(f, os.Stdout, recording.PlayOptions{
+		Speed:   replaySpeed,
+		MaxIdle: time.Duration(replayIdleLimit * float64(time.Second)),
+	})
+}
+
+// startRecording starts a recording named name in the project's recordings
+// directory, sized like the terminal, and returns it with its path
+func startRecording(projectDir, name, title string, started time.Time) (*recording.Recorder, string, error) {
+	width, height := 80, 24
+	if ws, err := term.GetWinsize(os.Stdout.Fd()); err == nil && ws.Width > 0 {
+		width, height = int(ws.Width), int(ws.Height)
+	}
+	path := recording.Path(projectDir, name, started)
+	rec, err := recording.Create(path, width, height, title)
+	if err != nil {
+		return nil, "", err
+	}
+	return rec, path, nil
+}
+
+// finishRecording closes a recording and tells where it was saved
+func finishRecording(rec *recording.Recorder, projectDir, path string) {
+	if err := rec.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if rel, err := filepath.Rel(projectDir, path); err == nil {
+		path = rel
+	}
+	fmt.Fprintf(os.Stderr, "Recording saved to %s (play it with 'rig replay')\n", path)
+}
@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/config"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/git"
+	"github.com/wfaler/rig/internal/project"
+	"github.com/wfaler/rig/internal/review"
+)
+
+var (
+	reviewWorktree string
+	reviewStat     bool
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Review changes made in an isolated workspace",
+	Long: `Shows how the container's copy of the workspace differs from the host tree.
+
+With isolation: review in .rig.yml, the container works on a copy of the
+project in a Docker volume instead of the host directory. Nothing reaches
+your checkout until you apply it:
+
+  rig review                    Show the changes as a diff
+  rig review --stat             List changed files only
+  rig review apply              Apply all changes to the host tree
+  rig review apply <file>...    Apply selected files
+  rig review discard            Reset the copy to the host tree
+  rig review discard <file>...  Reset selected files
+
+Commits made inside the container are not applied; only working tree files
+are compared.`,
+	Args: cobra.NoArgs,
+	RunE: runReview,
+}
+
+var reviewApplyCmd = &cobra.Command{
+	Use:   "apply [file...]",
+	Short: "Apply changes from the isolated workspace to the host tree",
+	RunE:  runReviewApply,
+}
+
+var reviewDiscardCmd = &cobra.Command{
+	Use:   "discard [file...]",
+	Short: "Reset the isolated workspace to the host tree",
+	RunE:  runReviewDiscard,
+}
+
+func init() {
+	reviewCmd.PersistentFlags().StringVarP(&reviewWorktree, "worktree", "w", "", "Review a worktree's container")
+	reviewCmd.Flags().BoolVar(&reviewStat, "stat", false, "List changed files without diffs")
+	reviewCmd.AddCommand(reviewApplyCmd)
+	reviewCmd.AddCommand(reviewDiscardCmd)
+	rootCmd.AddCommand(reviewCmd)
+}
+
+// workspaceReview is a snapshot of an isolated workspace compared with the host tree
+type workspaceReview struct {
+	hostDir     string
+	copyDir     string // Temporary extraction of the container's workspace
+	containerID string
+	workspace   string // Container path of the workspace
+	changes     []review.Change
+}
+
+// close removes the temporary copy
+func (r *workspaceReview) close() {
+	os.RemoveAll(r.copyDir)
+}
+
+// selectChanges returns the changes for the given paths, or all changes if none are given
+func (r *workspaceReview) selectChanges(paths []string) ([]review.Change, error) {
+	if len(paths) == 0 {
+		return r.changes, nil
+	}
+	byPath := make(map[string]review.Change, len(r.changes))
+	for _, c := range r.changes {
+		byPath[c.Path] = c
+	}
+	selected := make([]review.Change, 0, len(paths))
+	for _, p := range paths {
+		c, ok := byPath[filepath.ToSlash(filepath.Clean(p))]
+		if !ok {
+			return nil, fmt.Errorf("%s has no changes to review", p)
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+func runReview(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	r, err := loadReview(ctx, dockerClient)
+	if err != nil {
+		return err
+	}
+	defer r.close()
+
+	if len(r.changes) == 0 {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	for _, c := range r.changes {
+		if reviewStat {
+			fmt.Printf("%-8s  %s\n", c.Kind, c.Path)
+			continue
+		}
+		oldPath := filepath.Join(r.hostDir, filepath.FromSlash(c.Path))
+		newPath := filepath.Join(r.copyDir, filepath.FromSlash(c.Path))
+		switch c.Kind {
+		case review.Added:
+			oldPath = os.DevNull
+		case review.Deleted:
+			newPath = os.DevNull
+		}
+		diff, err := git.DiffNoIndex(oldPath, newPath)
+		if err != nil {
+			return err
+		}
+		// Show paths relative to the workspace rather than the temporary copy
+		diff = strings.ReplaceAll(diff, r.hostDir+string(filepath.Separator), "a/")
+		diff = strings.ReplaceAll(diff, r.copyDir+string(filepath.Separator), "b/")
+		fmt.Print(diff)
+	}
+	return nil
+}
+
+func runReviewApply(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	r, err := loadReview(ctx, dockerClient)
+	if err != nil {
+		return err
+	}
+	defer r.close()
+
+	changes, err := r.selectChanges(args)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	if err := review.Apply(r.hostDir, r.copyDir, changes); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		fmt.Printf("Applied %s (%s)\n", c.Path, c.Kind)
+	}
+	return nil
+}
+
+func runReviewDiscard(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	r, err := loadReview(ctx, dockerClient)
+	if err != nil {
+		return err
+	}
+	defer r.close()
+
+	changes, err := r.selectChanges(args)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	// Files added in the copy are removed with an exec, which needs the container running
+	running, err := dockerClient.IsContainerRunning(ctx, r.containerID)
+	if err != nil {
+		return fmt.Errorf("checking container status: %w", err)
+	}
+	if !running {
+		if err := dockerClient.StartContainer(ctx, r.containerID); err != nil {
+			return fmt.Errorf("starting container: %w", err)
+		}
+		defer dockerClient.StopContainer(ctx, r.containerID)
+	}
+
+	var restore, remove []string
+	for _, c := range changes {
+		if c.Kind == review.Added {
+			remove = append(remove, path.Join(r.workspace, c.Path))
+		} else {
+			restore = append(restore, c.Path)
+		}
+	}
+
+	if len(remove) > 0 {
+		code, err := dockerClient.Exec(ctx, r.containerID, docker.ExecOptions{
+			Cmd:    append([]string{"rm", "-f", "--"}, remove...),
+			Stdout: io.Discard,
+			Stderr: os.Stderr,
+		})
+		if err != nil {
+			return fmt.Errorf("removing added files: %w", err)
+		}
+		if code != 0 {
+			return fmt.Errorf("removing added files: rm exited with code %d", code)
+		}
+	}
+
+	if len(restore) > 0 {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(review.ArchiveFiles(r.hostDir, restore, pw))
+		}()
+		if err := dockerClient.CopyToContainer(ctx, r.containerID, r.workspace, pr); err != nil {
+			return fmt.Errorf("restoring files: %w", err)
+		}
+	}
+
+	for _, c := range changes {
+		fmt.Printf("Discarded %s (%s)\n", c.Path, c.Kind)
+	}
+	return nil
+}
+
+// loadReview copies the container's isolated workspace to a temporary
+// directory and compares it with the host tree
+func loadReview(ctx context.Context, dockerClient docker.DockerClient) (*workspaceReview, error) {
+	cwd, err := projectDir()
+	if err != nil {
+		return nil, err
+	}
+	containerName := targetContainerName(cwd, reviewWorktree)
+	hostDir := cwd
+	if reviewWorktree != "" {
+		hostDir = project.WorktreeDir(cwd, reviewWorktree)
+	}
+
+	exists, err := dockerClient.VolumeExists(ctx, project.WorkspaceVolumeName(containerName))
+	if err != nil {
+		return nil, err
+	}
+	containerID, err := dockerClient.FindContainer(ctx, containerName)
+	if err != nil {
+		return nil, fmt.Errorf("finding container: %w", err)
+	}
+	if !exists || containerID == "" {
+		return nil, fmt.Errorf("no isolated workspace found for %s (is isolation: review set in %s?)", containerName, configFileName)
+	}
+
+	workspace, err := dockerClient.GetContainerWorkingDir(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	copyDir, err := os.MkdirTemp("", "rig-review-")
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	r := &workspaceReview{hostDir: hostDir, copyDir: copyDir, containerID: containerID, workspace: workspace}
+
+	archive, err := dockerClient.CopyFromContainer(ctx, containerID, workspace)
+	if err != nil {
+		r.close()
+		return nil, err
+	}
+	defer archive.Close()
+	if err := review.Extract(archive, copyDir); err != nil {
+		r.close()
+		return nil, fmt.Errorf("extracting workspace copy: %w", err)
+	}
+
+	// Extra workspace folders are mounted inside the copy but aren't part of it
+	cfg, err := config.Load(project.ConfigPath(cwd))
+	if err != nil {
+		r.close()
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	if !cfg.IsWorkspaceMirrored() {
+		for _, f := range cfg.WorkspaceFolders {
+			if err := os.RemoveAll(filepath.Join(copyDir, f.GetName())); err != nil {
+				r.close()
+				return nil, err
+			}
+		}
+	}
+
+	if r.changes, err = review.Compare(hostDir, copyDir); err != nil {
+		r.close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// seedWorkspaceCopy fills a new container's isolated workspace volume,
+// mounted at workspace, with the host tree. The container must not be started yet.
+func seedWorkspaceCopy(ctx context.Context, dockerClient docker.DockerClient, containerID, dir, workspace string) error {
+	fmt.Printf("Copying %s into isolated workspace...\n", dir)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(review.Archive(dir, pw))
+	}()
+	if err := dockerClient.CopyToContainer(ctx, containerID, workspace, pr); err != nil {
+		return fmt.Errorf("seeding isolated workspace: %w", err)
+	}
+	return nil
+}
+
+// removeWorkspaceCopy removes a container's isolated workspace volume, if any,
+// discarding changes that were not applied. The container must already be removed.
+func removeWorkspaceCopy(ctx context.Context, dockerClient docker.DockerClient, containerName string) error {
+	volume := project.WorkspaceVolumeName(containerName)
+	exists, err := dockerClient.VolumeExists(ctx, volume)
+	if err != nil || !exists {
+		return err
+	}
+	fmt.Printf("Removing isolated workspace %s...\n", volume)
+	return dockerClient.RemoveVolume(ctx, volume)
+}
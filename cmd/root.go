@@ -1,15 +1,20 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
 )
 
+// Version is the rig release, set at build time with -ldflags "-X github.com/wfaler/rig/cmd.Version=..."
+var Version = "dev"
+
 var rootCmd = &cobra.Command{
-	Use:   "rig",
-	Short: "Create dockerized development sandboxes for AI agents",
+	Use:     "rig",
+	Version: Version,
+	Short:   "Create dockerized development sandboxes for AI agents",
 	Long: `Rig creates isolated Docker containers configured with language
 runtimes, build tools, and AI agent CLIs (Claude, Gemini, Codex, GitHub CLI).
 
@@ -18,16 +23,39 @@ when configuration changes.
 
 Commands:
   rig up        Enter the container (uses configured shell)
+  rig attach    Resume a detached session
+  rig replay    Play back a recorded session
   rig down      Stop the container (preserves state)
   rig destroy   Stop container and remove images
   rig list      List running rig containers
+  rig status    Show the project's container, image and pending rebuild
+  rig projects  List projects and where they live
+  rig agent     Run an AI agent headless in the container
+  rig exec      Run a command in the container, exiting with its status
+  rig run       Run a command as a job, optionally in the background
+  rig task      Run a task from .rig.yml
+  rig ps        List supervised processes
   rig init      Initialize a new workspace with .rig.yml
   rig rebuild   Force a clean rebuild of the image`,
 }
 
+// exitError carries the exit code of a command run inside the container,
+// so rig can exit with the same status
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
 // Execute runs the root command
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
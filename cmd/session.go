@@ -3,155 +3,448 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/wfaler/rig/internal/config"
 	"github.com/wfaler/rig/internal/docker"
 	"github.com/wfaler/rig/internal/dockerfile"
+	"github.com/wfaler/rig/internal/dockerproxy"
+	"github.com/wfaler/rig/internal/git"
 	"github.com/wfaler/rig/internal/project"
+	"github.com/wfaler/rig/internal/security"
+	"github.com/wfaler/rig/internal/sessions"
+	"github.com/wfaler/rig/internal/workspace"
 )
 
 const configFileName = ".rig.yml"
 
+// sessionOptions selects which container of a project a session targets
+type sessionOptions struct {
+	project  string // Registered project to start; empty for the current directory's
+	worktree string // Branch of a rig-managed worktree; empty for the main checkout
+	session  string // Interactive session to start or resume; empty for a new one
+	record   bool   // Record the session's terminal under .rig/recordings
+	offline  bool   // Run without any network, overriding the config
+
+	checkpoint bool   // Record a git checkpoint before starting, even if not configured
+	purpose    string // What the session is for, e.g. "rig up", used in checkpoint messages
+}
+
+// session holds the resolved state of a project whose container is running
+type session struct {
+	cwd           string
+	subdir        string            // Directory rig was run from, relative to cwd
+	workDir       string            // Host directory mounted as the workspace
+	offline       bool              // Container has no network
+	dockerAccess  string            // Effective docker_access mode
+	security      *security.Profile // Hardened protections, nil for the default profile
+	cfg           *config.Config
+	projectName   string
+	containerName string
+	containerID   string
+}
+
 // runSession handles the complete flow of loading config, building image,
 // creating container, and attaching to run a command
-func runSession(command []string) error {
+func runSession(command []string, opts sessionOptions) error {
 	ctx := context.Background()
 
-	// Get current working directory
-	cwd, err := os.Getwd()
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	sess, err := startSession(ctx, dockerClient, opts)
+	if err != nil {
+		return err
+	}
+
+	if sess.security != nil {
+		fmt.Println("Security: hardened")
+		for _, protection := range sess.security.Protections() {
+			fmt.Printf("  - %s\n", protection)
+		}
+		if sess.dockerAccess == "full" {
+			fmt.Println("  Warning: docker_access: full gives the container the host Docker daemon, which can start privileged containers; remove it to use the proxy")
+		}
+	}
+
+	if sess.offline && sess.cfg.IsCodeServerEnabled() {
+		fmt.Printf("Offline mode: run 'rig forward %d' in another terminal to reach code-server\n", sess.cfg.GetCodeServerPort())
+	}
+
+	// Use configured shell if no command specified
+	if len(command) == 0 {
+		command = []string{"/bin/" + sess.cfg.GetShell()}
+	}
+
+	// Run it in a tmux session, which outlives the terminal and can be resumed with 'rig attach'
+	name := opts.session
+	if name == "" {
+		live, err := listSessions(ctx, dockerClient, sess.containerID)
+		if err != nil {
+			return err
+		}
+		name = sessions.NextName(live)
+	}
+	attach := docker.ExecOptions{Cmd: sessions.NewCommand(name, sess.startDir(), command)}
+	if opts.record {
+		rec, path, err := startRecording(sess.cwd, "session-"+name, fmt.Sprintf("%s: session %s", sess.projectName, name), time.Now())
+		if err != nil {
+			return err
+		}
+		defer finishRecording(rec, sess.cwd, path)
+		attach.Stdout = io.MultiWriter(os.Stdout, rec)
+		attach.Resized = rec.Resize
+	}
+	return attachSession(ctx, dockerClient, sess.containerID, attach, name, opts.worktree, sess.cfg.GetDetachKeys())
+}
+
+// startDir returns the container directory matching the host directory rig
+// was run from, so a session started in services/api opens there
+func (s *session) startDir() string {
+	return path.Join(s.cfg.ContainerWorkspace(s.workDir), s.subdir)
+}
+
+// startSession loads config, builds the image if needed, and makes sure the
+// project container exists and is running
+func startSession(ctx context.Context, dockerClient docker.DockerClient, opts sessionOptions) (*session, error) {
+	// Find the project the current directory belongs to, or the named one
+	var cwd, subdir string
+	var err error
+	if opts.project != "" {
+		cwd, err = registeredProject(opts.project)
+	} else {
+		cwd, subdir, err = findProject()
+	}
 	if err != nil {
-		return fmt.Errorf("getting current directory: %w", err)
+		return nil, err
 	}
 
 	// Load config
 	configPath := filepath.Join(cwd, configFileName)
 	cfg, err := config.Load(configPath)
 	if err != nil {
-		return fmt.Errorf("loading config: %w", err)
+		return nil, fmt.Errorf("loading config: %w", err)
 	}
 
 	// Validate config
 	if err := cfg.Validate(); err != nil {
-		return fmt.Errorf("invalid config: %w", err)
+		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
 	// Expand environment variables
 	cfg.ExpandEnvVars()
 
-	// Use configured shell if no command specified
-	if len(command) == 0 {
-		command = []string{"/bin/" + cfg.GetShell()}
-	}
-
 	// Generate project name and image reference
-	projectName := project.GetProjectName(cwd)
+	projectName := project.GetProjectName(cwd, cfg.Name)
 	configHash, err := project.ComputeConfigHash(configPath)
 	if err != nil {
-		return fmt.Errorf("computing config hash: %w", err)
+		return nil, fmt.Errorf("computing config hash: %w", err)
 	}
 	imageRef := project.ImageRef(projectName, configHash)
 	containerName := project.ContainerName(projectName)
 
-	// Create Docker client
-	dockerClient, err := docker.New()
-	if err != nil {
-		return fmt.Errorf("creating docker client: %w", err)
+	sess := &session{
+		cwd:           cwd,
+		subdir:        subdir,
+		workDir:       cwd,
+		cfg:           cfg,
+		projectName:   projectName,
+		containerName: containerName,
+	}
+
+	// Worktrees share the project's image but get their own container and mount
+	var binds []string
+	ports := cfg.GetAllPorts()
+	if opts.worktree != "" {
+		sess.workDir = project.WorktreeDir(cwd, opts.worktree)
+		sess.containerName = project.WorktreeContainerName(projectName, opts.worktree)
+		containerName = sess.containerName
+		if _, err := os.Stat(sess.workDir); err != nil {
+			return nil, fmt.Errorf("worktree for branch %s not found (run 'rig worktree add %s')", opts.worktree, opts.worktree)
+		}
+		// The worktree's .git file points at the shared git directory by absolute
+		// path, so mount it at the same path for git to work inside the container
+		gitDir, err := git.CommonDir(cwd)
+		if err != nil {
+			return nil, fmt.Errorf("locating git directory: %w", err)
+		}
+		binds = append(binds, fmt.Sprintf("%s:%s:rw", gitDir, gitDir))
+		// Publish on ephemeral host ports so worktree containers don't collide
+		ports = ephemeralPorts(ports)
+	}
+
+	// A worktree may be on a branch without the directory rig was run from
+	if _, err := os.Stat(filepath.Join(sess.workDir, subdir)); err != nil {
+		sess.subdir = ""
+	}
+
+	labels := resourceLabels(projectName, cwd, sess.workDir, configHash, cfg)
+
+	// Snapshot the workspace so an agent's changes can be rolled back
+	if opts.checkpoint || cfg.Checkpoints {
+		recordCheckpoint(sess.workDir, opts.purpose)
 	}
-	defer dockerClient.Close()
 
 	// Check if image exists
 	imageExists, err := dockerClient.ImageExists(ctx, imageRef)
 	if err != nil {
-		return fmt.Errorf("checking image: %w", err)
+		return nil, fmt.Errorf("checking image: %w", err)
 	}
 
 	if !imageExists {
 		// Generate Dockerfile
 		fmt.Printf("Building image %s...\n", imageRef)
-		dockerfileContent, err := dockerfile.Generate(cfg)
+		dockerfileContent, err := dockerfile.Generate(cfg, cfg.ContainerWorkspace(cwd))
 		if err != nil {
-			return fmt.Errorf("generating dockerfile: %w", err)
+			return nil, fmt.Errorf("generating dockerfile: %w", err)
 		}
 
-		// Build image
-		if err := dockerClient.BuildImage(ctx, dockerfileContent, imageRef); err != nil {
-			return fmt.Errorf("building image: %w", err)
+		// Build image; worktrees share it, so it is labeled with the project directory
+		buildLabels, err := imageLabels(projectName, cwd, configHash, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := dockerClient.BuildImage(ctx, dockerfileContent, imageRef, buildLabels); err != nil {
+			return nil, fmt.Errorf("building image: %w", err)
 		}
 		fmt.Println("Image built successfully")
 	}
+	recordProject(projectName, cwd, configHash)
+
+	// Offline: no network, no Docker socket and no published ports
+	sess.offline = opts.offline || cfg.IsOffline()
+	networkMode := "bridge"
+	egressNetwork := ""
+	env := make(map[string]string, len(cfg.Env))
+	for k, v := range cfg.Env {
+		env[k] = v
+	}
+	if sess.offline {
+		networkMode = "none"
+		ports = nil
+	} else if cfg.IsEgressRestricted() {
+		// Restricted egress: join an internal network behind the filtering proxy
+		networkName, proxyEnv, err := startEgressProxy(ctx, dockerClient, containerName, cfg, ports, labels)
+		if err != nil {
+			return nil, err
+		}
+		networkMode = networkName
+		egressNetwork = networkName
+		for k, v := range proxyEnv {
+			env[k] = v
+		}
+		// Ports are published by the proxy instead
+		ports = nil
+	}
+
+	// Docker access: the host socket, a filtering proxy socket, or nothing
+	dockerAccess := cfg.GetDockerAccess()
+	if sess.offline {
+		dockerAccess = "none"
+	}
+	sess.dockerAccess = dockerAccess
+	dockerSocket := ""
+	switch dockerAccess {
+	case "full":
+		dockerSocket = dockerproxy.UpstreamSocket
+		// The raw socket can't be intercepted; tools may add this label themselves
+		env[childLabelEnv] = childLabel(containerName)
+	case "proxy":
+		// Children can't bind-mount the host tree past review isolation
+		proxyWorkspace := labels.WorkDir
+		if cfg.IsReviewIsolated() {
+			proxyWorkspace = ""
+		}
+		proxyBinds, proxyEnv, err := startDockerProxy(ctx, dockerClient, containerName, proxyWorkspace, egressNetwork, labels)
+		if err != nil {
+			return nil, err
+		}
+		binds = append(binds, proxyBinds...)
+		for k, v := range proxyEnv {
+			env[k] = v
+		}
+	}
+
+	// Extra workspace folders are resolved against the project, not a worktree
+	folders, err := workspace.Folders(cfg, cwd, cfg.ContainerWorkspace(sess.workDir))
+	if err != nil {
+		return nil, err
+	}
+	// Under review isolation, changes only reach the host through 'rig review'
+	binds = append(binds, workspace.Binds(folders, cfg.IsReviewIsolated())...)
+	if len(folders) > 0 && cfg.IsCodeServerEnabled() {
+		data, err := workspace.CodeWorkspace(cfg.ContainerWorkspace(sess.workDir), folders)
+		if err != nil {
+			return nil, fmt.Errorf("generating code-server workspace: %w", err)
+		}
+		file, err := workspace.WriteCodeWorkspace(cwd, containerName, data)
+		if err != nil {
+			return nil, err
+		}
+		binds = append(binds, fmt.Sprintf("%s:%s:ro", file, workspace.CodeWorkspacePath))
+		env[workspace.CodeWorkspaceEnv] = workspace.CodeWorkspacePath
+	}
+
+	// Hardened profile: dropped capabilities, no-new-privileges, seccomp, optional read-only root
+	if cfg.IsHardened() {
+		sess.security, err = security.Hardened(cfg.Security, dockerAccess, cwd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Review isolation: the container works on a copy of the workspace in a volume
+	workspaceVolume := ""
+	seedWorkspace := false
+	if cfg.IsReviewIsolated() {
+		workspaceVolume = project.WorkspaceVolumeName(containerName)
+		exists, err := dockerClient.VolumeExists(ctx, workspaceVolume)
+		if err != nil {
+			return nil, err
+		}
+		seedWorkspace = !exists
+		if err := dockerClient.EnsureVolume(ctx, workspaceVolume, labels.Map("")); err != nil {
+			return nil, err
+		}
+	}
 
 	// Find existing container
 	containerID, err := dockerClient.FindContainer(ctx, containerName)
 	if err != nil {
-		return fmt.Errorf("finding container: %w", err)
+		return nil, fmt.Errorf("finding container: %w", err)
+	}
+	if containerID == "" && opts.worktree == "" {
+		warnLegacyContainer(ctx, dockerClient, cwd, containerName)
 	}
 
 	if containerID != "" {
 		// Container exists - check its state and image
 		running, err := dockerClient.IsContainerRunning(ctx, containerID)
 		if err != nil {
-			return fmt.Errorf("checking container status: %w", err)
+			return nil, fmt.Errorf("checking container status: %w", err)
 		}
 
 		currentImage, err := dockerClient.GetContainerImage(ctx, containerID)
 		if err != nil {
-			return fmt.Errorf("getting container image: %w", err)
+			return nil, fmt.Errorf("getting container image: %w", err)
 		}
 
-		if currentImage == imageRef {
-			// Same image - reuse container
+		currentNetwork, err := dockerClient.GetContainerNetworkMode(ctx, containerID)
+		if err != nil {
+			return nil, err
+		}
+
+		if currentImage == imageRef && currentNetwork == networkMode {
+			// Same image and network - reuse container
+			sess.containerID = containerID
 			if running {
-				// Already running - just exec into it
 				fmt.Printf("Attaching to running container %s...\n", containerName)
-				if err := dockerClient.Attach(ctx, containerID, command); err != nil {
-					return fmt.Errorf("attaching to container: %w", err)
-				}
-				return nil
+				return sess, nil
 			}
 			// Same image but stopped - start it
 			fmt.Printf("Starting container %s...\n", containerName)
 			if err := dockerClient.StartContainer(ctx, containerID); err != nil {
-				return fmt.Errorf("starting container: %w", err)
+				return nil, fmt.Errorf("starting container: %w", err)
 			}
-			if err := dockerClient.Attach(ctx, containerID, command); err != nil {
-				return fmt.Errorf("attaching to container: %w", err)
-			}
-			return nil
+			return sess, nil
 		}
 
-		// Different image - need to remove and recreate
-		fmt.Printf("Config changed, recreating container...\n")
+		// Different image or network - need to remove and recreate
+		if currentImage == imageRef {
+			fmt.Printf("Network mode changed to %s, recreating container...\n", networkMode)
+		} else {
+			fmt.Printf("Config changed, recreating container...\n")
+		}
 		if err := dockerClient.RemoveContainer(ctx, containerID, true); err != nil {
-			return fmt.Errorf("removing old container: %w", err)
+			return nil, fmt.Errorf("removing old container: %w", err)
+		}
+		// Clean up sidecars that are no longer needed
+		if sess.offline || !cfg.IsEgressRestricted() {
+			if err := removeEgressProxy(ctx, dockerClient, containerName); err != nil {
+				return nil, err
+			}
+		}
+		if dockerAccess != "proxy" {
+			if err := removeDockerProxy(ctx, dockerClient, containerName); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	// Create new container
+	// Create new container, keeping it alive with the configured shell
 	fmt.Printf("Creating container %s...\n", containerName)
-	containerID, err = dockerClient.CreateContainer(ctx, docker.ContainerConfig{
-		ImageRef:      imageRef,
-		ContainerName: containerName,
-		WorkDir:       cwd,
-		Ports:         cfg.GetAllPorts(),
-		Env:           cfg.Env,
-		Command:       command,
-	})
+	stopTimeout := cfg.GetStopTimeout()
+	containerCfg := docker.ContainerConfig{
+		ImageRef:        imageRef,
+		ContainerName:   containerName,
+		WorkDir:         sess.workDir,
+		WorkspaceVolume: workspaceVolume,
+		WorkspaceDir:    cfg.ContainerWorkspace(sess.workDir),
+		Binds:           binds,
+		NetworkMode:     networkMode,
+		DockerSocket:    dockerSocket,
+		Ports:           ports,
+		Env:             env,
+		Command:         []string{"/bin/" + cfg.GetShell()},
+		Labels:          labels.Map(project.RoleWorkspace),
+		StopSignal:      cfg.GetStopSignal(),
+		StopTimeout:     &stopTimeout,
+	}
+	if sess.security != nil {
+		containerCfg.CapDrop = sess.security.CapDrop
+		containerCfg.CapAdd = sess.security.CapAdd
+		containerCfg.SecurityOpt = sess.security.SecurityOpt
+		containerCfg.ReadOnlyRootfs = sess.security.ReadOnlyRootfs
+		containerCfg.Tmpfs = sess.security.Tmpfs
+	}
+	containerID, err = dockerClient.CreateContainer(ctx, containerCfg)
 	if err != nil {
-		return fmt.Errorf("creating container: %w", err)
+		return nil, fmt.Errorf("creating container: %w", err)
+	}
+
+	// Fill a new isolated workspace before anything runs in it
+	if seedWorkspace {
+		if err := seedWorkspaceCopy(ctx, dockerClient, containerID, sess.workDir, containerCfg.WorkspaceDir); err != nil {
+			return nil, err
+		}
 	}
 
 	// Start container
 	fmt.Printf("Starting container...\n")
 	if err := dockerClient.StartContainer(ctx, containerID); err != nil {
-		return fmt.Errorf("starting container: %w", err)
+		return nil, fmt.Errorf("starting container: %w", err)
 	}
 
-	// Attach to container
-	if err := dockerClient.Attach(ctx, containerID, command); err != nil {
-		return fmt.Errorf("attaching to container: %w", err)
+	sess.containerID = containerID
+	return sess, nil
+}
+
+// ephemeralPorts rewrites port specs to publish each container port on a
+// host port chosen by Docker
+func ephemeralPorts(ports []string) []string {
+	result := make([]string, 0, len(ports))
+	for _, spec := range ports {
+		parts := strings.Split(spec, ":")
+		result = append(result, "0:"+parts[len(parts)-1])
 	}
+	return result
+}
 
-	return nil
+// targetContainerName returns the container name for the project in dir,
+// or for one of its worktrees
+func targetContainerName(dir, worktree string) string {
+	projectName := resolveProjectName(dir)
+	if worktree != "" {
+		return project.WorktreeContainerName(projectName, worktree)
+	}
+	return project.ContainerName(projectName)
 }
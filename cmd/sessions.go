@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/config"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/project"
+	"github.com/wfaler/rig/internal/sessions"
+)
+
+var (
+	attachWorktree   string
+	attachReadOnly   bool
+	sessionsWorktree string
+)
+
+var attachCmd = &cobra.Command{
+	Use:   "attach [session]",
+	Short: "Resume an interactive session in the rig container",
+	Long: `Attaches to a session started with 'rig up' and left with the detach keys
+(ctrl-p ctrl-q by default; set detach_keys in .rig.yml).
+
+Sessions keep running in the container while detached, also when the
+terminal that started them is closed. Without a name, attaches to the most
+recently used session. List sessions with 'rig sessions'.
+
+With --readonly, watches a session, e.g. an agent at work, without sending
+it any input: keystrokes other than the detach keys are dropped and the
+terminal's size doesn't affect the session. Any number of observers can
+watch a session at once.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAttach,
+}
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List interactive sessions in the rig container",
+	Args:  cobra.NoArgs,
+	RunE:  runSessions,
+}
+
+func init() {
+	attachCmd.Flags().StringVarP(&attachWorktree, "worktree", "w", "", "Attach in the container of a worktree")
+	attachCmd.Flags().BoolVarP(&attachReadOnly, "readonly", "r", false, "Watch the session without sending input")
+	sessionsCmd.Flags().StringVarP(&sessionsWorktree, "worktree", "w", "", "Use the container of a worktree")
+	rootCmd.AddCommand(attachCmd, sessionsCmd)
+}
+
+func runAttach(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if attachReadOnly && len(args) == 0 {
+		return fmt.Errorf("--readonly needs the session to watch (see 'rig sessions')")
+	}
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	containerID, containerName, err := runningContainer(ctx, dockerClient, attachWorktree)
+	if err != nil {
+		return err
+	}
+	live, err := listSessions(ctx, dockerClient, containerID)
+	if err != nil {
+		return err
+	}
+	if len(live) == 0 {
+		return fmt.Errorf("no sessions in %s (start one with 'rig up')", containerName)
+	}
+
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+		if !hasSession(live, name) {
+			return fmt.Errorf("no session %s in %s (see 'rig sessions')", name, containerName)
+		}
+	}
+
+	opts := docker.ExecOptions{Cmd: sessions.AttachCommand(name)}
+	if attachReadOnly {
+		opts = docker.ExecOptions{Cmd: sessions.ObserveCommand(name), ReadOnly: true}
+	}
+	return attachSession(ctx, dockerClient, containerID, opts, name, attachWorktree, detachKeys())
+}
+
+func runSessions(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	containerID, _, err := runningContainer(ctx, dockerClient, sessionsWorktree)
+	if err != nil {
+		return err
+	}
+	live, err := listSessions(ctx, dockerClient, containerID)
+	if err != nil {
+		return err
+	}
+
+	if len(live) == 0 {
+		fmt.Println("No sessions (start one with 'rig up')")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tATTACHED\tCREATED\tCOMMAND")
+	for _, s := range live {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", s.Name, s.Clients, s.Created.Local().Format("2006-01-02 15:04:05"), s.Command)
+	}
+	return w.Flush()
+}
+
+// attachSession runs a tmux client for a session in the container, opts
+// holding its command. name is used in the hint printed on detach; it is
+// empty when tmux picks the session.
+func attachSession(ctx context.Context, dockerClient docker.DockerClient, containerID string, opts docker.ExecOptions, name, worktree string, keys []byte) error {
+	opts.DetachKeys = keys
+	opts.DetachInput = []byte(sessions.DetachSequence)
+	err := dockerClient.Attach(ctx, containerID, opts)
+	if errors.Is(err, docker.ErrDetached) {
+		if opts.ReadOnly {
+			return nil
+		}
+		resume := "rig attach"
+		if worktree != "" {
+			resume += " -w " + worktree
+		}
+		if name != "" {
+			resume += " " + name
+		}
+		fmt.Fprintf(os.Stderr, "Detached; resume with '%s'\n", resume)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("attaching to container: %w", err)
+	}
+	return nil
+}
+
+// detachKeys returns the current project's detach keys, or the default ones
+// when its config can't be loaded
+func detachKeys() []byte {
+	cfg := &config.Config{}
+	if dir, err := projectDir(); err == nil {
+		if loaded, err := config.Load(project.ConfigPath(dir)); err == nil && loaded.Validate() == nil {
+			cfg = loaded
+		}
+	}
+	return cfg.GetDetachKeys()
+}
+
+// listSessions returns the interactive sessions in a running container
+func listSessions(ctx context.Context, dockerClient docker.DockerClient, containerID string) ([]sessions.Session, error) {
+	out, err := execOutput(ctx, dockerClient, containerID, sessions.ListCommand())
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+	return sessions.ParseList(out)
+}
+
+// hasSession reports whether a session with the given name is in the list
+func hasSession(list []sessions.Session, name string) bool {
+	for _, s := range list {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
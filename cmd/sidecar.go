@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/project"
+	"github.com/wfaler/rig/internal/sidecar"
+)
+
+// ensureSidecarImage builds the shared sidecar image if it doesn't exist yet
+// and returns its reference
+func ensureSidecarImage(ctx context.Context, dockerClient docker.DockerClient) (string, error) {
+	imageRef, err := sidecar.ImageRef()
+	if err != nil {
+		return "", err
+	}
+	imageExists, err := dockerClient.ImageExists(ctx, imageRef)
+	if err != nil {
+		return "", fmt.Errorf("checking image: %w", err)
+	}
+	if imageExists {
+		return imageRef, nil
+	}
+
+	fmt.Printf("Building sidecar image %s...\n", imageRef)
+	files, err := sidecar.BuildContext()
+	if err != nil {
+		return "", err
+	}
+	// Shared by all projects, so only the rig version is recorded
+	if err := dockerClient.BuildImageFromContext(ctx, files, imageRef, map[string]string{project.VersionLabel: Version}); err != nil {
+		return "", fmt.Errorf("building sidecar image: %w", err)
+	}
+	return imageRef, nil
+}
+
+// stopSidecars stops the egress and Docker proxies of a container, if any
+func stopSidecars(ctx context.Context, dockerClient docker.DockerClient, containerName string) error {
+	if err := stopEgressProxy(ctx, dockerClient, containerName); err != nil {
+		return err
+	}
+	return stopDockerProxy(ctx, dockerClient, containerName)
+}
+
+// removeSidecars removes the egress and Docker proxies of a container along
+// with their network and volume. The container must already be removed.
+func removeSidecars(ctx context.Context, dockerClient docker.DockerClient, containerName string) error {
+	if err := removeEgressProxy(ctx, dockerClient, containerName); err != nil {
+		return err
+	}
+	return removeDockerProxy(ctx, dockerClient, containerName)
+}
+
+// stopSidecar stops the named sidecar container if it exists and is running
+func stopSidecar(ctx context.Context, dockerClient docker.DockerClient, name string) error {
+	sidecarID, err := dockerClient.FindContainer(ctx, name)
+	if err != nil {
+		return fmt.Errorf("finding container: %w", err)
+	}
+	if sidecarID == "" {
+		return nil
+	}
+	running, err := dockerClient.IsContainerRunning(ctx, sidecarID)
+	if err != nil {
+		return fmt.Errorf("checking container status: %w", err)
+	}
+	if running {
+		if err := dockerClient.StopContainer(ctx, sidecarID); err != nil {
+			return fmt.Errorf("stopping %s: %w", name, err)
+		}
+	}
+	return nil
+}
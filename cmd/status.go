@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/config"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/project"
+)
+
+var (
+	statusWorktree string
+	statusJSON     bool
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the project's container and image",
+	Long: `Explains the state of the current project: whether its container exists
+and is running, and for how long; the image it runs and its age, against the
+image the current .rig.yml would produce; whether a rebuild is pending, with
+the config fields changed since the image was built; the host ports the
+container's ports are published on and the code-server URL.
+
+A pending rebuild is applied by the next 'rig up'. Changed fields can't be
+told for images built before rig recorded their config.
+
+With --json, prints the same as a JSON object.`,
+	Args: cobra.NoArgs,
+	RunE: runStatus,
+}
+
+func init() {
+	statusCmd.Flags().StringVarP(&statusWorktree, "worktree", "w", "", "Show the container of a worktree")
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print as JSON")
+	rootCmd.AddCommand(statusCmd)
+}
+
+// projectStatus is the state reported by 'rig status'. Times are omitted
+// when they don't apply, e.g. the start of a missing container.
+type projectStatus struct {
+	Project          string                 `json:"project"`
+	Directory        string                 `json:"directory"`
+	Container        string                 `json:"container"`
+	State            string                 `json:"state"` // Docker's container status, or "missing"
+	ExitCode         *int                   `json:"exit_code,omitempty"`
+	StartedAt        *time.Time             `json:"started_at,omitempty"`
+	FinishedAt       *time.Time             `json:"finished_at,omitempty"`
+	UptimeSeconds    int64                  `json:"uptime_seconds,omitempty"`
+	Image            string                 `json:"image"` // Image the container runs, or would run
+	ImageExists      bool                   `json:"image_exists"`
+	ImageCreated     *time.Time             `json:"image_created,omitempty"`
+	ImageAgeSeconds  int64                  `json:"image_age_seconds,omitempty"`
+	ImageRigVersion  string                 `json:"image_rig_version,omitempty"`
+	ConfigImage      string                 `json:"config_image"` // Image the current .rig.yml produces
+	ConfigImageBuilt bool                   `json:"config_image_built"`
+	RebuildPending   bool                   `json:"rebuild_pending"`
+	ChangedFields    []string               `json:"changed_fields"` // null when unknown
+	Ports            []docker.PublishedPort `json:"ports"`
+	CodeServerURL    string                 `json:"code_server_url,omitempty"`
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	cwd, err := projectDir()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(project.ConfigPath(cwd))
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	cfg, err := config.Parse(data)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	status, err := collectStatus(ctx, dockerClient, cwd, data, cfg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if statusJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	}
+	printStatus(status, cfg)
+	return nil
+}
+
+// collectStatus inspects the project's container and images. data is the
+// current .rig.yml, parsed as cfg.
+func collectStatus(ctx context.Context, dockerClient docker.DockerClient, cwd string, data []byte, cfg *config.Config, now time.Time) (*projectStatus, error) {
+	projectName := project.GetProjectName(cwd, cfg.Name)
+	containerName := project.ContainerName(projectName)
+	if statusWorktree != "" {
+		containerName = project.WorktreeContainerName(projectName, statusWorktree)
+	}
+	status := &projectStatus{
+		Project:     projectName,
+		Directory:   cwd,
+		Container:   containerName,
+		State:       "missing",
+		ConfigImage: project.ImageRef(projectName, project.ConfigHash(data)),
+		Ports:       []docker.PublishedPort{},
+	}
+	status.Image = status.ConfigImage
+
+	containerID, err := dockerClient.FindContainer(ctx, containerName)
+	if err != nil {
+		return nil, fmt.Errorf("finding container: %w", err)
+	}
+	var state docker.ContainerState
+	if containerID != "" {
+		if state, err = dockerClient.InspectContainer(ctx, containerID); err != nil {
+			return nil, err
+		}
+		status.State = state.Status
+		status.Image = state.Image
+		if !state.StartedAt.IsZero() {
+			status.StartedAt = &state.StartedAt
+		}
+		if state.Running {
+			status.UptimeSeconds = int64(now.Sub(state.StartedAt).Seconds())
+		} else if !state.FinishedAt.IsZero() {
+			status.FinishedAt = &state.FinishedAt
+			status.ExitCode = &state.ExitCode
+		}
+		status.Ports = append(status.Ports, state.Ports...)
+	}
+
+	image, err := dockerClient.InspectImage(ctx, status.Image)
+	if err != nil {
+		return nil, err
+	}
+	if image != nil {
+		status.ImageExists = true
+		if !image.Created.IsZero() {
+			status.ImageCreated = &image.Created
+			status.ImageAgeSeconds = int64(now.Sub(image.Created).Seconds())
+		}
+		status.ImageRigVersion = image.Labels[project.VersionLabel]
+		if built, ok := image.Labels[project.ConfigLabel]; ok {
+			changed, err := config.ChangedFields([]byte(built), data)
+			if err != nil {
+				return nil, err
+			}
+			status.ChangedFields = append([]string{}, changed...)
+		}
+	}
+	if status.Image == status.ConfigImage {
+		status.ConfigImageBuilt = status.ImageExists
+		// Same tag, same config
+		status.ChangedFields = []string{}
+	} else if configImage, err := dockerClient.InspectImage(ctx, status.ConfigImage); err != nil {
+		return nil, err
+	} else {
+		status.ConfigImageBuilt = configImage != nil
+	}
+	status.RebuildPending = status.Image != status.ConfigImage || !status.ConfigImageBuilt
+
+	// With restricted egress the proxy publishes the project's ports
+	if state.Running && len(status.Ports) == 0 {
+		proxyID, err := dockerClient.FindContainer(ctx, project.EgressProxyName(containerName))
+		if err != nil {
+			return nil, fmt.Errorf("finding container: %w", err)
+		}
+		if proxyID != "" {
+			proxy, err := dockerClient.InspectContainer(ctx, proxyID)
+			if err != nil {
+				return nil, err
+			}
+			if proxy.Running {
+				status.Ports = append(status.Ports, proxy.Ports...)
+			}
+		}
+	}
+
+	if state.Running && cfg.IsCodeServerEnabled() {
+		for _, p := range status.Ports {
+			if p.ContainerPort == cfg.GetCodeServerPort() {
+				status.CodeServerURL = fmt.Sprintf("http://localhost:%d", p.HostPort)
+				break
+			}
+		}
+	}
+	return status, nil
+}
+
+// printStatus prints a status for people
+func printStatus(s *projectStatus, cfg *config.Config) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Project:\t%s (%s)\n", s.Project, s.Directory)
+
+	switch {
+	case s.State == "missing":
+		fmt.Fprintf(w, "Container:\t%s, not created (run 'rig up')\n", s.Container)
+	case s.State == "running" && s.StartedAt != nil:
+		fmt.Fprintf(w, "Container:\t%s, running for %s\n", s.Container, formatAge(time.Duration(s.UptimeSeconds)*time.Second))
+	case s.FinishedAt != nil:
+		fmt.Fprintf(w, "Container:\t%s, %s (code %d) %s ago\n", s.Container, s.State, *s.ExitCode, formatAge(time.Since(*s.FinishedAt)))
+	default:
+		fmt.Fprintf(w, "Container:\t%s, %s\n", s.Container, s.State)
+	}
+
+	switch {
+	case !s.ImageExists:
+		fmt.Fprintf(w, "Image:\t%s, not built\n", s.Image)
+	case s.ImageCreated != nil:
+		fmt.Fprintf(w, "Image:\t%s, built %s ago\n", s.Image, formatAge(time.Duration(s.ImageAgeSeconds)*time.Second))
+	default:
+		fmt.Fprintf(w, "Image:\t%s\n", s.Image)
+	}
+	if s.ImageExists && s.ImageRigVersion != "" && s.ImageRigVersion != Version {
+		fmt.Fprintf(w, "\tbuilt by rig %s (now %s); 'rig rebuild' picks up changes to the image rig generates\n", s.ImageRigVersion, Version)
+	}
+
+	switch {
+	case !s.RebuildPending:
+		fmt.Fprintf(w, "Config:\tup to date\n")
+	case s.Image == s.ConfigImage:
+		fmt.Fprintf(w, "Config:\tnot built yet; the next 'rig up' builds %s\n", s.ConfigImage)
+	default:
+		fmt.Fprintf(w, "Config:\tchanged since the image was built; the next 'rig up' rebuilds as %s\n", s.ConfigImage)
+		switch {
+		case s.ChangedFields == nil:
+			fmt.Fprintf(w, "\tchanged fields unknown (the image predates rig status)\n")
+		case len(s.ChangedFields) == 0:
+			fmt.Fprintf(w, "\tonly comments or formatting changed\n")
+		default:
+			fmt.Fprintf(w, "\tchanged: %s\n", strings.Join(s.ChangedFields, ", "))
+		}
+	}
+
+	if len(s.Ports) > 0 {
+		mapped := make([]string, 0, len(s.Ports))
+		for _, p := range s.Ports {
+			mapped = append(mapped, fmt.Sprintf("%d -> localhost:%d", p.ContainerPort, p.HostPort))
+		}
+		fmt.Fprintf(w, "Ports:\t%s\n", strings.Join(mapped, ", "))
+	}
+	if s.CodeServerURL != "" {
+		fmt.Fprintf(w, "code-server:\t%s\n", s.CodeServerURL)
+	} else if s.State == "running" && cfg.IsCodeServerEnabled() {
+		fmt.Fprintf(w, "code-server:\tnot published (run 'rig forward %d')\n", cfg.GetCodeServerPort())
+	}
+	w.Flush()
+}
+
+// formatAge formats a duration to its two largest units, e.g. "3d 4h" or "5m 10s"
+func formatAge(d time.Duration) string {
+	d = d.Round(time.Second)
+	days := int(d / (24 * time.Hour))
+	hours := int(d/time.Hour) % 24
+	minutes := int(d/time.Minute) % 60
+	seconds := int(d/time.Second) % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
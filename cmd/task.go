@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/config"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/project"
+)
+
+var (
+	taskList     bool
+	taskWorktree string
+)
+
+var taskCmd = &cobra.Command{
+	Use:   "task <name> [-- args...]",
+	Short: "Run a task from .rig.yml in the rig container",
+	Long: `Runs a task declared under tasks: in .rig.yml inside the rig container,
+after the tasks it depends on, and exits with the exit code of the first task
+that fails.
+
+The container is created or started as with 'rig up'. Tasks run in the
+workspace (or their working_dir) regardless of the current directory.
+Arguments after the task name are appended to its command.
+
+Examples:
+  rig task --list
+  rig task test
+  rig task test -- -run TestParse ./internal/config`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runTask,
+}
+
+func init() {
+	taskCmd.Flags().BoolVarP(&taskList, "list", "l", false, "List tasks")
+	taskCmd.Flags().StringVarP(&taskWorktree, "worktree", "w", "", "Run in the container of a worktree created with 'rig worktree add'")
+	// Flags after the task name belong to the task: rig task test -v
+	taskCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(taskCmd)
+}
+
+func runTask(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	dir, err := projectDir()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(project.ConfigPath(dir))
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	if taskList || len(args) == 0 {
+		return listTasks(cfg)
+	}
+
+	name, extraArgs := args[0], args[1:]
+	// Flag parsing stops at the task name, so a separating -- is still here
+	if len(extraArgs) > 0 && extraArgs[0] == "--" {
+		extraArgs = extraArgs[1:]
+	}
+	// Fail fast on unknown tasks, before a container is started
+	if _, err := cfg.TaskOrder(name); err != nil {
+		return fmt.Errorf("%w (see 'rig task --list')", err)
+	}
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	var sess *session
+	err = withStdoutOnStderr(func() error {
+		sess, err = startSession(ctx, dockerClient, sessionOptions{
+			worktree: taskWorktree,
+			purpose:  "rig task " + name,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	workspaceDir := sess.cfg.ContainerWorkspace(sess.workDir)
+
+	// Run the tasks as configured for the session's container
+	order, err := sess.cfg.TaskOrder(name)
+	if err != nil {
+		return fmt.Errorf("%w (see 'rig task --list')", err)
+	}
+
+	tty := term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stdout.Fd())
+	for i, taskName := range order {
+		task := sess.cfg.Tasks[taskName]
+		if strings.TrimSpace(task.Command) == "" {
+			continue
+		}
+		last := i == len(order)-1
+
+		command := []string{"bash", "-c", task.Command}
+		if last && len(extraArgs) > 0 {
+			command = []string{"bash", "-c", task.Command + ` "$@"`, "rig-task"}
+			command = append(command, extraArgs...)
+		}
+		env := make([]string, 0, len(task.Env))
+		for k, v := range task.Env {
+			env = append(env, k+"="+v)
+		}
+		sort.Strings(env)
+
+		// Only the requested task reads stdin, so dependencies can't consume it
+		var stdin io.Reader
+		if last {
+			stdin = os.Stdin
+		}
+
+		fmt.Fprintf(os.Stderr, "==> %s: %s\n", taskName, task.Command)
+		exitCode, err := dockerClient.Exec(ctx, sess.containerID, docker.ExecOptions{
+			Cmd:        command,
+			WorkingDir: path.Join(workspaceDir, task.WorkingDir),
+			Env:        env,
+			Tty:        tty && last,
+			Stdin:      stdin,
+		})
+		if err != nil {
+			return fmt.Errorf("running task %s: %w", taskName, err)
+		}
+		if exitCode != 0 {
+			fmt.Fprintf(os.Stderr, "==> %s failed with exit code %d\n", taskName, exitCode)
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+			return &exitError{code: exitCode}
+		}
+	}
+	return nil
+}
+
+// listTasks prints the configured tasks with their descriptions and dependencies
+func listTasks(cfg *config.Config) error {
+	if len(cfg.Tasks) == 0 {
+		fmt.Printf("No tasks (declare them under tasks: in %s)\n", configFileName)
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.Tasks))
+	for name := range cfg.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TASK\tDESCRIPTION\tDEPENDS ON")
+	for _, name := range names {
+		task := cfg.Tasks[name]
+		description := task.Description
+		if description == "" {
+			description = task.Command
+		}
+		deps := "-"
+		if len(task.DependsOn) > 0 {
+			deps = strings.Join(task.DependsOn, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, description, deps)
+	}
+	w.Flush()
+
+	return nil
+}
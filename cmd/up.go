@@ -1,23 +1,77 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/sessions"
+)
+
+var (
+	upWorktree   string
+	upOffline    bool
+	upCheckpoint bool
+	upSession    string
+	upRecord     bool
 )
 
 var upCmd = &cobra.Command{
-	Use:   "up",
+	Use:   "up [project]",
 	Short: "Start and enter the rig container",
 	Long: `Starts the rig container and attaches to it with the configured shell.
 
 If the container doesn't exist, it will be created from the .rig.yml configuration.
 If the configuration has changed, the container will be rebuilt.
 If the container is stopped, it will be started.
-If the container is already running, it will attach to it.`,
+If the container is already running, it will attach to it.
+
+Run from anywhere in a project: rig uses the nearest .rig.yml above the
+current directory. With a project name (see 'rig projects'), starts that
+project from any directory.
+
+With --worktree, enters the container of a worktree created with 'rig worktree add'.
+
+With --offline, the container runs with no network at all and without the
+Docker socket, for reviewing untrusted code. The image is still built online.
+Use 'rig forward <port>' to reach services such as code-server.
+
+With --checkpoint (or checkpoints: true in .rig.yml), the workspace is saved
+as a git checkpoint first; see 'rig checkpoint'.
+
+The shell runs in a session that survives the terminal. Press the detach
+keys (ctrl-p ctrl-q by default, see detach_keys in .rig.yml) to leave it
+running and resume it later with 'rig attach'. Each 'rig up' starts a new
+session (named 1, 2, ...); with --session it resumes or starts the named one.
+
+With --record, everything the terminal shows is recorded with its timing
+under .rig/recordings/ until you exit or detach; play it with 'rig replay'.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runSession(nil) // Uses configured shell from .rig.yml
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if upSession != "" && !sessions.ValidName(upSession) {
+			return fmt.Errorf("invalid session name %q (use letters, digits, '_' and '-')", upSession)
+		}
+		// Uses configured shell from .rig.yml
+		return runSession(nil, sessionOptions{
+			project:    name,
+			worktree:   upWorktree,
+			offline:    upOffline,
+			checkpoint: upCheckpoint,
+			session:    upSession,
+			record:     upRecord,
+			purpose:    "rig up",
+		})
 	},
 }
 
 func init() {
+	upCmd.Flags().StringVarP(&upWorktree, "worktree", "w", "", "Enter the container of a rig-managed worktree")
+	upCmd.Flags().BoolVar(&upOffline, "offline", false, "Run the container without network or Docker socket")
+	upCmd.Flags().BoolVar(&upCheckpoint, "checkpoint", false, "Record a git checkpoint of the workspace first")
+	upCmd.Flags().StringVarP(&upSession, "session", "s", "", "Start or resume the named session")
+	upCmd.Flags().BoolVar(&upRecord, "record", false, "Record the session under .rig/recordings")
 	rootCmd.AddCommand(upCmd)
 }
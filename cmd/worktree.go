@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wfaler/rig/internal/docker"
+	"github.com/wfaler/rig/internal/git"
+	"github.com/wfaler/rig/internal/project"
+)
+
+var worktreeRemoveForce bool
+
+var worktreeCmd = &cobra.Command{
+	Use:   "worktree",
+	Short: "Manage git worktrees with their own rig containers",
+	Long: `Manages git worktrees for running several branches side by side.
+
+Each worktree lives under .rig/worktrees/<branch> and gets its own container,
+named rig-<project>-wt-<branch>, built from the project's image. Branch names
+with characters not allowed there, e.g. feature/x, are sanitized and get a
+short hash of the branch appended (feature-x-1a2b3c). Ports are
+published on ephemeral host ports so worktree containers don't collide.
+
+Examples:
+  rig worktree add feature-x
+  rig up --worktree feature-x
+  rig agent claude --worktree feature-x --prompt-file task.md
+  rig worktree ls
+  rig worktree rm feature-x`,
+}
+
+var worktreeAddCmd = &cobra.Command{
+	Use:   "add <branch>",
+	Short: "Create a worktree for a branch and start its container",
+	Long: `Creates a git worktree for <branch> under .rig/worktrees and starts a
+container for it. The branch is created from HEAD if it doesn't exist.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorktreeAdd,
+}
+
+var worktreeListCmd = &cobra.Command{
+	Use:     "ls",
+	Short:   "List rig-managed worktrees and their containers",
+	Aliases: []string{"list"},
+	Args:    cobra.NoArgs,
+	RunE:    runWorktreeList,
+}
+
+var worktreeRemoveCmd = &cobra.Command{
+	Use:   "rm <branch>",
+	Short: "Remove a worktree and its container",
+	Long: `Stops and removes the worktree's container, then removes the worktree.
+The branch itself is kept.`,
+	Aliases: []string{"remove"},
+	Args:    cobra.ExactArgs(1),
+	RunE:    runWorktreeRemove,
+}
+
+func init() {
+	worktreeRemoveCmd.Flags().BoolVarP(&worktreeRemoveForce, "force", "f", false, "Remove the worktree even if it has uncommitted changes")
+	worktreeCmd.AddCommand(worktreeAddCmd, worktreeListCmd, worktreeRemoveCmd)
+	rootCmd.AddCommand(worktreeCmd)
+}
+
+func runWorktreeAdd(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	branch := args[0]
+
+	cwd, err := projectDir()
+	if err != nil {
+		return err
+	}
+
+	if project.SanitizeBranch(branch) == "" {
+		return fmt.Errorf("invalid branch name: %s", branch)
+	}
+
+	worktreeDir := project.WorktreeDir(cwd, branch)
+	if _, err := os.Stat(worktreeDir); err == nil {
+		if err := checkWorktreeBranch(cwd, worktreeDir, branch); err != nil {
+			return err
+		}
+		fmt.Printf("Worktree %s already exists\n", worktreeDir)
+	} else {
+		fmt.Printf("Creating worktree for %s...\n", branch)
+		if err := git.AddWorktree(cwd, worktreeDir, branch); err != nil {
+			return fmt.Errorf("creating worktree: %w", err)
+		}
+	}
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	sess, err := startSession(ctx, dockerClient, sessionOptions{worktree: branch})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Worktree ready in container %s. Run 'rig up --worktree %s' to enter it.\n", sess.containerName, branch)
+	return nil
+}
+
+func runWorktreeList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	cwd, err := projectDir()
+	if err != nil {
+		return err
+	}
+	projectName := resolveProjectName(cwd)
+
+	worktrees, err := rigWorktrees(cwd)
+	if err != nil {
+		return err
+	}
+	if len(worktrees) == 0 {
+		fmt.Println("No rig worktrees")
+		return nil
+	}
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	// Print in table format
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tPATH\tCONTAINER\tSTATUS")
+	for _, wt := range worktrees {
+		// Detached worktrees are named after the directory, which already
+		// has the container's suffix
+		branch := wt.Branch
+		containerName := project.WorktreeContainerName(projectName, branch)
+		if branch == "" {
+			branch = "(detached)"
+			containerName = project.WorktreeContainerName(projectName, filepath.Base(wt.Path))
+		}
+		status, err := containerStatus(ctx, dockerClient, containerName)
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(resolvedDir(cwd), wt.Path)
+		if err != nil {
+			relPath = wt.Path
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", branch, relPath, containerName, status)
+	}
+	w.Flush()
+
+	return nil
+}
+
+func runWorktreeRemove(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	branch := args[0]
+
+	cwd, err := projectDir()
+	if err != nil {
+		return err
+	}
+	projectName := resolveProjectName(cwd)
+	containerName := project.WorktreeContainerName(projectName, branch)
+
+	// Create Docker client
+	dockerClient, err := docker.New()
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	// Find and remove container
+	containerID, err := dockerClient.FindContainer(ctx, containerName)
+	if err != nil {
+		return fmt.Errorf("finding container: %w", err)
+	}
+	if containerID != "" {
+		fmt.Printf("Removing container %s...\n", containerName)
+		if err := dockerClient.RemoveContainer(ctx, containerID, true); err != nil {
+			return fmt.Errorf("removing container: %w", err)
+		}
+	}
+	if err := removeChildren(ctx, dockerClient, containerName, true); err != nil {
+		return err
+	}
+	if err := removeSidecars(ctx, dockerClient, containerName); err != nil {
+		return err
+	}
+	if err := removeWorkspaceCopy(ctx, dockerClient, containerName); err != nil {
+		return err
+	}
+
+	worktreeDir := project.WorktreeDir(cwd, branch)
+	if _, err := os.Stat(worktreeDir); err != nil {
+		fmt.Printf("No worktree found for branch %s\n", branch)
+		return nil
+	}
+
+	fmt.Printf("Removing worktree %s...\n", worktreeDir)
+	if err := git.RemoveWorktree(cwd, worktreeDir, worktreeRemoveForce); err != nil {
+		return fmt.Errorf("removing worktree: %w", err)
+	}
+
+	fmt.Printf("Worktree %s removed (branch kept).\n", branch)
+	return nil
+}
+
+// rigWorktrees returns the git worktrees managed by rig for the project in dir
+func rigWorktrees(dir string) ([]git.Worktree, error) {
+	all, err := git.ListWorktrees(dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing worktrees: %w", err)
+	}
+
+	// git reports resolved paths, so resolve the project directory too
+	root := filepath.Join(resolvedDir(dir), project.WorktreesDir)
+
+	var worktrees []git.Worktree
+	for _, wt := range all {
+		if strings.HasPrefix(wt.Path, root+string(filepath.Separator)) {
+			worktrees = append(worktrees, wt)
+		}
+	}
+	return worktrees, nil
+}
+
+// checkWorktreeBranch fails if the rig worktree at dir has a branch other
+// than branch checked out, as its container would be taken for the wrong branch
+func checkWorktreeBranch(projectDir, dir, branch string) error {
+	worktrees, err := rigWorktrees(projectDir)
+	if err != nil {
+		return err
+	}
+	for _, wt := range worktrees {
+		if filepath.Base(wt.Path) == filepath.Base(dir) && wt.Branch != "" && wt.Branch != branch {
+			return fmt.Errorf("worktree %s has branch %s checked out, not %s", dir, wt.Branch, branch)
+		}
+	}
+	return nil
+}
+
+// containerStatus returns a short status for a container by name
+func containerStatus(ctx context.Context, dockerClient docker.DockerClient, containerName string) (string, error) {
+	containerID, err := dockerClient.FindContainer(ctx, containerName)
+	if err != nil {
+		return "", fmt.Errorf("finding container: %w", err)
+	}
+	if containerID == "" {
+		return "none", nil
+	}
+	running, err := dockerClient.IsContainerRunning(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("checking container status: %w", err)
+	}
+	if running {
+		return "running", nil
+	}
+	return "stopped", nil
+}
+
+// resolvedDir returns dir with symlinks resolved, or dir itself if that fails
+func resolvedDir(dir string) string {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return dir
+	}
+	return resolved
+}
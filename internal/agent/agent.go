@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// RunsDir is the directory (relative to the project) where agent transcripts are saved
+	RunsDir = ".rig/runs"
+
+	// RunIDFormat is the timestamp layout used to name run directories
+	RunIDFormat = "20060102-150405"
+
+	// PromptFile, OutputFile and ResultFile are the files saved in each run directory
+	PromptFile = "prompt.md"
+	OutputFile = "output.log"
+	ResultFile = "result.json"
+)
+
+// Result records the outcome of an agent run
+type Result struct {
+	Agent    string    `json:"agent"`
+	Command  []string  `json:"command"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	ExitCode int       `json:"exit_code"`
+}
+
+// commandBuilders maps supported agents to functions building their headless command line.
+// Each agent runs non-interactively in its "skip permissions" mode, since the
+// container is the sandbox. Extra arguments go where the agent's CLI accepts
+// them, without splitting an option from its value.
+var commandBuilders = map[string]func(prompt string, extra []string) []string{
+	"claude": func(prompt string, extra []string) []string {
+		cmd := []string{"claude", "--print", "--dangerously-skip-permissions"}
+		return append(append(cmd, extra...), prompt)
+	},
+	"gemini": func(prompt string, extra []string) []string {
+		cmd := []string{"gemini", "--yolo"}
+		return append(append(cmd, extra...), "--prompt", prompt)
+	},
+	"codex": func(prompt string, extra []string) []string {
+		cmd := []string{"codex", "exec", "--dangerously-bypass-approvals-and-sandbox"}
+		return append(append(cmd, extra...), prompt)
+	},
+}
+
+// Supported returns the names of supported agents in sorted order
+func Supported() []string {
+	names := make([]string, 0, len(commandBuilders))
+	for name := range commandBuilders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsSupported checks if an agent name is supported
+func IsSupported(name string) bool {
+	_, ok := commandBuilders[name]
+	return ok
+}
+
+// Command returns the command line that runs the agent headless with the given prompt.
+// Extra arguments are passed through to the agent CLI ahead of the prompt.
+func Command(name, prompt string, extraArgs []string) ([]string, error) {
+	build, ok := commandBuilders[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported agent: %s (supported: %s)", name, strings.Join(Supported(), ", "))
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return nil, fmt.Errorf("prompt is empty")
+	}
+
+	return build(prompt, extraArgs), nil
+}
+
+// RunDir returns the transcript directory for a run started at the given time
+func RunDir(projectDir string, started time.Time) string {
+	return filepath.Join(projectDir, RunsDir, started.Format(RunIDFormat))
+}
+
+// WriteResult saves the run result to the run directory
+func WriteResult(runDir string, result Result) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding result: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, ResultFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing result: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		agent     string
+		prompt    string
+		extraArgs []string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:   "claude",
+			agent:  "claude",
+			prompt: "fix the tests",
+			want:   []string{"claude", "--print", "--dangerously-skip-permissions", "fix the tests"},
+		},
+		{
+			name:   "gemini",
+			agent:  "gemini",
+			prompt: "fix the tests",
+			want:   []string{"gemini", "--yolo", "--prompt", "fix the tests"},
+		},
+		{
+			name:   "codex",
+			agent:  "codex",
+			prompt: "fix the tests",
+			want:   []string{"codex", "exec", "--dangerously-bypass-approvals-and-sandbox", "fix the tests"},
+		},
+		{
+			name:      "extra args go before the prompt",
+			agent:     "claude",
+			prompt:    "fix the tests",
+			extraArgs: []string{"--model", "opus"},
+			want:      []string{"claude", "--print", "--dangerously-skip-permissions", "--model", "opus", "fix the tests"},
+		},
+		{
+			name:      "gemini extra args keep --prompt with its value",
+			agent:     "gemini",
+			prompt:    "fix the tests",
+			extraArgs: []string{"--model", "gemini-2.5-pro"},
+			want:      []string{"gemini", "--yolo", "--model", "gemini-2.5-pro", "--prompt", "fix the tests"},
+		},
+		{
+			name:      "codex extra args go after exec",
+			agent:     "codex",
+			prompt:    "fix the tests",
+			extraArgs: []string{"--model", "o3"},
+			want:      []string{"codex", "exec", "--dangerously-bypass-approvals-and-sandbox", "--model", "o3", "fix the tests"},
+		},
+		{
+			name:    "unsupported agent",
+			agent:   "copilot",
+			prompt:  "fix the tests",
+			wantErr: true,
+		},
+		{
+			name:    "empty prompt",
+			agent:   "claude",
+			prompt:  "  \n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Command(tt.agent, tt.prompt, tt.extraArgs)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSupported(t *testing.T) {
+	assert.Equal(t, []string{"claude", "codex", "gemini"}, Supported())
+	assert.True(t, IsSupported("claude"))
+	assert.False(t, IsSupported("copilot"))
+}
+
+func TestRunDir(t *testing.T) {
+	started := time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC)
+	got := RunDir("/home/user/myproject", started)
+	assert.Equal(t, "/home/user/myproject/.rig/runs/20250314-092653", got)
+}
+
+func TestWriteResult(t *testing.T) {
+	tmpDir := t.TempDir()
+	result := Result{
+		Agent:    "claude",
+		Command:  []string{"claude", "--print", "hello"},
+		Started:  time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC),
+		Finished: time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC),
+		ExitCode: 2,
+	}
+
+	require.NoError(t, WriteResult(tmpDir, result))
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, ResultFile))
+	require.NoError(t, err)
+
+	var got Result
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, result, got)
+}
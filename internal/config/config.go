@@ -3,19 +3,410 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/moby/term"
+	"github.com/wfaler/rig/internal/egress"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the .assistant.yml file
 type Config struct {
+	// Name identifies the project in image and container names (default:
+	// directory name plus a hash of its path)
+	Name string `yaml:"name"`
+
 	Languages  map[string]LanguageConfig `yaml:"languages"`
 	Ports      []string                  `yaml:"ports"`
 	Env        map[string]string         `yaml:"env"`
 	CodeServer *CodeServerConfig         `yaml:"code_server"`
 	Shell      string                    `yaml:"shell"` // bash (default), zsh, fish
+	Network    *NetworkConfig            `yaml:"network"`
+
+	// DockerAccess controls access to the host Docker daemon: full (default,
+	// proxy with the hardened profile), proxy, none
+	DockerAccess string `yaml:"docker_access"`
+
+	Security *SecurityConfig `yaml:"security"`
+
+	// Isolation controls how /workspace is mounted: none (default, the host
+	// directory) or review (a copy whose changes are applied with 'rig review')
+	Isolation string `yaml:"isolation"`
+
+	// Checkpoints records a git checkpoint of the workspace before each session
+	Checkpoints bool `yaml:"checkpoints"`
+
+	// WorkspacePath is where the project appears in the container: /workspace
+	// (default) or mirror, the same absolute path as on the host
+	WorkspacePath string `yaml:"workspace_path"`
+
+	// WorkspaceFolders are extra directories, e.g. sibling repositories,
+	// mounted into the container next to the project
+	WorkspaceFolders []WorkspaceFolder `yaml:"workspace_folders"`
+
+	// Processes are long-running commands, e.g. dev servers, that rig starts
+	// with the container and restarts when they crash
+	Processes map[string]ProcessConfig `yaml:"processes"`
+
+	// Tasks are named commands, e.g. test or lint, run with 'rig task <name>'
+	Tasks map[string]TaskConfig `yaml:"tasks"`
+
+	// Shutdown controls how the container is stopped by 'rig down'
+	Shutdown *ShutdownConfig `yaml:"shutdown"`
+
+	// DetachKeys leave an interactive session running in the background,
+	// e.g. "ctrl-p,ctrl-q" (default); resume it with 'rig attach'
+	DetachKeys string `yaml:"detach_keys"`
+}
+
+// TaskConfig is a named command run in the container on demand.
+// In YAML, a plain command is shorthand for {command: <command>}.
+type TaskConfig struct {
+	Command     string            `yaml:"command"` // Run with bash -c
+	Description string            `yaml:"description"`
+	DependsOn   []string          `yaml:"depends_on"`  // Tasks run first, in order
+	WorkingDir  string            `yaml:"working_dir"` // Relative to the workspace (default: the workspace)
+	Env         map[string]string `yaml:"env"`
+}
+
+// UnmarshalYAML accepts either a command scalar or a mapping
+func (t *TaskConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		t.Command = value.Value
+		return nil
+	}
+	type plain TaskConfig
+	return value.Decode((*plain)(t))
+}
+
+// TaskOrder returns the tasks to run for a task, dependencies first, each
+// once. It fails on unknown tasks and dependency cycles.
+func (c *Config) TaskOrder(name string) ([]string, error) {
+	var order []string
+	done := make(map[string]bool)
+	visiting := make(map[string]bool)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		if done[name] {
+			return nil
+		}
+		path = append(path, name)
+		if visiting[name] {
+			return fmt.Errorf("task dependency cycle: %s", strings.Join(path, " -> "))
+		}
+		task, ok := c.Tasks[name]
+		if !ok {
+			if len(path) > 1 {
+				return fmt.Errorf("task %s depends on unknown task %s", path[len(path)-2], name)
+			}
+			return fmt.Errorf("unknown task: %s", name)
+		}
+		visiting[name] = true
+		for _, dep := range task.DependsOn {
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		done[name] = true
+		order = append(order, name)
+		return nil
+	}
+	if err := visit(name, nil); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// ProcessConfig is a long-running command supervised inside the container.
+// In YAML, a plain command is shorthand for {command: <command>}.
+type ProcessConfig struct {
+	Command    string            `yaml:"command"`     // Run with bash -c
+	WorkingDir string            `yaml:"working_dir"` // Relative to the workspace (default: the workspace)
+	Env        map[string]string `yaml:"env"`
+	Restart    string            `yaml:"restart"` // always, on-failure (default) or never
+}
+
+// UnmarshalYAML accepts either a command scalar or a mapping
+func (p *ProcessConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		p.Command = value.Value
+		return nil
+	}
+	type plain ProcessConfig
+	return value.Decode((*plain)(p))
+}
+
+// GetRestart returns the process's restart policy, defaulting to on-failure
+func (p ProcessConfig) GetRestart() string {
+	if p.Restart == "" {
+		return "on-failure"
+	}
+	return p.Restart
+}
+
+// CodeServerProcess is the process running code-server when it is enabled
+const CodeServerProcess = "code-server"
+
+// SupportedRestartPolicies lists valid process restart values
+var SupportedRestartPolicies = map[string]bool{
+	"always":     true,
+	"on-failure": true,
+	"never":      true,
+}
+
+// validCommandName matches process and task names; process names become
+// directory names in the container
+var validCommandName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// WorkspaceFolder is an extra directory mounted into the container.
+// In YAML, a plain path is shorthand for {path: <path>}.
+type WorkspaceFolder struct {
+	Path string `yaml:"path"` // Host directory, relative to the project
+	Name string `yaml:"name"` // Mounted as /workspace/<name> (default: base name of path)
+}
+
+// UnmarshalYAML accepts either a path scalar or a mapping
+func (f *WorkspaceFolder) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		f.Path = value.Value
+		return nil
+	}
+	type plain WorkspaceFolder
+	return value.Decode((*plain)(f))
+}
+
+// GetName returns the folder's name, defaulting to the base name of its path
+func (f WorkspaceFolder) GetName() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return filepath.Base(filepath.Clean(f.Path))
+}
+
+// validProjectName matches names usable in Docker image and container names
+var validProjectName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// DefaultWorkspaceDir is the container path of the project unless mirrored
+const DefaultWorkspaceDir = "/workspace"
+
+// SupportedWorkspacePaths lists valid workspace_path values
+var SupportedWorkspacePaths = map[string]bool{
+	DefaultWorkspaceDir: true,
+	"mirror":            true,
+}
+
+// IsWorkspaceMirrored returns true if the project is mounted at its host path
+func (c *Config) IsWorkspaceMirrored() bool {
+	return c.WorkspacePath == "mirror"
+}
+
+// ContainerWorkspace returns the container path for a project directory on the host
+func (c *Config) ContainerWorkspace(hostDir string) string {
+	if c.IsWorkspaceMirrored() {
+		return hostDir
+	}
+	return DefaultWorkspaceDir
+}
+
+// SupportedIsolationModes lists valid isolation modes
+var SupportedIsolationModes = map[string]bool{
+	"none":   true,
+	"review": true,
+}
+
+// IsReviewIsolated returns true if the container works on a copy of the workspace
+func (c *Config) IsReviewIsolated() bool {
+	return c.Isolation == "review"
+}
+
+// SecurityConfig defines the container's security profile.
+// In YAML, "security: hardened" is shorthand for "security: {profile: hardened}".
+type SecurityConfig struct {
+	Profile  string `yaml:"profile"`   // "default" or "hardened"
+	ReadOnly bool   `yaml:"read_only"` // Read-only root filesystem with a tmpfs /tmp (hardened only)
+	Seccomp  string `yaml:"seccomp"`   // Custom seccomp profile, relative to the project (hardened only)
+}
+
+// SupportedSecurityProfiles lists valid security profiles
+var SupportedSecurityProfiles = map[string]bool{
+	"default":  true,
+	"hardened": true,
+}
+
+// UnmarshalYAML accepts either a profile name scalar or a mapping
+func (s *SecurityConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		s.Profile = value.Value
+		return nil
+	}
+	type plain SecurityConfig
+	return value.Decode((*plain)(s))
+}
+
+// GetSecurityProfile returns the security profile, defaulting to default
+func (c *Config) GetSecurityProfile() string {
+	if c.Security == nil || c.Security.Profile == "" {
+		return "default"
+	}
+	return c.Security.Profile
+}
+
+// IsHardened returns true if the container runs with the hardened security profile
+func (c *Config) IsHardened() bool {
+	return c.Security != nil && c.Security.Profile == "hardened"
+}
+
+// NetworkConfig defines container networking settings.
+// In YAML, "network: none" is shorthand for "network: {mode: none}".
+type NetworkConfig struct {
+	Mode   string        `yaml:"mode"`   // "bridge" (default) or "none" for no network at all
+	Egress *EgressConfig `yaml:"egress"` // Outbound traffic policy (default: unrestricted)
+}
+
+// SupportedNetworkModes lists valid network modes
+var SupportedNetworkModes = map[string]bool{
+	"bridge": true,
+	"none":   true,
+}
+
+// UnmarshalYAML accepts either a network mode scalar or a mapping
+func (n *NetworkConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		n.Mode = value.Value
+		return nil
+	}
+	type plain NetworkConfig
+	return value.Decode((*plain)(n))
+}
+
+// IsOffline returns true if the container runs without any network
+func (c *Config) IsOffline() bool {
+	return c.Network != nil && c.Network.Mode == "none"
+}
+
+// EgressConfig restricts outbound traffic to an allow-list enforced by a
+// rig-managed filtering proxy. In YAML it is either "none" (deny everything)
+// or a list of allowed domains, wildcard domains and CIDRs.
+type EgressConfig struct {
+	Allow []string
+}
+
+// UnmarshalYAML accepts either the scalar "none" or a sequence of rules
+func (e *EgressConfig) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		if value.Value != "none" {
+			return fmt.Errorf("invalid network.egress %q: expected \"none\" or a list of domains/CIDRs", value.Value)
+		}
+		e.Allow = []string{}
+		return nil
+	case yaml.SequenceNode:
+		return value.Decode(&e.Allow)
+	default:
+		return fmt.Errorf("invalid network.egress: expected \"none\" or a list of domains/CIDRs")
+	}
+}
+
+// IsEgressRestricted returns true if outbound traffic goes through the egress proxy
+func (c *Config) IsEgressRestricted() bool {
+	return c.Network != nil && c.Network.Egress != nil
+}
+
+// GetEgressAllow returns the egress allow-list (empty when everything is denied)
+func (c *Config) GetEgressAllow() []string {
+	if !c.IsEgressRestricted() {
+		return nil
+	}
+	return c.Network.Egress.Allow
+}
+
+// SupportedDockerAccess lists valid docker_access modes
+var SupportedDockerAccess = map[string]bool{
+	"full":  true, // Host Docker socket mounted directly
+	"proxy": true, // Docker API through rig's filtering socket proxy
+	"none":  true, // No Docker access
+}
+
+// GetDockerAccess returns the Docker access mode, defaulting to full, or to
+// proxy with the hardened profile, which the raw socket would undermine
+func (c *Config) GetDockerAccess() string {
+	if c.DockerAccess == "" {
+		if c.IsHardened() {
+			return "proxy"
+		}
+		return "full"
+	}
+	return c.DockerAccess
+}
+
+// ShutdownConfig defines how the container is stopped
+type ShutdownConfig struct {
+	Timeout *int     `yaml:"timeout"`  // Seconds to wait before killing the container (default: 10)
+	Signal  string   `yaml:"signal"`   // Signal that starts the shutdown (default: SIGTERM)
+	PreStop []string `yaml:"pre_stop"` // Commands run in the container, in order, before it is stopped
+}
+
+// DefaultStopTimeout is how long a stopping container gets before it is killed
+const DefaultStopTimeout = 10
+
+// SupportedStopSignals lists valid shutdown signals
+var SupportedStopSignals = map[string]bool{
+	"SIGTERM": true,
+	"SIGINT":  true,
+	"SIGQUIT": true,
+	"SIGHUP":  true,
+	"SIGUSR1": true,
+	"SIGUSR2": true,
+}
+
+// GetStopTimeout returns the seconds a stopping container gets before it is killed
+func (c *Config) GetStopTimeout() int {
+	if c.Shutdown == nil || c.Shutdown.Timeout == nil {
+		return DefaultStopTimeout
+	}
+	return *c.Shutdown.Timeout
+}
+
+// GetStopSignal returns the shutdown signal with its SIG prefix, defaulting to SIGTERM
+func (c *Config) GetStopSignal() string {
+	if c.Shutdown == nil || c.Shutdown.Signal == "" {
+		return "SIGTERM"
+	}
+	signal := strings.ToUpper(c.Shutdown.Signal)
+	if !strings.HasPrefix(signal, "SIG") {
+		signal = "SIG" + signal
+	}
+	return signal
+}
+
+// GetPreStop returns the commands run before the container is stopped
+func (c *Config) GetPreStop() []string {
+	if c.Shutdown == nil {
+		return nil
+	}
+	return c.Shutdown.PreStop
+}
+
+// DefaultDetachKeys detach from a session, as with 'docker attach'
+const DefaultDetachKeys = "ctrl-p,ctrl-q"
+
+// GetDetachKeys returns the detach key sequence as bytes
+func (c *Config) GetDetachKeys() []byte {
+	keys := c.DetachKeys
+	if keys == "" {
+		keys = DefaultDetachKeys
+	}
+	b, err := term.ToBytes(keys)
+	if err != nil {
+		b, _ = term.ToBytes(DefaultDetachKeys)
+	}
+	return b
 }
 
 // SupportedShells lists valid shell options
@@ -129,6 +520,46 @@ func Load(path string) (*Config, error) {
 	return Parse(data)
 }
 
+// ChangedFields returns the fields that differ between two versions of a
+// config file as sorted dotted paths, e.g. "languages.go.version" or
+// "env.API_URL". Lists are compared as a whole. Changes to comments or
+// formatting only give no fields.
+func ChangedFields(old, new []byte) ([]string, error) {
+	var before, after map[string]any
+	if err := yaml.Unmarshal(old, &before); err != nil {
+		return nil, fmt.Errorf("parsing old config: %w", err)
+	}
+	if err := yaml.Unmarshal(new, &after); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	changed := diffFields("", before, after, nil)
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// diffFields appends the paths of the fields that differ between two
+// mappings, descending into mappings present in both
+func diffFields(prefix string, before, after map[string]any, changed []string) []string {
+	keys := make(map[string]bool, len(before)+len(after))
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+	for k := range keys {
+		path := prefix + k
+		b, bIsMap := before[k].(map[string]any)
+		a, aIsMap := after[k].(map[string]any)
+		if bIsMap && aIsMap {
+			changed = diffFields(path+".", b, a, changed)
+		} else if !reflect.DeepEqual(before[k], after[k]) {
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
 // Parse parses config from YAML bytes
 func Parse(data []byte) (*Config, error) {
 	var cfg Config
@@ -152,6 +583,16 @@ func (c *Config) ExpandEnvVars() {
 	for key, value := range c.Env {
 		c.Env[key] = os.Expand(value, os.Getenv)
 	}
+	for _, proc := range c.Processes {
+		for key, value := range proc.Env {
+			proc.Env[key] = os.Expand(value, os.Getenv)
+		}
+	}
+	for _, task := range c.Tasks {
+		for key, value := range task.Env {
+			task.Env[key] = os.Expand(value, os.Getenv)
+		}
+	}
 }
 
 // Validate checks the config for errors
@@ -185,6 +626,120 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("unsupported shell: %s (supported: bash, zsh, fish)", c.Shell)
 	}
 
+	// Validate docker access
+	if c.DockerAccess != "" && !SupportedDockerAccess[c.DockerAccess] {
+		return fmt.Errorf("unsupported docker_access: %s (supported: full, proxy, none)", c.DockerAccess)
+	}
+
+	// Validate isolation
+	if c.Isolation != "" && !SupportedIsolationModes[c.Isolation] {
+		return fmt.Errorf("unsupported isolation: %s (supported: none, review)", c.Isolation)
+	}
+
+	// Validate project name: it becomes part of image and container names
+	if c.Name != "" && !validProjectName.MatchString(c.Name) {
+		return fmt.Errorf("invalid name: %s (use lowercase letters, digits, '.', '_' and '-')", c.Name)
+	}
+
+	// Validate workspace path
+	if c.WorkspacePath != "" && !SupportedWorkspacePaths[c.WorkspacePath] {
+		return fmt.Errorf("unsupported workspace_path: %s (supported: /workspace, mirror)", c.WorkspacePath)
+	}
+
+	// Validate workspace folders: names become directories under /workspace
+	names := make(map[string]bool)
+	for _, f := range c.WorkspaceFolders {
+		if f.Path == "" {
+			return fmt.Errorf("workspace_folders: path is required")
+		}
+		name := f.GetName()
+		if name == "." || name == ".." || name == string(filepath.Separator) || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("workspace_folders: invalid name %q for %s", name, f.Path)
+		}
+		if names[name] {
+			return fmt.Errorf("workspace_folders: duplicate name %q (set name: to disambiguate)", name)
+		}
+		names[name] = true
+	}
+
+	// Validate processes
+	for name, proc := range c.Processes {
+		if !validCommandName.MatchString(name) {
+			return fmt.Errorf("processes: invalid name %q (use lowercase letters, digits, '_' and '-')", name)
+		}
+		if name == CodeServerProcess {
+			return fmt.Errorf("processes: %s is reserved for code_server", name)
+		}
+		if strings.TrimSpace(proc.Command) == "" {
+			return fmt.Errorf("processes: %s: command is required", name)
+		}
+		if !SupportedRestartPolicies[proc.GetRestart()] {
+			return fmt.Errorf("processes: %s: unsupported restart: %s (supported: always, on-failure, never)", name, proc.Restart)
+		}
+	}
+
+	// Validate tasks
+	for name, task := range c.Tasks {
+		if !validCommandName.MatchString(name) {
+			return fmt.Errorf("tasks: invalid name %q (use lowercase letters, digits, '_' and '-')", name)
+		}
+		if strings.TrimSpace(task.Command) == "" && len(task.DependsOn) == 0 {
+			return fmt.Errorf("tasks: %s: command or depends_on is required", name)
+		}
+		if _, err := c.TaskOrder(name); err != nil {
+			return fmt.Errorf("tasks: %w", err)
+		}
+	}
+
+	// Validate shutdown
+	if c.Shutdown != nil {
+		if c.GetStopTimeout() < 0 {
+			return fmt.Errorf("shutdown.timeout must not be negative")
+		}
+		if !SupportedStopSignals[c.GetStopSignal()] {
+			return fmt.Errorf("unsupported shutdown.signal: %s (supported: SIGTERM, SIGINT, SIGQUIT, SIGHUP, SIGUSR1, SIGUSR2)", c.Shutdown.Signal)
+		}
+		for _, hook := range c.Shutdown.PreStop {
+			if strings.TrimSpace(hook) == "" {
+				return fmt.Errorf("shutdown.pre_stop: command must not be empty")
+			}
+		}
+	}
+
+	// Validate detach keys
+	if c.DetachKeys != "" {
+		if _, err := term.ToBytes(c.DetachKeys); err != nil {
+			return fmt.Errorf("invalid detach_keys %q: use comma-separated keys such as ctrl-p,ctrl-q", c.DetachKeys)
+		}
+	}
+
+	// Validate security profile
+	if c.Security != nil {
+		if c.Security.Profile != "" && !SupportedSecurityProfiles[c.Security.Profile] {
+			return fmt.Errorf("unsupported security profile: %s (supported: default, hardened)", c.Security.Profile)
+		}
+		if !c.IsHardened() && (c.Security.ReadOnly || c.Security.Seccomp != "") {
+			return fmt.Errorf("security.read_only and security.seccomp require profile hardened")
+		}
+	}
+
+	// Validate network
+	if c.Network != nil {
+		if c.Network.Mode != "" && !SupportedNetworkModes[c.Network.Mode] {
+			return fmt.Errorf("unsupported network mode: %s (supported: bridge, none)", c.Network.Mode)
+		}
+		if c.IsOffline() && c.Network.Egress != nil {
+			return fmt.Errorf("network.egress cannot be combined with network mode none")
+		}
+	}
+
+	// Validate egress rules
+	for _, rule := range c.GetEgressAllow() {
+		if err := egress.ValidateRule(rule); err != nil {
+			return fmt.Errorf("invalid network.egress rule: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -550,3 +550,591 @@ func TestValidateMultipleBuildSystems(t *testing.T) {
 		})
 	}
 }
+
+func TestParseNetworkEgress(t *testing.T) {
+	tests := []struct {
+		name           string
+		yaml           string
+		wantRestricted bool
+		wantAllow      []string
+		wantErr        bool
+	}{
+		{
+			name:           "no network config",
+			yaml:           `shell: bash`,
+			wantRestricted: false,
+		},
+		{
+			name: "egress none",
+			yaml: `
+network:
+  egress: none
+`,
+			wantRestricted: true,
+			wantAllow:      []string{},
+		},
+		{
+			name: "egress allow-list",
+			yaml: `
+network:
+  egress:
+    - github.com
+    - "*.npmjs.org"
+    - 10.0.0.0/8
+`,
+			wantRestricted: true,
+			wantAllow:      []string{"github.com", "*.npmjs.org", "10.0.0.0/8"},
+		},
+		{
+			name: "invalid egress scalar",
+			yaml: `
+network:
+  egress: all
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.yaml))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRestricted, cfg.IsEgressRestricted())
+			assert.Equal(t, tt.wantAllow, cfg.GetEgressAllow())
+		})
+	}
+}
+
+func TestEgressValidation(t *testing.T) {
+	valid := Config{Network: &NetworkConfig{Egress: &EgressConfig{Allow: []string{"github.com", "10.0.0.0/8"}}}}
+	assert.NoError(t, valid.Validate())
+
+	invalid := Config{Network: &NetworkConfig{Egress: &EgressConfig{Allow: []string{"https://github.com"}}}}
+	err := invalid.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid network.egress rule")
+}
+
+func TestParseNetworkMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		wantOffline bool
+		wantErr     bool
+	}{
+		{
+			name:        "network none shorthand",
+			yaml:        `network: none`,
+			wantOffline: true,
+		},
+		{
+			name: "network mode none",
+			yaml: `
+network:
+  mode: none
+`,
+			wantOffline: true,
+		},
+		{
+			name:        "network bridge",
+			yaml:        `network: bridge`,
+			wantOffline: false,
+		},
+		{
+			name:    "unsupported mode",
+			yaml:    `network: host`,
+			wantErr: true,
+		},
+		{
+			name: "none with egress",
+			yaml: `
+network:
+  mode: none
+  egress:
+    - github.com
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.yaml))
+			require.NoError(t, err)
+
+			err = cfg.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOffline, cfg.IsOffline())
+		})
+	}
+}
+
+func TestDockerAccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", yaml: `shell: bash`, want: "full"},
+		{name: "full", yaml: `docker_access: full`, want: "full"},
+		{name: "proxy", yaml: `docker_access: proxy`, want: "proxy"},
+		{name: "none", yaml: `docker_access: none`, want: "none"},
+		{name: "hardened default", yaml: `security: hardened`, want: "proxy"},
+		{name: "hardened full", yaml: "security: hardened\ndocker_access: full", want: "full"},
+		{name: "unsupported", yaml: `docker_access: readonly`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.yaml))
+			require.NoError(t, err)
+
+			err = cfg.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "unsupported docker_access")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cfg.GetDockerAccess())
+		})
+	}
+}
+
+func TestParseSecurity(t *testing.T) {
+	tests := []struct {
+		name         string
+		yaml         string
+		wantHardened bool
+		wantReadOnly bool
+		wantSeccomp  string
+		wantErr      string
+	}{
+		{
+			name: "not set",
+			yaml: `shell: bash`,
+		},
+		{
+			name:         "hardened shorthand",
+			yaml:         `security: hardened`,
+			wantHardened: true,
+		},
+		{
+			name: "default shorthand",
+			yaml: `security: default`,
+		},
+		{
+			name: "hardened with options",
+			yaml: `
+security:
+  profile: hardened
+  read_only: true
+  seccomp: seccomp.json
+`,
+			wantHardened: true,
+			wantReadOnly: true,
+			wantSeccomp:  "seccomp.json",
+		},
+		{
+			name:    "unsupported profile",
+			yaml:    `security: paranoid`,
+			wantErr: "unsupported security profile",
+		},
+		{
+			name: "read only without hardened",
+			yaml: `
+security:
+  read_only: true
+`,
+			wantErr: "require profile hardened",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.yaml))
+			require.NoError(t, err)
+
+			err = cfg.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHardened, cfg.IsHardened())
+			if tt.wantHardened {
+				assert.Equal(t, "hardened", cfg.GetSecurityProfile())
+			} else {
+				assert.Equal(t, "default", cfg.GetSecurityProfile())
+			}
+			if cfg.Security != nil {
+				assert.Equal(t, tt.wantReadOnly, cfg.Security.ReadOnly)
+				assert.Equal(t, tt.wantSeccomp, cfg.Security.Seccomp)
+			}
+		})
+	}
+}
+
+func TestIsolation(t *testing.T) {
+	cfg, err := Parse([]byte(`isolation: review`))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.True(t, cfg.IsReviewIsolated())
+
+	cfg, err = Parse([]byte(`isolation: none`))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.False(t, cfg.IsReviewIsolated())
+
+	cfg, err = Parse([]byte(`isolation: overlay`))
+	require.NoError(t, err)
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported isolation")
+}
+
+func TestParseCheckpoints(t *testing.T) {
+	cfg, err := Parse([]byte(`checkpoints: true`))
+	require.NoError(t, err)
+	assert.True(t, cfg.Checkpoints)
+
+	cfg, err = Parse([]byte(`shell: bash`))
+	require.NoError(t, err)
+	assert.False(t, cfg.Checkpoints)
+}
+
+func TestWorkspacePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", yaml: `shell: bash`, want: "/workspace"},
+		{name: "explicit default", yaml: `workspace_path: /workspace`, want: "/workspace"},
+		{name: "mirror", yaml: `workspace_path: mirror`, want: "/home/user/api"},
+		{name: "unsupported", yaml: `workspace_path: /src`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.yaml))
+			require.NoError(t, err)
+			err = cfg.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "unsupported workspace_path")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cfg.ContainerWorkspace("/home/user/api"))
+		})
+	}
+}
+
+func TestParseWorkspaceFolders(t *testing.T) {
+	cfg, err := Parse([]byte(`
+workspace_folders:
+  - ../shared-lib
+  - path: ../proto/
+  - path: ../other/api
+    name: other-api
+`))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	require.Len(t, cfg.WorkspaceFolders, 3)
+	assert.Equal(t, "../shared-lib", cfg.WorkspaceFolders[0].Path)
+	assert.Equal(t, "shared-lib", cfg.WorkspaceFolders[0].GetName())
+	assert.Equal(t, "proto", cfg.WorkspaceFolders[1].GetName())
+	assert.Equal(t, "other-api", cfg.WorkspaceFolders[2].GetName())
+}
+
+func TestWorkspaceFoldersValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "duplicate names", yaml: "workspace_folders: [../a/lib, ../b/lib]", wantErr: "duplicate name"},
+		{name: "missing path", yaml: "workspace_folders: [{name: lib}]", wantErr: "path is required"},
+		{name: "nested name", yaml: "workspace_folders: [{path: ../lib, name: a/b}]", wantErr: "invalid name"},
+		{name: "parent path", yaml: "workspace_folders: [..]", wantErr: "invalid name"},
+		{name: "renamed duplicate", yaml: "workspace_folders: [../a/lib, {path: ../b/lib, name: lib-b}]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.yaml))
+			require.NoError(t, err)
+			err = cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestProjectName(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{name: "unset", yaml: `shell: bash`},
+		{name: "simple", yaml: `name: billing`},
+		{name: "with separators", yaml: `name: billing-api_v2.1`},
+		{name: "uppercase", yaml: `name: Billing`, wantErr: true},
+		{name: "slash", yaml: `name: work/api`, wantErr: true},
+		{name: "leading dash", yaml: `name: -api`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.yaml))
+			require.NoError(t, err)
+			err = cfg.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid name")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseProcesses(t *testing.T) {
+	t.Setenv("API_TOKEN", "secret")
+	cfg, err := Parse([]byte(`
+processes:
+  web: npm run dev
+  worker:
+    command: go run ./cmd/worker
+    working_dir: services/worker
+    env:
+      TOKEN: "${API_TOKEN}"
+    restart: always
+`))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	cfg.ExpandEnvVars()
+
+	web := cfg.Processes["web"]
+	assert.Equal(t, "npm run dev", web.Command)
+	assert.Equal(t, "on-failure", web.GetRestart())
+
+	worker := cfg.Processes["worker"]
+	assert.Equal(t, "go run ./cmd/worker", worker.Command)
+	assert.Equal(t, "services/worker", worker.WorkingDir)
+	assert.Equal(t, "always", worker.GetRestart())
+	assert.Equal(t, "secret", worker.Env["TOKEN"])
+}
+
+func TestProcessesValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "missing command", yaml: "processes: {web: {restart: always}}", wantErr: "command is required"},
+		{name: "invalid name", yaml: "processes: {Web/UI: npm start}", wantErr: "invalid name"},
+		{name: "reserved name", yaml: "processes: {code-server: code-server}", wantErr: "reserved"},
+		{name: "invalid restart", yaml: "processes: {web: {command: npm start, restart: sometimes}}", wantErr: "unsupported restart"},
+		{name: "valid", yaml: "processes: {web: {command: npm start, restart: never}}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.yaml))
+			require.NoError(t, err)
+			err = cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestParseTasks(t *testing.T) {
+	t.Setenv("DB_URL", "postgres://db")
+	cfg, err := Parse([]byte(`
+tasks:
+  test: go test ./...
+  lint:
+    command: golangci-lint run
+    description: Run the linters
+  integration:
+    command: go test -tags integration ./...
+    depends_on: [generate]
+    working_dir: services/api
+    env:
+      DATABASE_URL: "${DB_URL}"
+  generate: go generate ./...
+  ci:
+    description: Everything CI runs
+    depends_on: [lint, test, integration]
+`))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	cfg.ExpandEnvVars()
+
+	assert.Equal(t, "go test ./...", cfg.Tasks["test"].Command)
+	assert.Equal(t, "Run the linters", cfg.Tasks["lint"].Description)
+	integration := cfg.Tasks["integration"]
+	assert.Equal(t, "services/api", integration.WorkingDir)
+	assert.Equal(t, "postgres://db", integration.Env["DATABASE_URL"])
+
+	order, err := cfg.TaskOrder("ci")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lint", "test", "generate", "integration", "ci"}, order)
+
+	_, err = cfg.TaskOrder("deploy")
+	assert.ErrorContains(t, err, "unknown task: deploy")
+}
+
+func TestTasksValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "empty task", yaml: "tasks: {test: {description: nothing}}", wantErr: "command or depends_on is required"},
+		{name: "invalid name", yaml: "tasks: {Test: go test}", wantErr: "invalid name"},
+		{name: "unknown dependency", yaml: "tasks: {test: {command: go test, depends_on: [build]}}", wantErr: "test depends on unknown task build"},
+		{name: "cycle", yaml: "tasks: {a: {command: x, depends_on: [b]}, b: {command: y, depends_on: [a]}}", wantErr: "dependency cycle"},
+		{name: "self dependency", yaml: "tasks: {a: {command: x, depends_on: [a]}}", wantErr: "a -> a"},
+		{name: "shared dependency", yaml: "tasks: {a: {command: x, depends_on: [c]}, b: {command: y, depends_on: [a, c]}, c: z}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.yaml))
+			require.NoError(t, err)
+			err = cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		wantTimeout int
+		wantSignal  string
+		wantPreStop []string
+		wantErr     string
+	}{
+		{name: "defaults", yaml: "shell: bash", wantTimeout: 10, wantSignal: "SIGTERM"},
+		{
+			name:        "configured",
+			yaml:        "shutdown: {timeout: 30, signal: SIGINT, pre_stop: [./scripts/flush.sh, pg_ctl stop]}",
+			wantTimeout: 30,
+			wantSignal:  "SIGINT",
+			wantPreStop: []string{"./scripts/flush.sh", "pg_ctl stop"},
+		},
+		{name: "zero timeout", yaml: "shutdown: {timeout: 0}", wantTimeout: 0, wantSignal: "SIGTERM"},
+		{name: "short signal name", yaml: "shutdown: {signal: quit}", wantTimeout: 10, wantSignal: "SIGQUIT"},
+		{name: "negative timeout", yaml: "shutdown: {timeout: -1}", wantErr: "must not be negative"},
+		{name: "unsupported signal", yaml: "shutdown: {signal: SIGKILL}", wantErr: "unsupported shutdown.signal: SIGKILL"},
+		{name: "empty hook", yaml: "shutdown: {pre_stop: [' ']}", wantErr: "must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.yaml))
+			require.NoError(t, err)
+			err = cfg.Validate()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTimeout, cfg.GetStopTimeout())
+			assert.Equal(t, tt.wantSignal, cfg.GetStopSignal())
+			assert.Equal(t, tt.wantPreStop, cfg.GetPreStop())
+		})
+	}
+}
+
+func TestDetachKeys(t *testing.T) {
+	cfg, err := Parse([]byte("shell: bash"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{16, 17}, cfg.GetDetachKeys())
+
+	cfg, err = Parse([]byte("detach_keys: ctrl-a,d"))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, []byte{1, 'd'}, cfg.GetDetachKeys())
+
+	cfg, err = Parse([]byte("detach_keys: ctrl-p,shift-q"))
+	require.NoError(t, err)
+	assert.ErrorContains(t, cfg.Validate(), "invalid detach_keys")
+}
+
+func TestChangedFields(t *testing.T) {
+	old := `# Project config
+languages:
+  go:
+    version: "1.22"
+  node:
+    version: "20"
+env:
+  API_URL: http://localhost:9000
+ports:
+  - "3000"
+shell: bash
+`
+	tests := []struct {
+		name string
+		new  string
+		want []string
+	}{
+		{name: "unchanged", new: old},
+		{name: "comments and formatting", new: "languages: {go: {version: \"1.22\"}, node: {version: \"20\"}}\nenv:\n  API_URL: http://localhost:9000\nports: [\"3000\"]\nshell: bash\n"},
+		{
+			name: "changed, added and removed fields",
+			new: `languages:
+  go:
+    version: "1.23"
+  node:
+    version: "20"
+  python:
+    version: "3.12"
+env:
+  API_URL: http://localhost:9000
+ports:
+  - "3000"
+  - "5432"
+`,
+			want: []string{"languages.go.version", "languages.python", "ports", "shell"},
+		},
+		{name: "section replaced by scalar", new: "languages: none\nenv:\n  API_URL: http://localhost:9000\nports:\n  - \"3000\"\nshell: bash\n", want: []string{"languages"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ChangedFields([]byte(old), []byte(tt.new))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := ChangedFields([]byte(old), []byte("languages: [\n"))
+	assert.Error(t, err)
+}
@@ -3,11 +3,15 @@ package docker
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/go-connections/nat"
+	"github.com/wfaler/rig/internal/project"
 )
 
 // FindContainer returns container ID if it exists, empty string otherwise
@@ -45,34 +49,62 @@ func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (stri
 		envSlice = append(envSlice, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	// Container configuration
+	workspaceDir := cfg.WorkspaceDir
+	if workspaceDir == "" {
+		workspaceDir = "/workspace"
+	}
+
+	// Container configuration. Without a TTY the logs keep stdout and stderr
+	// apart; the open stdin keeps a shell command waiting for input.
 	containerCfg := &container.Config{
 		Image:        cfg.ImageRef,
 		Cmd:          cfg.Command,
 		Env:          envSlice,
 		ExposedPorts: exposedPorts,
-		Tty:          true,
 		OpenStdin:    true,
 		AttachStdin:  true,
 		AttachStdout: true,
 		AttachStderr: true,
-		WorkingDir:   "/workspace",
+		WorkingDir:   workspaceDir,
+		Labels:       cfg.Labels,
+		StopSignal:   cfg.StopSignal,
+		StopTimeout:  cfg.StopTimeout,
+	}
+
+	// Mount project directory, or an isolated copy of it
+	workspaceSource := cfg.WorkDir
+	if cfg.WorkspaceVolume != "" {
+		workspaceSource = cfg.WorkspaceVolume
+	}
+	binds := []string{fmt.Sprintf("%s:%s:rw", workspaceSource, workspaceDir)}
+	if cfg.DockerSocket != "" {
+		// Docker socket for DinD (testcontainers support)
+		binds = append(binds, fmt.Sprintf("%s:/var/run/docker.sock", cfg.DockerSocket))
+	}
+	binds = append(binds, cfg.Binds...)
+
+	networkMode := cfg.NetworkMode
+	if networkMode == "" {
+		networkMode = "bridge"
 	}
 
 	// Host configuration with mounts
 	hostCfg := &container.HostConfig{
-		Binds: []string{
-			// Mount project directory
-			fmt.Sprintf("%s:/workspace:rw", cfg.WorkDir),
-			// Docker socket for DinD (testcontainers support)
-			"/var/run/docker.sock:/var/run/docker.sock",
-		},
+		Binds:         binds,
 		PortBindings:  portBindings,
 		Privileged:    false, // Socket mount doesn't need privileged mode
-		NetworkMode:   "bridge",
+		NetworkMode:   container.NetworkMode(networkMode),
 		RestartPolicy: container.RestartPolicy{Name: "no"},
+
+		CapDrop:        cfg.CapDrop,
+		CapAdd:         cfg.CapAdd,
+		SecurityOpt:    cfg.SecurityOpt,
+		ReadonlyRootfs: cfg.ReadOnlyRootfs,
+		Tmpfs:          cfg.Tmpfs,
+	}
+	if networkMode != "none" {
 		// Add host.docker.internal for Linux (Docker Desktop on Mac/Windows adds this automatically)
-		ExtraHosts: []string{"host.docker.internal:host-gateway"},
+		hostCfg.ExtraHosts = []string{"host.docker.internal:host-gateway"}
 	}
 
 	resp, err := c.cli.ContainerCreate(ctx, containerCfg, hostCfg, nil, nil, cfg.ContainerName)
@@ -83,6 +115,55 @@ func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (stri
 	return resp.ID, nil
 }
 
+// CreateSidecar creates a non-interactive helper container, such as a proxy,
+// that runs alongside a project container
+func (c *Client) CreateSidecar(ctx context.Context, cfg SidecarConfig) (string, error) {
+	exposedPorts, portBindings, err := parsePortMappings(cfg.Ports)
+	if err != nil {
+		return "", fmt.Errorf("parsing ports: %w", err)
+	}
+
+	envSlice := make([]string, 0, len(cfg.Env))
+	for k, v := range cfg.Env {
+		envSlice = append(envSlice, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	networkMode := cfg.NetworkMode
+	if networkMode == "" {
+		networkMode = "bridge"
+	}
+
+	containerCfg := &container.Config{
+		Image:        cfg.ImageRef,
+		Cmd:          cfg.Command,
+		Env:          envSlice,
+		Labels:       cfg.Labels,
+		ExposedPorts: exposedPorts,
+	}
+	hostCfg := &container.HostConfig{
+		Binds:         cfg.Binds,
+		PortBindings:  portBindings,
+		NetworkMode:   container.NetworkMode(networkMode),
+		RestartPolicy: container.RestartPolicy{Name: "no"},
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx, containerCfg, hostCfg, nil, nil, cfg.ContainerName)
+	if err != nil {
+		return "", fmt.Errorf("creating container: %w", err)
+	}
+
+	return resp.ID, nil
+}
+
+// GetContainerLabels returns the labels of a container
+func (c *Client) GetContainerLabels(ctx context.Context, containerID string) (map[string]string, error) {
+	info, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting container: %w", err)
+	}
+	return info.Config.Labels, nil
+}
+
 // StartContainer starts an existing container
 func (c *Client) StartContainer(ctx context.Context, containerID string) error {
 	if err := c.cli.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
@@ -141,6 +222,43 @@ func (c *Client) GetContainerImage(ctx context.Context, containerID string) (str
 	return info.Config.Image, nil
 }
 
+// GetContainerNetworkMode returns the network mode a container was created with
+func (c *Client) GetContainerNetworkMode(ctx context.Context, containerID string) (string, error) {
+	info, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("inspecting container: %w", err)
+	}
+	return string(info.HostConfig.NetworkMode), nil
+}
+
+// GetContainerWorkingDir returns the working directory a container was created with
+func (c *Client) GetContainerWorkingDir(ctx context.Context, containerID string) (string, error) {
+	info, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("inspecting container: %w", err)
+	}
+	return info.Config.WorkingDir, nil
+}
+
+// GetContainerMountSource returns the host path of a bind mount, or the name of
+// a volume, mounted at destination in a container; empty if nothing is mounted there
+func (c *Client) GetContainerMountSource(ctx context.Context, containerID, destination string) (string, error) {
+	info, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("inspecting container: %w", err)
+	}
+	for _, m := range info.Mounts {
+		if m.Destination != destination {
+			continue
+		}
+		if m.Name != "" {
+			return m.Name, nil
+		}
+		return m.Source, nil
+	}
+	return "", nil
+}
+
 // RigContainer represents a rig container with its status info
 type RigContainer struct {
 	Name    string
@@ -148,34 +266,74 @@ type RigContainer struct {
 	Status  string
 	Running bool
 	Image   string
+	Labels  map[string]string
 }
 
-// ListRigContainers returns running containers with names starting with "rig-"
+// ListRigContainers returns running rig workspace containers, identified by
+// their role label rather than their name
 func (c *Client) ListRigContainers(ctx context.Context) ([]RigContainer, error) {
 	containers, err := c.cli.ContainerList(ctx, container.ListOptions{
-		All: false, // Only running containers
+		All:     false, // Only running containers
+		Filters: filters.NewArgs(filters.Arg("label", project.Filter(project.RoleLabel, project.RoleWorkspace))),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+	return toRigContainers(containers), nil
+}
+
+// ListProjectContainers returns all workspace containers of a project,
+// running or not: its main container and those of its worktrees
+func (c *Client) ListProjectContainers(ctx context.Context, projectName string) ([]RigContainer, error) {
+	containers, err := c.cli.ContainerList(ctx, container.ListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", project.Filter(project.ProjectLabel, projectName)),
+			filters.Arg("label", project.Filter(project.RoleLabel, project.RoleWorkspace)),
+		),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("listing containers: %w", err)
 	}
+	return toRigContainers(containers), nil
+}
 
-	var rigContainers []RigContainer
+// toRigContainers converts a container list to RigContainers
+func toRigContainers(containers []container.Summary) []RigContainer {
+	rigContainers := make([]RigContainer, 0, len(containers))
 	for _, ctr := range containers {
-		for _, name := range ctr.Names {
+		name := ctr.ID[:12]
+		if len(ctr.Names) > 0 {
 			// Container names in Docker API are prefixed with "/"
-			if strings.HasPrefix(name, "/rig-") {
-				rigContainers = append(rigContainers, RigContainer{
-					Name:    strings.TrimPrefix(name, "/"),
-					ID:      ctr.ID[:12],
-					Status:  ctr.Status,
-					Running: ctr.State == "running",
-					Image:   ctr.Image,
-				})
-				break
-			}
+			name = strings.TrimPrefix(ctr.Names[0], "/")
 		}
+		rigContainers = append(rigContainers, RigContainer{
+			Name:    name,
+			ID:      ctr.ID[:12],
+			Status:  ctr.Status,
+			Running: ctr.State == "running",
+			Image:   ctr.Image,
+			Labels:  ctr.Labels,
+		})
 	}
-	return rigContainers, nil
+	return rigContainers
+}
+
+// ListContainersByLabel returns the IDs of all containers, running or not,
+// carrying a label ("key=value")
+func (c *Client) ListContainersByLabel(ctx context.Context, label string) ([]string, error) {
+	containers, err := c.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", label)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+	ids := make([]string, 0, len(containers))
+	for _, ctr := range containers {
+		ids = append(ids, ctr.ID)
+	}
+	return ids, nil
 }
 
 // parsePortMappings converts port specs to Docker port structures
@@ -220,3 +378,91 @@ func parsePortMappings(ports []string) (nat.PortSet, nat.PortMap, error) {
 
 	return exposedPorts, portBindings, nil
 }
+
+// ContainerState describes a container's state and published ports
+type ContainerState struct {
+	Status     string    // "running", "exited", "created", ...
+	Running    bool      // Whether it is running
+	ExitCode   int       // Exit code of the last run
+	StartedAt  time.Time // Start of the current or last run (zero if never started)
+	FinishedAt time.Time // End of the last run (zero if still running or never started)
+	Image      string    // Image reference it was created from
+	Ports      []PublishedPort
+}
+
+// PublishedPort is a container port published on the host
+type PublishedPort struct {
+	ContainerPort int    `json:"container_port"`
+	Protocol      string `json:"protocol"`
+	HostIP        string `json:"host_ip"`
+	HostPort      int    `json:"host_port"`
+}
+
+// InspectContainer returns a container's state and the host ports its ports
+// are published on, sorted by container port
+func (c *Client) InspectContainer(ctx context.Context, containerID string) (ContainerState, error) {
+	info, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return ContainerState{}, fmt.Errorf("inspecting container: %w", err)
+	}
+
+	state := ContainerState{Image: info.Config.Image}
+	if info.State != nil {
+		state.Status = info.State.Status
+		state.Running = info.State.Running
+		state.ExitCode = info.State.ExitCode
+		state.StartedAt = dockerTime(info.State.StartedAt)
+		state.FinishedAt = dockerTime(info.State.FinishedAt)
+	}
+	if info.NetworkSettings != nil {
+		for port, bindings := range info.NetworkSettings.Ports {
+			for _, b := range bindings {
+				hostPort, err := strconv.Atoi(b.HostPort)
+				if err != nil {
+					continue
+				}
+				state.Ports = append(state.Ports, PublishedPort{
+					ContainerPort: port.Int(),
+					Protocol:      port.Proto(),
+					HostIP:        b.HostIP,
+					HostPort:      hostPort,
+				})
+			}
+		}
+	}
+	sort.Slice(state.Ports, func(i, j int) bool {
+		a, b := state.Ports[i], state.Ports[j]
+		if a.ContainerPort != b.ContainerPort {
+			return a.ContainerPort < b.ContainerPort
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.HostPort != b.HostPort {
+			return a.HostPort < b.HostPort
+		}
+		return a.HostIP < b.HostIP
+	})
+	// Docker lists a port published on all interfaces once for IPv4 and once
+	// for IPv6; the IPv4 binding sorts first and is kept
+	ports := state.Ports[:0]
+	for i, p := range state.Ports {
+		if i > 0 && p.ContainerPort == ports[len(ports)-1].ContainerPort &&
+			p.HostPort == ports[len(ports)-1].HostPort && p.Protocol == ports[len(ports)-1].Protocol {
+			continue
+		}
+		ports = append(ports, p)
+	}
+	state.Ports = ports
+	return state, nil
+}
+
+// dockerTime parses a timestamp from the Docker API; Docker reports times
+// that never happened as the zero time of year 1, which is kept zero
+func dockerTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil || t.Year() <= 1 {
+		return time.Time{}
+	}
+	return t
+}
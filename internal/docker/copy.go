@@ -0,0 +1,30 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// CopyToContainer extracts a tar archive into a directory of a container,
+// owned by the container's user. The container doesn't need to be running.
+func (c *Client) CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader) error {
+	if err := c.cli.CopyToContainer(ctx, containerID, dstPath, content, container.CopyToContainerOptions{
+		CopyUIDGID: true,
+	}); err != nil {
+		return fmt.Errorf("copying to container: %w", err)
+	}
+	return nil
+}
+
+// CopyFromContainer returns a tar archive of a path in a container. Entries
+// are prefixed with the path's base name. The caller must close the reader.
+func (c *Client) CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, error) {
+	reader, _, err := c.cli.CopyFromContainer(ctx, containerID, srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("copying from container: %w", err)
+	}
+	return reader, nil
+}
@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/moby/term"
+)
+
+// ErrDetached is returned by Exec when the detach keys were read
+var ErrDetached = errors.New("detached")
+
+// Exec runs a command in a container, streaming its input and output, and
+// returns the command's exit code
+func (c *Client) Exec(ctx context.Context, containerID string, opts ExecOptions) (int, error) {
+	stdout := opts.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	stderr := opts.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	execResp, err := c.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          opts.Cmd,
+		WorkingDir:   opts.WorkingDir,
+		Env:          opts.Env,
+		User:         opts.User,
+		Tty:          opts.Tty,
+		AttachStdin:  opts.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("creating exec: %w", err)
+	}
+
+	attachResp, err := c.cli.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{
+		Tty: opts.Tty,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("attaching to exec: %w", err)
+	}
+	defer attachResp.Close()
+
+	if opts.Tty && opts.Stdin != nil {
+		restore, err := c.startTTY(ctx, execResp.ID, opts.Stdin, opts.Resized)
+		if err != nil {
+			return 0, err
+		}
+		defer restore()
+	}
+
+	// Copy stdin to container, closing the write side so the command sees EOF
+	var detached atomic.Bool
+	if opts.Stdin != nil {
+		stdin := opts.Stdin
+		if opts.Tty && len(opts.DetachKeys) > 0 {
+			stdin = term.NewEscapeProxy(stdin, opts.DetachKeys)
+		}
+		var input io.Writer = attachResp.Conn
+		if opts.ReadOnly {
+			input = io.Discard
+		}
+		go func() {
+			_, err := io.Copy(input, stdin)
+			if errors.As(err, &term.EscapeError{}) {
+				detached.Store(true)
+				if opts.DetachInput == nil {
+					attachResp.Close()
+					return
+				}
+				_, _ = attachResp.Conn.Write(opts.DetachInput)
+			}
+			_ = attachResp.CloseWrite()
+		}()
+	}
+
+	// A TTY merges stdout and stderr; without one they are multiplexed on a single stream
+	if opts.Tty {
+		_, err = io.Copy(stdout, attachResp.Reader)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, attachResp.Reader)
+	}
+	if detached.Load() {
+		return 0, ErrDetached
+	}
+	if err != nil {
+		return 0, fmt.Errorf("I/O error: %w", err)
+	}
+
+	return c.execExitCode(ctx, execResp.ID)
+}
+
+// ExecDetached starts a command in a container without attaching to it. The
+// command keeps running after rig exits; its streams are not connected, so
+// it must take care of its own output.
+func (c *Client) ExecDetached(ctx context.Context, containerID string, opts ExecOptions) error {
+	execResp, err := c.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:        opts.Cmd,
+		WorkingDir: opts.WorkingDir,
+		Env:        opts.Env,
+		User:       opts.User,
+		Detach:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("creating exec: %w", err)
+	}
+	if err := c.cli.ContainerExecStart(ctx, execResp.ID, container.ExecStartOptions{Detach: true}); err != nil {
+		return fmt.Errorf("starting exec: %w", err)
+	}
+	return nil
+}
+
+// execExitCode waits for an exec instance to finish and returns its exit code
+func (c *Client) execExitCode(ctx context.Context, execID string) (int, error) {
+	for {
+		inspect, err := c.cli.ContainerExecInspect(ctx, execID)
+		if err != nil {
+			return 0, fmt.Errorf("inspecting exec: %w", err)
+		}
+		// The output stream can close slightly before the exec is marked finished
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strings"
+	"sort"
+	"time"
 
 	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 )
 
@@ -27,16 +29,46 @@ func (c *Client) ImageExists(ctx context.Context, imageRef string) (bool, error)
 	return true, nil
 }
 
-// BuildImage builds a Docker image from a Dockerfile string
-func (c *Client) BuildImage(ctx context.Context, dockerfile string, imageRef string) error {
-	// Create tar archive with Dockerfile in memory
-	tarBuf, err := createDockerfileTar(dockerfile)
+// ImageInfo describes a local image
+type ImageInfo struct {
+	ID      string
+	Created time.Time
+	Labels  map[string]string
+}
+
+// InspectImage returns a local image's details, or nil if it doesn't exist
+func (c *Client) InspectImage(ctx context.Context, imageRef string) (*ImageInfo, error) {
+	info, err := c.cli.ImageInspect(ctx, imageRef)
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("inspecting image: %w", err)
+	}
+	result := &ImageInfo{ID: info.ID, Created: dockerTime(info.Created)}
+	if info.Config != nil {
+		result.Labels = info.Config.Labels
+	}
+	return result, nil
+}
+
+// BuildImage builds a labeled Docker image from a Dockerfile string
+func (c *Client) BuildImage(ctx context.Context, dockerfile string, imageRef string, labels map[string]string) error {
+	return c.BuildImageFromContext(ctx, map[string][]byte{"Dockerfile": []byte(dockerfile)}, imageRef, labels)
+}
+
+// BuildImageFromContext builds a labeled Docker image from in-memory build
+// context files keyed by path, which must include a Dockerfile
+func (c *Client) BuildImageFromContext(ctx context.Context, files map[string][]byte, imageRef string, labels map[string]string) error {
+	// Create tar archive with build context in memory
+	tarBuf, err := createContextTar(files)
 	if err != nil {
 		return fmt.Errorf("creating build context: %w", err)
 	}
 
 	resp, err := c.cli.ImageBuild(ctx, tarBuf, build.ImageBuildOptions{
 		Tags:        []string{imageRef},
+		Labels:      labels,
 		Dockerfile:  "Dockerfile",
 		Remove:      true, // Remove intermediate containers
 		ForceRemove: true,
@@ -55,22 +87,31 @@ func (c *Client) BuildImage(ctx context.Context, dockerfile string, imageRef str
 	return nil
 }
 
-// createDockerfileTar creates an in-memory tar archive containing the Dockerfile
-func createDockerfileTar(dockerfile string) (io.Reader, error) {
+// createContextTar creates an in-memory tar archive containing the build context files
+func createContextTar(files map[string][]byte) (io.Reader, error) {
 	var buf bytes.Buffer
 	tw := tar.NewWriter(&buf)
 
-	header := &tar.Header{
-		Name: "Dockerfile",
-		Mode: 0644,
-		Size: int64(len(dockerfile)),
+	// Sort paths so identical contexts produce identical archives
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
 	}
+	sort.Strings(paths)
 
-	if err := tw.WriteHeader(header); err != nil {
-		return nil, fmt.Errorf("writing tar header: %w", err)
-	}
-	if _, err := tw.Write([]byte(dockerfile)); err != nil {
-		return nil, fmt.Errorf("writing dockerfile to tar: %w", err)
+	for _, path := range paths {
+		header := &tar.Header{
+			Name: path,
+			Mode: 0644,
+			Size: int64(len(files[path])),
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("writing tar header: %w", err)
+		}
+		if _, err := tw.Write(files[path]); err != nil {
+			return nil, fmt.Errorf("writing %s to tar: %w", path, err)
+		}
 	}
 	if err := tw.Close(); err != nil {
 		return nil, fmt.Errorf("closing tar writer: %w", err)
@@ -113,32 +154,32 @@ func streamBuildOutput(reader io.Reader) error {
 	return nil
 }
 
-// RemoveImagesByName removes all images matching the given name (any tag)
-func (c *Client) RemoveImagesByName(ctx context.Context, imageName string) error {
-	images, err := c.cli.ImageList(ctx, image.ListOptions{All: true})
+// RemoveImagesByLabel removes all images carrying a label ("key=value")
+func (c *Client) RemoveImagesByLabel(ctx context.Context, label string) error {
+	images, err := c.cli.ImageList(ctx, image.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", label)),
+	})
 	if err != nil {
 		return fmt.Errorf("listing images: %w", err)
 	}
 
 	var removed int
 	for _, img := range images {
-		for _, tag := range img.RepoTags {
-			// Check if the image name matches (before the :tag)
-			if strings.HasPrefix(tag, imageName+":") || tag == imageName {
-				fmt.Printf("Removing image %s...\n", tag)
-				_, err := c.cli.ImageRemove(ctx, img.ID, image.RemoveOptions{Force: true, PruneChildren: true})
-				if err != nil {
-					fmt.Printf("Warning: could not remove %s: %v\n", tag, err)
-				} else {
-					removed++
-				}
-				break // Only need to remove once per image ID
-			}
+		name := img.ID
+		if len(img.RepoTags) > 0 {
+			name = img.RepoTags[0]
+		}
+		fmt.Printf("Removing image %s...\n", name)
+		if _, err := c.cli.ImageRemove(ctx, img.ID, image.RemoveOptions{Force: true, PruneChildren: true}); err != nil {
+			fmt.Printf("Warning: could not remove %s: %v\n", name, err)
+		} else {
+			removed++
 		}
 	}
 
 	if removed == 0 {
-		return fmt.Errorf("no images found matching %s", imageName)
+		return fmt.Errorf("no images found with label %s", label)
 	}
 
 	return nil
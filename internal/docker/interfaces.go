@@ -1,6 +1,9 @@
 package docker
 
-import "context"
+import (
+	"context"
+	"io"
+)
 
 // DockerClient defines the interface for Docker operations
 // This interface enables mocking for testing
@@ -14,8 +17,14 @@ type DockerClient interface {
 	// ImageExists checks if an image with the given ref exists locally
 	ImageExists(ctx context.Context, imageRef string) (bool, error)
 
-	// BuildImage builds a Docker image from a Dockerfile string
-	BuildImage(ctx context.Context, dockerfile string, imageRef string) error
+	// InspectImage returns a local image's details, or nil if it doesn't exist
+	InspectImage(ctx context.Context, imageRef string) (*ImageInfo, error)
+
+	// BuildImage builds a labeled Docker image from a Dockerfile string
+	BuildImage(ctx context.Context, dockerfile string, imageRef string, labels map[string]string) error
+
+	// BuildImageFromContext builds a labeled Docker image from in-memory build context files
+	BuildImageFromContext(ctx context.Context, files map[string][]byte, imageRef string, labels map[string]string) error
 
 	// FindContainer returns container ID if it exists, empty string otherwise
 	FindContainer(ctx context.Context, name string) (string, error)
@@ -23,6 +32,12 @@ type DockerClient interface {
 	// CreateContainer creates a new container
 	CreateContainer(ctx context.Context, cfg ContainerConfig) (string, error)
 
+	// CreateSidecar creates a non-interactive helper container
+	CreateSidecar(ctx context.Context, cfg SidecarConfig) (string, error)
+
+	// GetContainerLabels returns the labels of a container
+	GetContainerLabels(ctx context.Context, containerID string) (map[string]string, error)
+
 	// StartContainer starts an existing container
 	StartContainer(ctx context.Context, containerID string) error
 
@@ -35,22 +50,131 @@ type DockerClient interface {
 	// RemoveContainer removes a container
 	RemoveContainer(ctx context.Context, containerID string, force bool) error
 
+	// ListContainersByLabel returns the IDs of all containers carrying a label ("key=value")
+	ListContainersByLabel(ctx context.Context, label string) ([]string, error)
+
+	// ListProjectContainers returns all workspace containers of a project,
+	// including those of its worktrees
+	ListProjectContainers(ctx context.Context, projectName string) ([]RigContainer, error)
+
 	// IsContainerRunning checks if a container is currently running
 	IsContainerRunning(ctx context.Context, containerID string) (bool, error)
 
+	// InspectContainer returns a container's state and published ports
+	InspectContainer(ctx context.Context, containerID string) (ContainerState, error)
+
 	// GetContainerImage returns the image reference used by a container
 	GetContainerImage(ctx context.Context, containerID string) (string, error)
 
-	// Attach connects stdin/stdout to a container with TTY support
-	Attach(ctx context.Context, containerID string, command []string) error
+	// GetContainerNetworkMode returns the network mode a container was created with
+	GetContainerNetworkMode(ctx context.Context, containerID string) (string, error)
+
+	// GetContainerWorkingDir returns the working directory a container was created with
+	GetContainerWorkingDir(ctx context.Context, containerID string) (string, error)
+
+	// GetContainerMountSource returns the host path or volume name mounted at a container path
+	GetContainerMountSource(ctx context.Context, containerID, destination string) (string, error)
+
+	// Attach runs opts.Cmd with a TTY connected to the terminal on stdin/stdout
+	Attach(ctx context.Context, containerID string, opts ExecOptions) error
+
+	// Exec runs a command, with or without a TTY, and returns its exit code
+	Exec(ctx context.Context, containerID string, opts ExecOptions) (int, error)
+
+	// ExecDetached starts a command in the background without attaching to it
+	ExecDetached(ctx context.Context, containerID string, opts ExecOptions) error
+
+	// Logs streams a container's stdout and stderr, also of a stopped container
+	Logs(ctx context.Context, containerID string, opts LogsOptions, stdout, stderr io.Writer) error
+
+	// EnsureNetwork creates a labeled bridge network if it doesn't exist yet
+	EnsureNetwork(ctx context.Context, name string, internal bool, labels map[string]string) error
+
+	// RemoveNetwork removes a network, ignoring networks that don't exist
+	RemoveNetwork(ctx context.Context, name string) error
+
+	// ListNetworksByLabel returns the IDs of networks carrying a label ("key=value")
+	ListNetworksByLabel(ctx context.Context, label string) ([]string, error)
+
+	// ConnectNetwork attaches a container to a network with DNS aliases
+	ConnectNetwork(ctx context.Context, networkName, containerID string, aliases []string) error
+
+	// EnsureVolume creates a labeled named volume if it doesn't exist yet
+	EnsureVolume(ctx context.Context, name string, labels map[string]string) error
+
+	// RemoveVolume removes a named volume, ignoring volumes that don't exist
+	RemoveVolume(ctx context.Context, name string) error
+
+	// VolumeExists checks if a named volume exists
+	VolumeExists(ctx context.Context, name string) (bool, error)
+
+	// ListVolumesByLabel returns the names of volumes carrying a label ("key=value")
+	ListVolumesByLabel(ctx context.Context, label string) ([]string, error)
+
+	// CopyToContainer extracts a tar archive into a directory of a container
+	CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader) error
+
+	// CopyFromContainer returns a tar archive of a path in a container
+	CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, error)
 }
 
 // ContainerConfig holds container creation options
 type ContainerConfig struct {
+	ImageRef        string            // Image reference (name:tag)
+	ContainerName   string            // Container name
+	WorkDir         string            // Host directory to mount as the workspace
+	WorkspaceVolume string            // Named volume to mount as the workspace instead of WorkDir
+	WorkspaceDir    string            // Container path of the workspace and working directory (default: /workspace)
+	Binds           []string          // Additional bind mounts ("host:container[:mode]")
+	NetworkMode     string            // Network to attach to (default: "bridge"); "none" disables networking
+	DockerSocket    string            // Host Docker socket to mount at /var/run/docker.sock (empty: none)
+	Ports           []string          // Port mappings ("host:container" or "port")
+	Env             map[string]string // Environment variables
+	Command         []string          // Command to run
+	Labels          map[string]string // Container labels
+	StopSignal      string            // Signal sent to stop the container (empty: SIGTERM)
+	StopTimeout     *int              // Seconds to wait after StopSignal before killing (nil: Docker's default)
+
+	// Security restrictions (zero values keep Docker's defaults)
+	CapDrop        []string          // Capabilities to drop ("ALL" for every capability)
+	CapAdd         []string          // Capabilities to add back after dropping
+	SecurityOpt    []string          // Security options, e.g. "no-new-privileges:true"
+	ReadOnlyRootfs bool              // Mount the root filesystem read-only
+	Tmpfs          map[string]string // tmpfs mounts, path to mount options
+}
+
+// SidecarConfig holds options for helper containers that run next to a project container
+type SidecarConfig struct {
 	ImageRef      string            // Image reference (name:tag)
 	ContainerName string            // Container name
-	WorkDir       string            // Host directory to mount as /workspace
-	Ports         []string          // Port mappings ("host:container" or "port")
+	Command       []string          // Command to run (default: image's)
 	Env           map[string]string // Environment variables
-	Command       []string          // Command to run
+	Binds         []string          // Bind mounts ("host:container[:mode]")
+	Ports         []string          // Port mappings ("host:container" or "port")
+	NetworkMode   string            // Network to attach to (default: "bridge")
+	Labels        map[string]string // Container labels
+}
+
+// ExecOptions holds options for running a command in a container
+type ExecOptions struct {
+	Cmd        []string  // Command to run
+	WorkingDir string    // Directory to run in (default: the container's working directory)
+	Env        []string  // Extra environment variables as KEY=value
+	User       string    // User to run as (default: the container's user)
+	Tty        bool      // Allocate a TTY; output then arrives on Stdout only
+	Stdin      io.Reader // Optional input; stdin is not attached when nil
+	Stdout     io.Writer // Output destination (default: os.Stdout)
+	Stderr     io.Writer // Error output destination (default: os.Stderr)
+
+	// DetachKeys, read from Stdin with a TTY, detach from the command and
+	// leave it running; Exec then returns ErrDetached
+	DetachKeys []byte
+	// DetachInput is sent to the command on detach, which is left to exit by
+	// itself. Without it, the connection is closed right away.
+	DetachInput []byte
+	// ReadOnly watches Stdin for DetachKeys without forwarding anything else
+	ReadOnly bool
+	// Resized is called with the terminal's width and height each time the
+	// exec's TTY is resized to match it, starting with the initial size
+	Resized func(width, height int)
 }
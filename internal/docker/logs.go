@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// LogsOptions select the container output to stream
+type LogsOptions struct {
+	Follow     bool      // Keep streaming new output
+	Since      time.Time // Only output written from this time on (zero: all)
+	Timestamps bool      // Prefix each line with the time it was written (RFC3339Nano)
+}
+
+// Logs streams a container's stdout and stderr. Logs of stopped containers
+// can be read too.
+func (c *Client) Logs(ctx context.Context, containerID string, opts LogsOptions, stdout, stderr io.Writer) error {
+	info, err := c.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("inspecting container: %w", err)
+	}
+
+	logsOpts := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     opts.Follow,
+		Timestamps: opts.Timestamps,
+	}
+	if !opts.Since.IsZero() {
+		logsOpts.Since = strconv.FormatInt(opts.Since.Unix(), 10)
+	}
+	reader, err := c.cli.ContainerLogs(ctx, containerID, logsOpts)
+	if err != nil {
+		return fmt.Errorf("reading logs: %w", err)
+	}
+	defer reader.Close()
+
+	// TTY containers have a single raw stream; others multiplex stdout and stderr
+	if info.Config.Tty {
+		_, err = io.Copy(stdout, reader)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, reader)
+	}
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("streaming logs: %w", err)
+	}
+	return nil
+}
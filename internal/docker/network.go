@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+)
+
+// EnsureNetwork creates a labeled bridge network if it doesn't exist yet.
+// Internal networks have no route outside the Docker host.
+func (c *Client) EnsureNetwork(ctx context.Context, name string, internal bool, labels map[string]string) error {
+	_, err := c.cli.NetworkInspect(ctx, name, network.InspectOptions{})
+	if err == nil {
+		return nil
+	}
+	if !cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("inspecting network: %w", err)
+	}
+
+	if _, err := c.cli.NetworkCreate(ctx, name, network.CreateOptions{
+		Driver:   "bridge",
+		Internal: internal,
+		Labels:   labels,
+	}); err != nil {
+		return fmt.Errorf("creating network: %w", err)
+	}
+	return nil
+}
+
+// RemoveNetwork removes a network, ignoring networks that don't exist
+func (c *Client) RemoveNetwork(ctx context.Context, name string) error {
+	if err := c.cli.NetworkRemove(ctx, name); err != nil && !cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("removing network: %w", err)
+	}
+	return nil
+}
+
+// ListNetworksByLabel returns the IDs of networks carrying a label ("key=value")
+func (c *Client) ListNetworksByLabel(ctx context.Context, label string) ([]string, error) {
+	networks, err := c.cli.NetworkList(ctx, network.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", label)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing networks: %w", err)
+	}
+	ids := make([]string, 0, len(networks))
+	for _, n := range networks {
+		ids = append(ids, n.ID)
+	}
+	return ids, nil
+}
+
+// ConnectNetwork attaches a container to a network, reachable under the given DNS aliases
+func (c *Client) ConnectNetwork(ctx context.Context, networkName, containerID string, aliases []string) error {
+	if err := c.cli.NetworkConnect(ctx, networkName, containerID, &network.EndpointSettings{
+		Aliases: aliases,
+	}); err != nil {
+		return fmt.Errorf("connecting container to network %s: %w", networkName, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// EnsureVolume creates a labeled named volume if it doesn't exist yet
+func (c *Client) EnsureVolume(ctx context.Context, name string, labels map[string]string) error {
+	exists, err := c.VolumeExists(ctx, name)
+	if err != nil || exists {
+		return err
+	}
+	if _, err := c.cli.VolumeCreate(ctx, volume.CreateOptions{Name: name, Labels: labels}); err != nil {
+		return fmt.Errorf("creating volume: %w", err)
+	}
+	return nil
+}
+
+// RemoveVolume removes a named volume, ignoring volumes that don't exist
+func (c *Client) RemoveVolume(ctx context.Context, name string) error {
+	if err := c.cli.VolumeRemove(ctx, name, true); err != nil && !cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("removing volume: %w", err)
+	}
+	return nil
+}
+
+// VolumeExists checks if a named volume exists
+func (c *Client) VolumeExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.cli.VolumeInspect(ctx, name)
+	if err == nil {
+		return true, nil
+	}
+	if cerrdefs.IsNotFound(err) {
+		return false, nil
+	}
+	return false, fmt.Errorf("inspecting volume: %w", err)
+}
+
+// ListVolumesByLabel returns the names of volumes carrying a label ("key=value")
+func (c *Client) ListVolumesByLabel(ctx context.Context, label string) ([]string, error) {
+	resp, err := c.cli.VolumeList(ctx, volume.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", label)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing volumes: %w", err)
+	}
+	names := make([]string, 0, len(resp.Volumes))
+	for _, v := range resp.Volumes {
+		names = append(names, v.Name)
+	}
+	return names, nil
+}
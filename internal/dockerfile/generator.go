@@ -2,11 +2,13 @@ package dockerfile
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"strings"
 	"text/template"
 
 	"github.com/wfaler/rig/internal/config"
+	"github.com/wfaler/rig/internal/supervisor"
 )
 
 // TemplateData holds the data passed to the Dockerfile template
@@ -21,10 +23,27 @@ type TemplateData struct {
 	CodeServerTheme      string
 	CodeServerExtensions []string
 	Shell                string
+	Hardened             bool
+	WorkspaceDir         string
+	Supervisor           string // Base64 of the supervisor script
+	SupervisorPath       string
+	ProcessesDir         string
+	Processes            []ProcessFiles
+	PreStop              string // Base64 of the pre_stop hooks script, empty without hooks
+	PreStopPath          string
 }
 
-// Generate creates a Dockerfile string from the config
-func Generate(cfg *config.Config) (string, error) {
+// ProcessFiles holds a supervised process's definition files. The run script
+// is base64 encoded so arbitrary commands survive the Dockerfile's quoting.
+type ProcessFiles struct {
+	Name    string
+	Run     string
+	Restart string
+}
+
+// Generate creates a Dockerfile string from the config, with workspaceDir
+// (the project's path in the container) as the working directory
+func Generate(cfg *config.Config, workspaceDir string) (string, error) {
 	// Build language installation commands
 	var langInstalls []string
 	for lang, langCfg := range cfg.Languages {
@@ -49,6 +68,20 @@ func Generate(cfg *config.Config) (string, error) {
 		extensions = cfg.GetCodeServerExtensions()
 	}
 
+	var processes []ProcessFiles
+	for _, p := range supervisor.Processes(cfg) {
+		processes = append(processes, ProcessFiles{
+			Name:    p.Name,
+			Run:     base64.StdEncoding.EncodeToString([]byte(supervisor.RunScript(p))),
+			Restart: p.Restart,
+		})
+	}
+
+	var preStop string
+	if hooks := cfg.GetPreStop(); len(hooks) > 0 {
+		preStop = base64.StdEncoding.EncodeToString([]byte(supervisor.PreStopScript(hooks)))
+	}
+
 	data := TemplateData{
 		LanguageInstalls:     strings.Join(langInstalls, "\n\n"),
 		BuildSystemInstalls:  strings.Join(bsInstalls, "\n\n"),
@@ -60,6 +93,14 @@ func Generate(cfg *config.Config) (string, error) {
 		CodeServerTheme:      cfg.GetCodeServerTheme(),
 		CodeServerExtensions: extensions,
 		Shell:                cfg.GetShell(),
+		Hardened:             cfg.IsHardened(),
+		WorkspaceDir:         workspaceDir,
+		Supervisor:           base64.StdEncoding.EncodeToString([]byte(supervisor.Script)),
+		SupervisorPath:       supervisor.ScriptPath,
+		ProcessesDir:         supervisor.DefinitionsDir,
+		Processes:            processes,
+		PreStop:              preStop,
+		PreStopPath:          supervisor.PreStopPath,
 	}
 
 	tmpl, err := template.New("dockerfile").Parse(BaseTemplate)
@@ -1,12 +1,15 @@
 package dockerfile
 
 import (
+	"encoding/base64"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/wfaler/rig/internal/config"
+	"github.com/wfaler/rig/internal/project"
+	"github.com/wfaler/rig/internal/supervisor"
 )
 
 func TestGenerate(t *testing.T) {
@@ -111,7 +114,7 @@ func TestGenerate(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			dockerfile, err := Generate(tt.config)
+			dockerfile, err := Generate(tt.config, config.DefaultWorkspaceDir)
 			require.NoError(t, err)
 
 			for _, want := range tt.wantContains {
@@ -133,7 +136,7 @@ func TestGenerateDockerfileStructure(t *testing.T) {
 		Env: map[string]string{},
 	}
 
-	dockerfile, err := Generate(cfg)
+	dockerfile, err := Generate(cfg, config.DefaultWorkspaceDir)
 	require.NoError(t, err)
 
 	// Verify basic structure
@@ -216,7 +219,7 @@ func TestGenerateWithCodeServer(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			dockerfile, err := Generate(tt.config)
+			dockerfile, err := Generate(tt.config, config.DefaultWorkspaceDir)
 			require.NoError(t, err)
 
 			for _, want := range tt.wantContains {
@@ -238,7 +241,7 @@ func TestGenerateWithJavaIncludesSDKMAN(t *testing.T) {
 		Env: map[string]string{},
 	}
 
-	dockerfile, err := Generate(cfg)
+	dockerfile, err := Generate(cfg, config.DefaultWorkspaceDir)
 	require.NoError(t, err)
 
 	// Should include SDKMAN installation
@@ -344,7 +347,7 @@ func TestGenerateWithShellConfiguration(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			dockerfile, err := Generate(tt.config)
+			dockerfile, err := Generate(tt.config, config.DefaultWorkspaceDir)
 			require.NoError(t, err)
 
 			for _, want := range tt.wantContains {
@@ -357,3 +360,98 @@ func TestGenerateWithShellConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateHardened(t *testing.T) {
+	cfg := &config.Config{
+		Languages: map[string]config.LanguageConfig{},
+		Env:       map[string]string{},
+	}
+	dockerfile, err := Generate(cfg, config.DefaultWorkspaceDir)
+	require.NoError(t, err)
+	assert.Contains(t, dockerfile, "NOPASSWD:ALL")
+	assert.Contains(t, dockerfile, "sudo chmod 666 /var/run/docker.sock")
+
+	cfg.Security = &config.SecurityConfig{Profile: "hardened"}
+	dockerfile, err = Generate(cfg, config.DefaultWorkspaceDir)
+	require.NoError(t, err)
+	assert.NotContains(t, dockerfile, "NOPASSWD")
+	assert.NotContains(t, dockerfile, "sudo chmod")
+	assert.Contains(t, dockerfile, "RUN useradd -m -s /bin/zsh developer\n")
+	assert.Contains(t, dockerfile, "'#!/bin/bash' \\\n    '# Start supervised processes")
+}
+
+func TestGenerateWorkspaceDir(t *testing.T) {
+	cfg := &config.Config{
+		Languages: map[string]config.LanguageConfig{},
+		Env:       map[string]string{},
+	}
+	dockerfile, err := Generate(cfg, config.DefaultWorkspaceDir)
+	require.NoError(t, err)
+	assert.Contains(t, dockerfile, "WORKDIR /workspace\n")
+
+	dockerfile, err = Generate(cfg, "/home/user/work/api")
+	require.NoError(t, err)
+	assert.Contains(t, dockerfile, "WORKDIR /home/user/work/api\n")
+	assert.NotContains(t, dockerfile, "WORKDIR /workspace")
+}
+
+func TestGenerateProcesses(t *testing.T) {
+	cfg := &config.Config{
+		Languages: map[string]config.LanguageConfig{},
+		Env:       map[string]string{},
+	}
+	dockerfile, err := Generate(cfg, config.DefaultWorkspaceDir)
+	require.NoError(t, err)
+	assert.Contains(t, dockerfile, "base64 -d > "+supervisor.ScriptPath)
+	assert.Contains(t, dockerfile, "'"+supervisor.ScriptPath+" boot'")
+	assert.NotContains(t, dockerfile, supervisor.DefinitionsDir+"/")
+
+	cfg.Processes = map[string]config.ProcessConfig{
+		"web": {Command: "npm run dev 'quoted'", Restart: "always"},
+	}
+	cfg.CodeServer = &config.CodeServerConfig{Enabled: true}
+	dockerfile, err = Generate(cfg, config.DefaultWorkspaceDir)
+	require.NoError(t, err)
+	assert.NotContains(t, dockerfile, "> /tmp/code-server.log")
+
+	for _, p := range supervisor.Processes(cfg) {
+		run := base64.StdEncoding.EncodeToString([]byte(supervisor.RunScript(p)))
+		dir := supervisor.DefinitionsDir + "/" + p.Name
+		assert.Contains(t, dockerfile, "echo '"+run+"' | base64 -d > "+dir+"/run")
+		assert.Contains(t, dockerfile, "echo '"+p.Restart+"' > "+dir+"/restart")
+	}
+
+	// Process definitions come after the tool installs so editing them keeps the cache
+	assert.Greater(t, strings.Index(dockerfile, supervisor.DefinitionsDir+"/web"), strings.Index(dockerfile, "npm install -g"))
+}
+
+func TestGenerateShutdown(t *testing.T) {
+	cfg := &config.Config{
+		Languages: map[string]config.LanguageConfig{},
+		Env:       map[string]string{},
+	}
+	dockerfile, err := Generate(cfg, config.DefaultWorkspaceDir)
+	require.NoError(t, err)
+	assert.Contains(t, dockerfile, `ENTRYPOINT ["/usr/bin/tini", "-g", "--", "/usr/local/bin/docker-entrypoint.sh"]`)
+	assert.Contains(t, dockerfile, "'env --default-signal "+supervisor.ScriptPath+" watch &'")
+	assert.NotContains(t, dockerfile, supervisor.PreStopPath)
+
+	cfg.Shutdown = &config.ShutdownConfig{PreStop: []string{"./scripts/flush.sh"}}
+	dockerfile, err = Generate(cfg, config.DefaultWorkspaceDir)
+	require.NoError(t, err)
+	script := base64.StdEncoding.EncodeToString([]byte(supervisor.PreStopScript(cfg.Shutdown.PreStop)))
+	assert.Contains(t, dockerfile, "echo '"+script+"' | base64 -d > "+supervisor.PreStopPath)
+}
+
+// imageTemplateHashes pins the template and supervisor script of each image
+// version. Images from older rig versions lack tini and rig-supervise and are
+// only rebuilt when project.ImageVersion changes.
+var imageTemplateHashes = map[string]string{
+	"2": "95a31ca86839",
+}
+
+func TestImageVersion(t *testing.T) {
+	got := project.ComputeHash([]byte(BaseTemplate + supervisor.Script))
+	assert.Equal(t, imageTemplateHashes[project.ImageVersion], got,
+		"the image template changed: bump project.ImageVersion and pin the new hash")
+}
@@ -28,9 +28,11 @@ RUN apt-get update && apt-get install -y --no-install-recommends \
     less \
     tmux \
     jq \
+    tini \
     unzip \
     zip \
     procps \
+    socat \
     libssl-dev \
     zlib1g-dev \
     libbz2-dev \
@@ -61,8 +63,13 @@ RUN curl -fsSL https://code-server.dev/install.sh | sh
 {{ end }}
 
 # Create non-root user for development
+{{ if .Hardened -}}
+# Hardened: no sudo access for the developer user
+RUN useradd -m -s /bin/{{ .Shell }} developer
+{{- else -}}
 RUN useradd -m -s /bin/{{ .Shell }} developer \
     && echo "developer ALL=(ALL) NOPASSWD:ALL" >> /etc/sudoers
+{{- end }}
 
 # Add developer to docker group for socket access
 RUN groupadd -f docker && usermod -aG docker developer
@@ -74,21 +81,29 @@ RUN sh -c "$(curl -fsSL https://raw.githubusercontent.com/ohmyzsh/ohmyzsh/master
 USER root
 {{ end }}
 
-# Create entrypoint script to fix Docker socket permissions and start services
+# Install the process supervisor (see 'rig ps')
+RUN echo '{{ .Supervisor }}' | base64 -d > {{ .SupervisorPath }} \
+    && chmod +x {{ .SupervisorPath }}
+
+# Create entrypoint script to fix Docker socket permissions and start processes
 RUN printf '%s\n' '#!/bin/bash' \
+{{- if not .Hardened }}
     '# Fix Docker socket permissions' \
     'if [ -S /var/run/docker.sock ]; then' \
     '  sudo chmod 666 /var/run/docker.sock' \
     'fi' \
-    '# Start code-server in background if installed' \
-    'if command -v code-server > /dev/null 2>&1; then' \
-    '  code-server --bind-addr 0.0.0.0:${CODE_SERVER_PORT:-8080} --auth none > /tmp/code-server.log 2>&1 &' \
-    '  echo "code-server started on http://localhost:${CODE_SERVER_PORT:-8080}"' \
-    'fi' \
+{{- end }}
+    '# Start supervised processes (processes: and code-server) in the background' \
+    '{{ .SupervisorPath }} boot' \
+    '# Pass the stop signal from init on to them; signals ignored by this script are reset first' \
+    'env --default-signal {{ .SupervisorPath }} watch &' \
+    '# The command ignores them; the watcher kills it once the processes have stopped' \
+    'trap "" TERM INT QUIT HUP USR1 USR2' \
     'exec "$@"' > /usr/local/bin/docker-entrypoint.sh \
     && chmod +x /usr/local/bin/docker-entrypoint.sh
 
-ENTRYPOINT ["/usr/local/bin/docker-entrypoint.sh"]
+# tini reaps orphaned processes and forwards signals to the shell's process group
+ENTRYPOINT ["/usr/bin/tini", "-g", "--", "/usr/local/bin/docker-entrypoint.sh"]
 
 # Switch to developer user for tool installation
 USER developer
@@ -120,7 +135,7 @@ RUN mise use --global node@lts
 {{ end }}
 
 # Install AI agents via npm
-RUN eval "$(~/.local/bin/mise activate bash)" && npm install -g @anthropic-ai/claude-code @google/gemini-cli openai
+RUN eval "$(~/.local/bin/mise activate bash)" && npm install -g @anthropic-ai/claude-code @google/gemini-cli @openai/codex openai
 
 {{ .BuildSystemInstalls }}
 
@@ -144,7 +159,28 @@ RUN {{ range $i, $ext := .CodeServerExtensions }}{{ if $i }} && {{ end }}code-se
 {{ end }}
 {{ end }}
 
-WORKDIR /workspace
+{{ if .Processes }}
+# Supervised processes: a run script and restart policy each. Near the end so
+# editing them doesn't invalidate the tool installation layers.
+USER root
+{{ range .Processes -}}
+RUN mkdir -p {{ $.ProcessesDir }}/{{ .Name }} \
+    && echo '{{ .Run }}' | base64 -d > {{ $.ProcessesDir }}/{{ .Name }}/run \
+    && chmod +x {{ $.ProcessesDir }}/{{ .Name }}/run \
+    && echo '{{ .Restart }}' > {{ $.ProcessesDir }}/{{ .Name }}/restart
+{{ end -}}
+USER developer
+{{ end }}
+{{ if .PreStop }}
+# shutdown.pre_stop hooks, run by 'rig down' before stopping the container
+USER root
+RUN mkdir -p $(dirname {{ .PreStopPath }}) \
+    && echo '{{ .PreStop }}' | base64 -d > {{ .PreStopPath }} \
+    && chmod +x {{ .PreStopPath }}
+USER developer
+{{ end }}
+
+WORKDIR {{ .WorkspaceDir }}
 
 {{ range $key, $value := .Env }}
 ENV {{ $key }}="{{ $value }}"
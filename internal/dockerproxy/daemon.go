@@ -0,0 +1,109 @@
+package dockerproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// apiDaemon looks up resources through the Docker API
+type apiDaemon struct {
+	client *http.Client
+}
+
+// NewDaemon returns a Daemon querying the Docker socket at upstream
+func NewDaemon(upstream string) Daemon {
+	return &apiDaemon{client: &http.Client{Transport: upstreamTransport(upstream)}}
+}
+
+// upstreamTransport dials the Docker socket at upstream for every request
+func upstreamTransport(upstream string) *http.Transport {
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", upstream)
+		},
+	}
+}
+
+// Lookup inspects a container, network, volume or image
+func (d *apiDaemon) Lookup(kind, id string) (*Resource, error) {
+	// Only image references have slashes, and none have dot segments that
+	// would point the lookup at another resource
+	if (kind != KindImage && strings.Contains(id, "/")) || hasDotSegment(id) {
+		return nil, nil
+	}
+	apiPath := "/" + kind + "/" + id
+	if kind == KindContainer || kind == KindImage {
+		apiPath += "/json"
+	}
+
+	// Containers and images keep their labels in Config, networks and volumes at the top
+	var inspect struct {
+		Name   string
+		Labels map[string]string
+		Config struct {
+			Labels map[string]string
+		}
+	}
+	found, err := d.get(apiPath, &inspect)
+	if err != nil || !found {
+		return nil, err
+	}
+	r := &Resource{Name: strings.TrimPrefix(inspect.Name, "/"), Labels: inspect.Labels}
+	if kind == KindContainer || kind == KindImage {
+		r.Labels = inspect.Config.Labels
+	}
+	return r, nil
+}
+
+// ExecContainer returns the ID of the container an exec instance runs in
+func (d *apiDaemon) ExecContainer(id string) (string, error) {
+	var inspect struct {
+		ContainerID string
+	}
+	found, err := d.get("/exec/"+id+"/json", &inspect)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("no such exec instance")
+	}
+	return inspect.ContainerID, nil
+}
+
+// get decodes the response to a GET request into v. It returns false if
+// the resource doesn't exist. apiPath is escaped except for its slashes,
+// which image references contain.
+func (d *apiDaemon) get(apiPath string, v any) (bool, error) {
+	u := url.URL{Scheme: "http", Host: "docker", Path: apiPath}
+	resp, err := d.client.Get(u.String())
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode != http.StatusOK:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return true, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// hasDotSegment reports whether a path has a "." or ".." segment
+func hasDotSegment(p string) bool {
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,631 @@
+package dockerproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// versionPrefix matches the optional API version prefix, e.g. "/v1.43"
+var versionPrefix = regexp.MustCompile(`^/v[0-9]+(\.[0-9]+)?`)
+
+// route is an allowed API endpoint
+type route struct {
+	method  string
+	pattern *regexp.Regexp
+}
+
+// allowedRoutes are the Docker API calls needed by testcontainers and
+// similar tooling: pinging the daemon, pulling and building images, and
+// managing containers, exec sessions, networks and volumes
+var allowedRoutes = []route{
+	{"GET", regexp.MustCompile(`^/_ping$`)},
+	{"HEAD", regexp.MustCompile(`^/_ping$`)},
+	{"GET", regexp.MustCompile(`^/version$`)},
+	{"GET", regexp.MustCompile(`^/info$`)},
+	{"GET", regexp.MustCompile(`^/events$`)},
+
+	{"GET", regexp.MustCompile(`^/images/json$`)},
+	{"POST", regexp.MustCompile(`^/images/create$`)},
+	{"GET", regexp.MustCompile(`^/images/.+/json$`)},
+	{"POST", regexp.MustCompile(`^/images/.+/tag$`)},
+	{"DELETE", regexp.MustCompile(`^/images/.+$`)},
+	{"POST", regexp.MustCompile(`^/build$`)},
+
+	{"GET", regexp.MustCompile(`^/containers/json$`)},
+	{"POST", regexp.MustCompile(`^/containers/create$`)},
+	{"GET", regexp.MustCompile(`^/containers/[^/]+/(json|logs|top|stats)$`)},
+	{"POST", regexp.MustCompile(`^/containers/[^/]+/(start|stop|restart|kill|wait|attach|resize|pause|unpause|exec)$`)},
+	{"GET", regexp.MustCompile(`^/containers/[^/]+/archive$`)},
+	{"HEAD", regexp.MustCompile(`^/containers/[^/]+/archive$`)},
+	{"PUT", regexp.MustCompile(`^/containers/[^/]+/archive$`)},
+	{"DELETE", regexp.MustCompile(`^/containers/[^/]+$`)},
+
+	{"POST", regexp.MustCompile(`^/exec/[^/]+/(start|resize)$`)},
+	{"GET", regexp.MustCompile(`^/exec/[^/]+/json$`)},
+
+	{"GET", regexp.MustCompile(`^/networks$`)},
+	{"POST", regexp.MustCompile(`^/networks/create$`)},
+	{"GET", regexp.MustCompile(`^/networks/[^/]+$`)},
+	{"POST", regexp.MustCompile(`^/networks/[^/]+/(connect|disconnect)$`)},
+	{"DELETE", regexp.MustCompile(`^/networks/[^/]+$`)},
+
+	{"GET", regexp.MustCompile(`^/volumes$`)},
+	{"POST", regexp.MustCompile(`^/volumes/create$`)},
+	{"GET", regexp.MustCompile(`^/volumes/[^/]+$`)},
+	{"DELETE", regexp.MustCompile(`^/volumes/[^/]+$`)},
+}
+
+// Routes on existing resources, which must have been created through the proxy
+var (
+	containerRoute = regexp.MustCompile(`^/containers/([^/]+)(/[^/]+)?$`)
+	execRoute      = regexp.MustCompile(`^/exec/([^/]+)/[^/]+$`)
+	networkRoute   = regexp.MustCompile(`^/networks/([^/]+)(/(connect|disconnect))?$`)
+	volumeRoute    = regexp.MustCompile(`^/volumes/([^/]+)$`)
+	imageTagRoute  = regexp.MustCompile(`^/images/(.+)/tag$`)
+	imageRoute     = regexp.MustCompile(`^/images/(.+)$`)
+)
+
+// ReservedImagePrefix starts the names of images rig builds and runs, which
+// containers must not be able to replace
+const ReservedImagePrefix = "rig-"
+
+// Kinds of resources looked up on the daemon
+const (
+	KindContainer = "containers"
+	KindNetwork   = "networks"
+	KindVolume    = "volumes"
+	KindImage     = "images"
+)
+
+// Resource is an existing container, network, volume or image
+type Resource struct {
+	Name   string
+	Labels map[string]string
+}
+
+// Daemon looks up existing resources for the ownership checks
+type Daemon interface {
+	// Lookup returns a container, network, volume or image given by ID or
+	// name, or nil if it doesn't exist
+	Lookup(kind, id string) (*Resource, error)
+
+	// ExecContainer returns the ID of the container an exec instance runs in
+	ExecContainer(id string) (string, error)
+}
+
+// createRequest holds the fields of a container create request that are checked
+type createRequest struct {
+	HostConfig struct {
+		Privileged        bool
+		Binds             []string
+		NetworkMode       string
+		CapAdd            []string
+		PidMode           string
+		IpcMode           string
+		UTSMode           string
+		UsernsMode        string
+		CgroupnsMode      string
+		SecurityOpt       []string
+		VolumesFrom       []string
+		Devices           []json.RawMessage
+		DeviceRequests    []json.RawMessage
+		DeviceCgroupRules []string
+		Sysctls           map[string]string
+		Mounts            []struct {
+			Type          string
+			Source        string
+			VolumeOptions *struct {
+				DriverConfig *struct {
+					Name    string
+					Options map[string]string
+				}
+			}
+		}
+	}
+	NetworkingConfig struct {
+		EndpointsConfig map[string]json.RawMessage
+	}
+}
+
+// Filter decides which Docker API requests are forwarded to the daemon
+type Filter struct {
+	// Workspace is the host directory that containers may bind-mount (with its
+	// subdirectories). Symlinks are resolved, so it must be visible at the same
+	// path to the proxy.
+	Workspace string
+
+	// Parent is the rig container the proxy serves. Existing containers,
+	// networks and volumes can only be used if labeled with ParentLabel=Parent.
+	Parent string
+
+	// Network, if set, is the only network containers may join besides the
+	// ones created through the proxy, which are made internal. It is the
+	// egress network, so children can't get around the egress rules.
+	Network string
+
+	// Daemon looks up the labels of existing resources
+	Daemon Daemon
+}
+
+// Check returns an error describing why a request is denied, or nil if it is allowed.
+// requestURI is the request's path with its query; body is inspected for
+// create, exec and network connect requests.
+func (f *Filter) Check(method, requestURI string, body []byte) error {
+	urlPath, rawQuery, _ := strings.Cut(requestURI, "?")
+	apiPath := versionPrefix.ReplaceAllString(urlPath, "")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return fmt.Errorf("invalid query: %v", err)
+	}
+
+	allowed := false
+	for _, r := range allowedRoutes {
+		if r.method == method && r.pattern.MatchString(apiPath) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("endpoint %s %s is not allowed", method, apiPath)
+	}
+
+	switch {
+	case method == http.MethodPost && apiPath == "/containers/create":
+		return f.checkCreate(body)
+	case method == http.MethodPost && apiPath == "/volumes/create":
+		return checkVolumeCreate(body)
+	case method == http.MethodPost && apiPath == "/build":
+		return f.checkBuild(query)
+	case method == http.MethodPost && apiPath == "/images/create":
+		// Importing from a URL would have the daemon fetch it
+		if src := query.Get("fromSrc"); src != "" && src != "-" {
+			return fmt.Errorf("importing images from a URL is not allowed")
+		}
+		if err := checkImageName(query.Get("fromImage")); err != nil {
+			return err
+		}
+		return checkImageName(query.Get("repo"))
+	case apiPath == "/containers/json" || apiPath == "/networks/create":
+		return nil
+	}
+
+	if m := containerRoute.FindStringSubmatch(apiPath); m != nil {
+		// The rig container may inspect itself, e.g. to find its network
+		if method == http.MethodGet && m[2] == "/json" && f.isParent(m[1]) {
+			return nil
+		}
+		if err := f.checkOwned(KindContainer, m[1]); err != nil {
+			return err
+		}
+		if m[2] == "/exec" {
+			return checkExec(body)
+		}
+		return nil
+	}
+	if m := execRoute.FindStringSubmatch(apiPath); m != nil {
+		if f.Daemon == nil {
+			return fmt.Errorf("exec %s was not created from within %s", m[1], f.Parent)
+		}
+		containerID, err := f.Daemon.ExecContainer(m[1])
+		if err != nil {
+			return fmt.Errorf("looking up exec %s: %v", m[1], err)
+		}
+		return f.checkOwned(KindContainer, containerID)
+	}
+	if m := networkRoute.FindStringSubmatch(apiPath); m != nil && method != http.MethodGet {
+		if m[2] == "" {
+			return f.checkOwned(KindNetwork, m[1])
+		}
+		return f.checkConnect(m[1], body)
+	}
+	if m := volumeRoute.FindStringSubmatch(apiPath); m != nil && method == http.MethodDelete {
+		return f.checkOwned(KindVolume, m[1])
+	}
+	// Images built through the proxy carry ParentLabel; others, pulled or
+	// the host's, can be inspected but not tagged or removed
+	if m := imageTagRoute.FindStringSubmatch(apiPath); m != nil && method == http.MethodPost {
+		if err := checkImageName(query.Get("repo")); err != nil {
+			return err
+		}
+		return f.checkOwned(KindImage, m[1])
+	}
+	if m := imageRoute.FindStringSubmatch(apiPath); m != nil && method == http.MethodDelete {
+		return f.checkOwned(KindImage, m[1])
+	}
+	return nil
+}
+
+// Rewrite adjusts an allowed request body before it is forwarded. With
+// Network set, containers asking for the default network join it instead
+// and new networks are internal.
+func (f *Filter) Rewrite(method, urlPath string, body []byte) ([]byte, error) {
+	if f.Network == "" || method != http.MethodPost {
+		return body, nil
+	}
+	switch versionPrefix.ReplaceAllString(urlPath, "") {
+	case "/containers/create":
+		network, err := json.Marshal(f.Network)
+		if err != nil {
+			return nil, err
+		}
+		body, err = setField(body, "HostConfig", func(hc map[string]json.RawMessage) error {
+			var mode string
+			if raw, ok := hc["NetworkMode"]; ok {
+				if err := json.Unmarshal(raw, &mode); err != nil {
+					return err
+				}
+			}
+			if isDefaultNetwork(mode) {
+				hc["NetworkMode"] = network
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return setField(body, "NetworkingConfig", func(nc map[string]json.RawMessage) error {
+			raw, ok := nc["EndpointsConfig"]
+			if !ok || string(raw) == "null" {
+				return nil
+			}
+			endpoints := map[string]json.RawMessage{}
+			if err := json.Unmarshal(raw, &endpoints); err != nil {
+				return err
+			}
+			for name, endpoint := range endpoints {
+				if isDefaultNetwork(name) {
+					delete(endpoints, name)
+					endpoints[f.Network] = endpoint
+				}
+			}
+			raw, err := json.Marshal(endpoints)
+			nc["EndpointsConfig"] = raw
+			return err
+		})
+	case "/networks/create":
+		return setField(body, "", func(fields map[string]json.RawMessage) error {
+			fields["Internal"] = json.RawMessage("true")
+			return nil
+		})
+	}
+	return body, nil
+}
+
+// checkCreate rejects container configurations that could escape the sandbox
+func (f *Filter) checkCreate(body []byte) error {
+	var req createRequest
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid container create request: %v", err)
+		}
+	}
+	hc := req.HostConfig
+
+	if hc.Privileged {
+		return fmt.Errorf("privileged containers are not allowed")
+	}
+	if len(hc.CapAdd) > 0 {
+		return fmt.Errorf("adding capabilities is not allowed (%s)", strings.Join(hc.CapAdd, ", "))
+	}
+	if hc.NetworkMode == "host" {
+		return fmt.Errorf("host network mode is not allowed")
+	}
+	if hc.PidMode == "host" || hc.IpcMode == "host" || hc.UTSMode == "host" || hc.UsernsMode == "host" || hc.CgroupnsMode == "host" {
+		return fmt.Errorf("host PID, IPC, UTS, user and cgroup namespaces are not allowed")
+	}
+	for _, mode := range []string{hc.NetworkMode, hc.PidMode, hc.IpcMode} {
+		if strings.HasPrefix(mode, "container:") {
+			return fmt.Errorf("sharing namespaces with another container is not allowed (%s)", mode)
+		}
+	}
+	for _, opt := range hc.SecurityOpt {
+		if !isNoNewPrivileges(opt) {
+			return fmt.Errorf("security option %s is not allowed", opt)
+		}
+	}
+	if len(hc.Devices) > 0 || len(hc.DeviceRequests) > 0 || len(hc.DeviceCgroupRules) > 0 {
+		return fmt.Errorf("device mappings are not allowed")
+	}
+	if len(hc.Sysctls) > 0 {
+		return fmt.Errorf("sysctls are not allowed")
+	}
+	for _, source := range hc.VolumesFrom {
+		id, _, _ := strings.Cut(source, ":")
+		if err := f.checkOwned(KindContainer, id); err != nil {
+			return fmt.Errorf("volumes from %s: %w", id, err)
+		}
+	}
+
+	// Default networks are replaced by Network in Rewrite
+	if err := f.checkNetwork(hc.NetworkMode, true); err != nil {
+		return err
+	}
+	for name := range req.NetworkingConfig.EndpointsConfig {
+		if err := f.checkNetwork(name, true); err != nil {
+			return err
+		}
+	}
+
+	for _, bind := range hc.Binds {
+		source, _, _ := strings.Cut(bind, ":")
+		if err := f.checkSource(source); err != nil {
+			return err
+		}
+	}
+	for _, m := range hc.Mounts {
+		switch m.Type {
+		case "bind":
+			if err := f.checkHostPath(m.Source); err != nil {
+				return err
+			}
+		case "volume", "":
+			if m.VolumeOptions != nil && m.VolumeOptions.DriverConfig != nil {
+				if err := checkVolumeDriver(m.VolumeOptions.DriverConfig.Name, m.VolumeOptions.DriverConfig.Options); err != nil {
+					return err
+				}
+			}
+			if m.Source != "" {
+				if err := f.checkVolume(m.Source); err != nil {
+					return err
+				}
+			}
+		case "tmpfs":
+		default:
+			return fmt.Errorf("%s mounts are not allowed", m.Type)
+		}
+	}
+	return nil
+}
+
+// checkBuild keeps built images from taking rig's names, and the RUN steps
+// of builds off the host network and, with Network set, off the network
+// entirely, as builds can't join it
+func (f *Filter) checkBuild(query url.Values) error {
+	for _, tag := range query["t"] {
+		if err := checkImageName(tag); err != nil {
+			return err
+		}
+	}
+	mode := query.Get("networkmode")
+	if mode == "host" {
+		return fmt.Errorf("host network mode is not allowed")
+	}
+	if f.Network != "" && mode != "none" {
+		return fmt.Errorf("builds must use networkmode=none with restricted egress")
+	}
+	return nil
+}
+
+// checkImageName rejects image references in rig's namespace, which would
+// replace the image the next 'rig up' of some project runs
+func checkImageName(ref string) error {
+	repo := ref
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo = repo[:i]
+	}
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	// Docker Hub names are stored without these prefixes
+	for _, prefix := range []string{"docker.io/", "index.docker.io/", "library/"} {
+		repo = strings.TrimPrefix(repo, prefix)
+	}
+	if strings.HasPrefix(repo, ReservedImagePrefix) {
+		return fmt.Errorf("image name %s is reserved for rig", ref)
+	}
+	return nil
+}
+
+// checkExec rejects privileged exec sessions
+func checkExec(body []byte) error {
+	var req struct{ Privileged bool }
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid exec request: %v", err)
+		}
+	}
+	if req.Privileged {
+		return fmt.Errorf("privileged exec is not allowed")
+	}
+	return nil
+}
+
+// checkConnect allows connecting and disconnecting the proxy's own
+// containers, to networks they may join
+func (f *Filter) checkConnect(network string, body []byte) error {
+	var req struct{ Container string }
+	if err := json.Unmarshal(body, &req); err != nil {
+		return fmt.Errorf("invalid network request: %v", err)
+	}
+	if err := f.checkOwned(KindContainer, req.Container); err != nil {
+		return err
+	}
+	return f.checkNetwork(network, false)
+}
+
+// checkNetwork allows a container to join a network: with Network set, that
+// one or one created through the proxy; otherwise any but the host's.
+// allowDefault lets through default networks that Rewrite replaces.
+func (f *Filter) checkNetwork(name string, allowDefault bool) error {
+	if f.Network == "" || name == f.Network || name == "none" || (allowDefault && isDefaultNetwork(name)) {
+		return nil
+	}
+	if err := f.checkOwned(KindNetwork, name); err != nil {
+		return fmt.Errorf("network %s is not allowed with restricted egress: %w", name, err)
+	}
+	return nil
+}
+
+// checkSource checks the source of a bind: a host path or a volume name
+func (f *Filter) checkSource(source string) error {
+	if strings.HasPrefix(source, "/") {
+		return f.checkHostPath(source)
+	}
+	return f.checkVolume(source)
+}
+
+// checkVolume allows named volumes created through the proxy, or not yet
+// existing ones, which the daemon creates without options
+func (f *Filter) checkVolume(name string) error {
+	if f.Daemon == nil {
+		return fmt.Errorf("volume %s was not created from within %s", name, f.Parent)
+	}
+	volume, err := f.Daemon.Lookup(KindVolume, name)
+	if err != nil {
+		return fmt.Errorf("looking up volume %s: %v", name, err)
+	}
+	if volume != nil && !f.owns(volume) {
+		return fmt.Errorf("volume %s was not created from within %s", name, f.Parent)
+	}
+	return nil
+}
+
+// checkHostPath allows host paths inside the workspace. Symlinks are
+// resolved first, so a link in the workspace can't point the mount elsewhere.
+func (f *Filter) checkHostPath(source string) error {
+	if f.Workspace == "" {
+		return fmt.Errorf("bind mount of %s is outside the workspace", source)
+	}
+	workspace, err := filepath.EvalSymlinks(path.Clean(f.Workspace))
+	if err != nil {
+		return fmt.Errorf("resolving workspace: %v", err)
+	}
+	resolved, err := resolveExisting(path.Clean(source))
+	if err != nil {
+		return fmt.Errorf("resolving %s: %v", source, err)
+	}
+	if resolved == workspace || strings.HasPrefix(resolved, workspace+"/") {
+		return nil
+	}
+	return fmt.Errorf("bind mount of %s is outside the workspace", source)
+}
+
+// resolveExisting resolves the symlinks in the longest existing leading part
+// of an absolute path; the rest doesn't exist and can't hold links yet
+func resolveExisting(p string) (string, error) {
+	rest := ""
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return path.Join(resolved, rest), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) || p == "/" {
+			return "", err
+		}
+		rest = path.Join(path.Base(p), rest)
+		p = path.Dir(p)
+	}
+}
+
+// checkVolumeCreate rejects volumes whose driver options could mount host paths
+func checkVolumeCreate(body []byte) error {
+	var req struct {
+		Driver     string
+		DriverOpts map[string]string
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid volume create request: %v", err)
+		}
+	}
+	return checkVolumeDriver(req.Driver, req.DriverOpts)
+}
+
+// checkVolumeDriver allows the local driver with no options, or as a tmpfs
+func checkVolumeDriver(driver string, opts map[string]string) error {
+	if driver != "" && driver != "local" {
+		return fmt.Errorf("volume driver %s is not allowed", driver)
+	}
+	for key, value := range opts {
+		switch {
+		case key == "type" && value == "tmpfs", key == "device" && value == "tmpfs":
+		case key == "o" && opts["type"] == "tmpfs" && !strings.Contains(value, "bind"):
+		default:
+			return fmt.Errorf("volume option %s=%s is not allowed", key, value)
+		}
+	}
+	return nil
+}
+
+// checkOwned requires a resource to have been created from within the parent
+func (f *Filter) checkOwned(kind, id string) error {
+	if f.Parent == "" || f.Daemon == nil {
+		return fmt.Errorf("%s %s was not created from within the rig container", strings.TrimSuffix(kind, "s"), id)
+	}
+	resource, err := f.Daemon.Lookup(kind, id)
+	if err != nil {
+		return fmt.Errorf("looking up %s %s: %v", strings.TrimSuffix(kind, "s"), id, err)
+	}
+	if resource == nil || !f.owns(resource) {
+		return fmt.Errorf("%s %s was not created from within %s", strings.TrimSuffix(kind, "s"), id, f.Parent)
+	}
+	return nil
+}
+
+// isParent reports whether a container ID or name is the rig container
+func (f *Filter) isParent(id string) bool {
+	if f.Parent == "" || f.Daemon == nil {
+		return false
+	}
+	container, err := f.Daemon.Lookup(KindContainer, id)
+	return err == nil && container != nil && container.Name == f.Parent
+}
+
+// owns reports whether a resource was created from within the parent
+func (f *Filter) owns(r *Resource) bool {
+	return r.Labels[ParentLabel] == f.Parent
+}
+
+// isNoNewPrivileges reports whether a security option only sets no-new-privileges
+func isNoNewPrivileges(opt string) bool {
+	switch opt {
+	case "no-new-privileges", "no-new-privileges:true", "no-new-privileges=true":
+		return true
+	}
+	return false
+}
+
+// isDefaultNetwork reports whether a network mode leaves the choice to the daemon
+func isDefaultNetwork(mode string) bool {
+	return mode == "" || mode == "default" || mode == "bridge"
+}
+
+// setField applies change to an object field of a JSON body (the top-level
+// object if field is empty), keeping all other fields
+func setField(body []byte, field string, change func(map[string]json.RawMessage) error) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, fmt.Errorf("parsing request: %w", err)
+		}
+	}
+	if field == "" {
+		if err := change(fields); err != nil {
+			return nil, err
+		}
+		return json.Marshal(fields)
+	}
+	inner := map[string]json.RawMessage{}
+	if raw, ok := fields[field]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &inner); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", field, err)
+		}
+	}
+	if err := change(inner); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(inner)
+	if err != nil {
+		return nil, err
+	}
+	fields[field] = raw
+	return json.Marshal(fields)
+}
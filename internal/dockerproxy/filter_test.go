@@ -0,0 +1,456 @@
+package dockerproxy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDaemon serves lookups from fixed resources, keyed by kind and ID or name
+type fakeDaemon struct {
+	resources map[string]*Resource
+	execs     map[string]string
+}
+
+func (d *fakeDaemon) Lookup(kind, id string) (*Resource, error) {
+	return d.resources[kind+"/"+id], nil
+}
+
+func (d *fakeDaemon) ExecContainer(id string) (string, error) {
+	return d.execs[id], nil
+}
+
+// testFilter returns a filter for the container rig-myproject with workspace
+// ws. It owns container abc123, network testnet, volume data and image
+// myimage:latest; its sidecar, the container other and pulled or rig's
+// images are not its own.
+func testFilter(ws, network string) *Filter {
+	owned := map[string]string{ParentLabel: "rig-myproject"}
+	return &Filter{
+		Workspace: ws,
+		Parent:    "rig-myproject",
+		Network:   network,
+		Daemon: &fakeDaemon{
+			resources: map[string]*Resource{
+				"containers/abc123":                     {Name: "postgres", Labels: owned},
+				"containers/f00d":                       {Name: "rig-myproject"},
+				"containers/rig-myproject-docker-proxy": {Name: "rig-myproject-docker-proxy"},
+				"containers/other":                      {Name: "other", Labels: map[string]string{ParentLabel: "rig-otherproject"}},
+				"networks/testnet":                      {Name: "testnet", Labels: owned},
+				"networks/bridge":                       {Name: "bridge"},
+				"volumes/data":                          {Name: "data", Labels: owned},
+				"volumes/secrets":                       {Name: "secrets"},
+				"images/myimage:latest":                 {Labels: owned},
+				"images/postgres:16":                    {},
+				"images/rig-otherproject:abc123":        {Labels: map[string]string{"rig.project": "otherproject"}},
+			},
+			execs: map[string]string{
+				"def456": "abc123",
+				"bad789": "rig-myproject-docker-proxy",
+			},
+		},
+	}
+}
+
+func TestFilterCheckEndpoints(t *testing.T) {
+	filter := testFilter("/home/user/myproject", "")
+
+	tests := []struct {
+		method  string
+		path    string
+		body    string
+		wantErr string
+	}{
+		{method: "GET", path: "/_ping"},
+		{method: "HEAD", path: "/v1.43/_ping"},
+		{method: "GET", path: "/v1.43/info"},
+		{method: "POST", path: "/v1.43/images/create?fromImage=postgres&tag=16"},
+		{method: "GET", path: "/v1.43/images/postgres:16/json"},
+		{method: "POST", path: "/v1.43/build?t=myimage"},
+		{method: "POST", path: "/v1.43/containers/abc123/start"},
+		{method: "POST", path: "/v1.43/containers/abc123/exec", body: `{"Cmd":["psql"]}`},
+		{method: "POST", path: "/v1.43/exec/def456/start"},
+		{method: "GET", path: "/v1.43/containers/abc123/logs?follow=1"},
+		{method: "PUT", path: "/v1.43/containers/abc123/archive?path=/tmp"},
+		{method: "DELETE", path: "/v1.43/containers/abc123"},
+		{method: "GET", path: "/v1.43/containers/f00d/json"},
+		{method: "POST", path: "/v1.43/networks/create"},
+		{method: "POST", path: "/v1.43/networks/testnet/connect", body: `{"Container":"abc123"}`},
+		{method: "DELETE", path: "/v1.43/networks/testnet"},
+		{method: "GET", path: "/v1.43/networks/bridge"},
+		{method: "DELETE", path: "/v1.43/volumes/data"},
+		{method: "POST", path: "/v1.43/build?t=myimage:latest&t=myimage:v2"},
+		{method: "POST", path: "/v1.43/images/myimage:latest/tag?repo=myimage&tag=v2"},
+		{method: "DELETE", path: "/v1.43/images/myimage:latest"},
+		{method: "GET", path: "/v1.43/images/rig-otherproject:abc123/json"},
+		{method: "POST", path: "/v1.43/containers/abc123/update", wantErr: "not allowed"},
+		{method: "POST", path: "/v1.43/plugins/pull", wantErr: "not allowed"},
+		{method: "POST", path: "/v1.43/swarm/init", wantErr: "not allowed"},
+		{method: "GET", path: "/v1.43/secrets", wantErr: "not allowed"},
+		{method: "POST", path: "/v1.43/containers/prune", wantErr: "not allowed"},
+		{method: "POST", path: "/v1.43/images/create?fromSrc=http://example.com/rootfs.tar", wantErr: "URL"},
+		{method: "POST", path: "/v1.43/build?networkmode=host", wantErr: "host network"},
+		// The rig container and its sidecars hold the host's Docker socket
+		{method: "POST", path: "/v1.43/containers/f00d/exec", body: `{"Cmd":["sh"]}`, wantErr: "not created from within"},
+		{method: "POST", path: "/v1.43/containers/rig-myproject-docker-proxy/exec", body: `{"Cmd":["sh"]}`, wantErr: "not created from within"},
+		{method: "PUT", path: "/v1.43/containers/rig-myproject-docker-proxy/archive?path=/", wantErr: "not created from within"},
+		{method: "POST", path: "/v1.43/exec/bad789/start", wantErr: "not created from within"},
+		{method: "GET", path: "/v1.43/containers/rig-myproject-docker-proxy/json", wantErr: "not created from within"},
+		{method: "POST", path: "/v1.43/containers/other/kill", wantErr: "not created from within"},
+		{method: "DELETE", path: "/v1.43/containers/other", wantErr: "not created from within"},
+		{method: "POST", path: "/v1.43/containers/missing/start", wantErr: "not created from within"},
+		{method: "POST", path: "/v1.43/containers/abc123/exec", body: `{"Cmd":["sh"],"Privileged":true}`, wantErr: "privileged exec"},
+		{method: "POST", path: "/v1.43/networks/testnet/connect", body: `{"Container":"f00d"}`, wantErr: "not created from within"},
+		{method: "DELETE", path: "/v1.43/networks/bridge", wantErr: "not created from within"},
+		{method: "DELETE", path: "/v1.43/volumes/secrets", wantErr: "not created from within"},
+		// Images rig runs can't be replaced, and others' images can't be touched
+		{method: "POST", path: "/v1.43/build?t=rig-otherproject:abc123", wantErr: "reserved for rig"},
+		{method: "POST", path: "/v1.43/build?t=myimage&t=rig-sidecar:abc123", wantErr: "reserved for rig"},
+		{method: "POST", path: "/v1.43/images/create?fromImage=rig-otherproject&tag=abc123", wantErr: "reserved for rig"},
+		{method: "POST", path: "/v1.43/images/create?fromImage=docker.io/library/rig-sidecar:abc123", wantErr: "reserved for rig"},
+		{method: "POST", path: "/v1.43/images/create?fromSrc=-&repo=rig-otherproject", wantErr: "reserved for rig"},
+		{method: "POST", path: "/v1.43/images/myimage:latest/tag?repo=rig-otherproject&tag=abc123", wantErr: "reserved for rig"},
+		{method: "POST", path: "/v1.43/images/postgres:16/tag?repo=mypostgres", wantErr: "not created from within"},
+		{method: "POST", path: "/v1.43/images/rig-otherproject:abc123/tag?repo=stolen", wantErr: "not created from within"},
+		{method: "DELETE", path: "/v1.43/images/postgres:16", wantErr: "not created from within"},
+		{method: "DELETE", path: "/v1.43/images/rig-otherproject:abc123", wantErr: "not created from within"},
+		{method: "DELETE", path: "/v1.43/images/missing", wantErr: "not created from within"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			err := filter.Check(tt.method, tt.path, []byte(tt.body))
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckImageName(t *testing.T) {
+	for _, ref := range []string{"postgres:16", "myorg/rig-tools", "registry.example.com/rig-x:1", "rigid:latest"} {
+		assert.NoError(t, checkImageName(ref), ref)
+	}
+	for _, ref := range []string{"rig-myproject", "rig-myproject:abc123", "rig-sidecar@sha256:0123", "library/rig-x", "docker.io/rig-x:1"} {
+		assert.Error(t, checkImageName(ref), ref)
+	}
+}
+
+func TestFilterCheckCreate(t *testing.T) {
+	ws := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(ws, "testdata"), 0755))
+	require.NoError(t, os.Symlink("/etc", filepath.Join(ws, "etc-link")))
+	filter := testFilter(ws, "")
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{
+			name: "plain container",
+			body: `{"Image":"postgres:16","HostConfig":{"NetworkMode":"bridge"}}`,
+		},
+		{
+			name: "named volume and workspace bind",
+			body: `{"HostConfig":{"Binds":["data:/var/lib/data","WS/testdata:/data:ro"]}}`,
+		},
+		{
+			name: "workspace bind via mounts",
+			body: `{"HostConfig":{"Mounts":[{"Type":"bind","Source":"WS"},{"Type":"volume","Source":"cache"},{"Type":"tmpfs"}]}}`,
+		},
+		{
+			name: "not yet existing path in workspace",
+			body: `{"HostConfig":{"Binds":["WS/testdata/out:/out"]}}`,
+		},
+		{
+			name: "volumes from own container",
+			body: `{"HostConfig":{"VolumesFrom":["abc123:ro"]}}`,
+		},
+		{
+			name: "no-new-privileges",
+			body: `{"HostConfig":{"SecurityOpt":["no-new-privileges"]}}`,
+		},
+		{
+			name: "own network",
+			body: `{"HostConfig":{"NetworkMode":"testnet"},"NetworkingConfig":{"EndpointsConfig":{"testnet":{}}}}`,
+		},
+		{
+			name:    "privileged",
+			body:    `{"HostConfig":{"Privileged":true}}`,
+			wantErr: "privileged",
+		},
+		{
+			name:    "added capabilities",
+			body:    `{"HostConfig":{"CapAdd":["SYS_ADMIN"]}}`,
+			wantErr: "capabilities",
+		},
+		{
+			name:    "host network",
+			body:    `{"HostConfig":{"NetworkMode":"host"}}`,
+			wantErr: "host network",
+		},
+		{
+			name:    "host pid",
+			body:    `{"HostConfig":{"PidMode":"host"}}`,
+			wantErr: "host PID",
+		},
+		{
+			name:    "host cgroup namespace",
+			body:    `{"HostConfig":{"CgroupnsMode":"host"}}`,
+			wantErr: "cgroup",
+		},
+		{
+			name:    "network of the rig container",
+			body:    `{"HostConfig":{"NetworkMode":"container:rig-myproject"}}`,
+			wantErr: "sharing namespaces",
+		},
+		{
+			name:    "pid namespace of the docker proxy",
+			body:    `{"HostConfig":{"PidMode":"container:rig-myproject-docker-proxy"}}`,
+			wantErr: "sharing namespaces",
+		},
+		{
+			name:    "ipc namespace of another container",
+			body:    `{"HostConfig":{"IpcMode":"container:abc123"}}`,
+			wantErr: "sharing namespaces",
+		},
+		{
+			name:    "unconfined seccomp",
+			body:    `{"HostConfig":{"SecurityOpt":["seccomp=unconfined"]}}`,
+			wantErr: "security option",
+		},
+		{
+			name:    "unconfined apparmor",
+			body:    `{"HostConfig":{"SecurityOpt":["apparmor:unconfined"]}}`,
+			wantErr: "security option",
+		},
+		{
+			name:    "devices",
+			body:    `{"HostConfig":{"Devices":[{"PathOnHost":"/dev/sda"}]}}`,
+			wantErr: "device",
+		},
+		{
+			name:    "device requests",
+			body:    `{"HostConfig":{"DeviceRequests":[{"Driver":"nvidia","Count":-1}]}}`,
+			wantErr: "device",
+		},
+		{
+			name:    "device cgroup rules",
+			body:    `{"HostConfig":{"DeviceCgroupRules":["b 8:* rmw"]}}`,
+			wantErr: "device",
+		},
+		{
+			name:    "sysctls",
+			body:    `{"HostConfig":{"Sysctls":{"kernel.core_pattern":"|/tmp/x"}}}`,
+			wantErr: "sysctls",
+		},
+		{
+			name:    "volumes from the docker proxy",
+			body:    `{"HostConfig":{"VolumesFrom":["rig-myproject-docker-proxy"]}}`,
+			wantErr: "not created from within",
+		},
+		{
+			name:    "volumes from an unrelated container",
+			body:    `{"HostConfig":{"VolumesFrom":["other:rw"]}}`,
+			wantErr: "not created from within",
+		},
+		{
+			name:    "existing volume of another project",
+			body:    `{"HostConfig":{"Binds":["secrets:/secrets"]}}`,
+			wantErr: "not created from within",
+		},
+		{
+			name:    "volume mount with bind options",
+			body:    `{"HostConfig":{"Mounts":[{"Type":"volume","Source":"new","VolumeOptions":{"DriverConfig":{"Options":{"type":"none","o":"bind","device":"/"}}}}]}}`,
+			wantErr: "volume option",
+		},
+		{
+			name:    "bind outside workspace",
+			body:    `{"HostConfig":{"Binds":["/etc:/host-etc"]}}`,
+			wantErr: "outside the workspace",
+		},
+		{
+			name:    "docker socket bind",
+			body:    `{"HostConfig":{"Binds":["/var/run/docker.sock:/var/run/docker.sock"]}}`,
+			wantErr: "outside the workspace",
+		},
+		{
+			name:    "path traversal out of workspace",
+			body:    `{"HostConfig":{"Binds":["WS/../other:/data"]}}`,
+			wantErr: "outside the workspace",
+		},
+		{
+			name:    "sibling directory with workspace prefix",
+			body:    `{"HostConfig":{"Binds":["WS-secrets:/data"]}}`,
+			wantErr: "outside the workspace",
+		},
+		{
+			name:    "symlink out of workspace",
+			body:    `{"HostConfig":{"Binds":["WS/etc-link:/data"]}}`,
+			wantErr: "outside the workspace",
+		},
+		{
+			name:    "path below symlink out of workspace",
+			body:    `{"HostConfig":{"Mounts":[{"Type":"bind","Source":"WS/etc-link/ssl"}]}}`,
+			wantErr: "outside the workspace",
+		},
+		{
+			name:    "bind mount outside workspace via mounts",
+			body:    `{"HostConfig":{"Mounts":[{"Type":"bind","Source":"/"}]}}`,
+			wantErr: "outside the workspace",
+		},
+		{
+			name:    "npipe mount",
+			body:    `{"HostConfig":{"Mounts":[{"Type":"npipe","Source":"/x"}]}}`,
+			wantErr: "not allowed",
+		},
+		{
+			name:    "invalid json",
+			body:    `{`,
+			wantErr: "invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.ReplaceAll(tt.body, "WS", ws)
+			err := filter.Check("POST", "/v1.43/containers/create", []byte(body))
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFilterCheckVolumeCreate(t *testing.T) {
+	filter := testFilter("/home/user/myproject", "")
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{name: "plain volume", body: `{"Name":"cache"}`},
+		{name: "local driver", body: `{"Name":"cache","Driver":"local"}`},
+		{name: "tmpfs", body: `{"Driver":"local","DriverOpts":{"type":"tmpfs","device":"tmpfs","o":"size=100m"}}`},
+		{name: "bind to host root", body: `{"DriverOpts":{"type":"none","o":"bind","device":"/"}}`, wantErr: "volume option"},
+		{name: "bind disguised as tmpfs", body: `{"DriverOpts":{"type":"tmpfs","o":"bind","device":"/"}}`, wantErr: "volume option"},
+		{name: "nfs", body: `{"DriverOpts":{"type":"nfs","o":"addr=10.0.0.1","device":":/export"}}`, wantErr: "volume option"},
+		{name: "other driver", body: `{"Driver":"sshfs"}`, wantErr: "driver"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := filter.Check("POST", "/v1.43/volumes/create", []byte(tt.body))
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFilterRestrictedNetwork(t *testing.T) {
+	filter := testFilter("/home/user/myproject", "rig-myproject-egress")
+
+	tests := []struct {
+		method  string
+		path    string
+		body    string
+		wantErr string
+	}{
+		{method: "POST", path: "/v1.43/containers/create", body: `{"HostConfig":{"NetworkMode":"bridge"}}`},
+		{method: "POST", path: "/v1.43/containers/create", body: `{"HostConfig":{"NetworkMode":"rig-myproject-egress"}}`},
+		{method: "POST", path: "/v1.43/containers/create", body: `{"HostConfig":{"NetworkMode":"testnet"}}`},
+		{method: "POST", path: "/v1.43/networks/testnet/connect", body: `{"Container":"abc123"}`},
+		{method: "POST", path: "/v1.43/build?networkmode=none"},
+		{method: "POST", path: "/v1.43/containers/create", body: `{"HostConfig":{"NetworkMode":"my-host-network"}}`, wantErr: "restricted egress"},
+		{method: "POST", path: "/v1.43/containers/create", body: `{"NetworkingConfig":{"EndpointsConfig":{"other":{}}}}`, wantErr: "restricted egress"},
+		{method: "POST", path: "/v1.43/networks/bridge/connect", body: `{"Container":"abc123"}`, wantErr: "restricted egress"},
+		{method: "POST", path: "/v1.43/networks/testnet/connect", body: `{"Container":"rig-myproject"}`, wantErr: "not created from within"},
+		{method: "POST", path: "/v1.43/build", wantErr: "networkmode=none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.body, func(t *testing.T) {
+			err := filter.Check(tt.method, tt.path, []byte(tt.body))
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFilterRewrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		path    string
+		body    string
+		want    string
+	}{
+		{
+			name:    "default network replaced",
+			network: "rig-myproject-egress",
+			path:    "/v1.43/containers/create",
+			body:    `{"Image":"redis","HostConfig":{"NetworkMode":"bridge"}}`,
+			want:    `{"Image":"redis","HostConfig":{"NetworkMode":"rig-myproject-egress"},"NetworkingConfig":{}}`,
+		},
+		{
+			name:    "no host config",
+			network: "rig-myproject-egress",
+			path:    "/v1.43/containers/create",
+			body:    `{"Image":"redis"}`,
+			want:    `{"Image":"redis","HostConfig":{"NetworkMode":"rig-myproject-egress"},"NetworkingConfig":{}}`,
+		},
+		{
+			name:    "default endpoint replaced",
+			network: "rig-myproject-egress",
+			path:    "/v1.43/containers/create",
+			body:    `{"HostConfig":{"NetworkMode":"testnet"},"NetworkingConfig":{"EndpointsConfig":{"testnet":{},"bridge":{"Aliases":["db"]}}}}`,
+			want:    `{"HostConfig":{"NetworkMode":"testnet"},"NetworkingConfig":{"EndpointsConfig":{"testnet":{},"rig-myproject-egress":{"Aliases":["db"]}}}}`,
+		},
+		{
+			name:    "networks made internal",
+			network: "rig-myproject-egress",
+			path:    "/v1.43/networks/create",
+			body:    `{"Name":"testnet","Internal":false}`,
+			want:    `{"Name":"testnet","Internal":true}`,
+		},
+		{
+			name: "unrestricted",
+			path: "/v1.43/containers/create",
+			body: `{"Image":"redis"}`,
+			want: `{"Image":"redis"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := testFilter("/home/user/myproject", tt.network)
+			got, err := filter.Rewrite("POST", tt.path, []byte(tt.body))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
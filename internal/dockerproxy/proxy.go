@@ -0,0 +1,321 @@
+package dockerproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SocketDir is where the proxy socket is shared with the dev container
+	SocketDir = "/var/run/rig-docker"
+
+	// SocketPath is the proxy's Docker API socket inside both containers
+	SocketPath = SocketDir + "/docker.sock"
+
+	// UpstreamSocket is the host Docker socket mounted into the proxy container
+	UpstreamSocket = "/var/run/docker.sock"
+
+	// WorkspaceEnv is the environment variable holding the host workspace path
+	WorkspaceEnv = "RIG_DOCKER_PROXY_WORKSPACE"
+
+	// ParentEnv is the environment variable holding the dev container's name
+	ParentEnv = "RIG_DOCKER_PROXY_PARENT"
+
+	// NetworkEnv is the environment variable holding the network containers
+	// are kept on, if any
+	NetworkEnv = "RIG_DOCKER_PROXY_NETWORK"
+
+	// ParentLabel marks containers, networks and volumes created from within
+	// a rig container, so they can be cleaned up with it
+	ParentLabel = "rig.parent"
+
+	// ReservedLabelPrefix starts the labels rig finds its resources by.
+	// Clients can't set them, so children can't pass as rig's own resources.
+	ReservedLabelPrefix = "rig."
+
+	// ActionDeny marks log entries for denied requests
+	ActionDeny = "deny"
+
+	// maxCreateBody limits the size of request bodies read for inspection
+	maxCreateBody = 1 << 20
+)
+
+// LogEntry is a JSON line written by the proxy for each denied request
+type LogEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Reason string    `json:"reason"`
+}
+
+// Proxy forwards allowed Docker API requests to the daemon's socket
+type Proxy struct {
+	filter  *Filter
+	labels  map[string]string
+	reverse *httputil.ReverseProxy
+
+	logMu sync.Mutex
+	log   io.Writer
+}
+
+// NewProxy creates a proxy forwarding to the Docker socket at upstream,
+// adding labels to created resources and writing denial entries to log
+func NewProxy(upstream string, filter *Filter, labels map[string]string, log io.Writer) *Proxy {
+	reverse := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.Out.URL.Scheme = "http"
+			r.Out.URL.Host = "docker"
+			r.Out.Host = "docker"
+		},
+		Transport: upstreamTransport(upstream),
+		// Stream logs, events and build output as they arrive
+		FlushInterval: -1,
+	}
+
+	return &Proxy{filter: filter, labels: labels, reverse: reverse, log: log}
+}
+
+// ServeHTTP checks each request against the filter before forwarding it
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	if r.Method == http.MethodPost && r.Body != nil {
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxCreateBody+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(data) > maxCreateBody && (isCreate(r.URL.Path) || isLabeledCreate(r.URL.Path)) {
+			p.deny(w, r, "request body too large to inspect")
+			return
+		}
+		// Hand the already-read body plus any remainder to the daemon
+		body = data
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), r.Body))
+	}
+
+	if err := p.filter.Check(r.Method, r.URL.RequestURI(), body); err != nil {
+		p.deny(w, r, err.Error())
+		return
+	}
+
+	rewritten, err := p.filter.Rewrite(r.Method, r.URL.Path, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(p.labels) > 0 && isLabeledCreate(r.URL.Path) {
+		if rewritten, err = addLabels(rewritten, p.labels); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	// Built images take their labels from the query
+	if len(p.labels) > 0 && isBuild(r.URL.Path) {
+		if r.URL.RawQuery, err = addQueryLabels(r.URL.Query(), p.labels); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	// Lists and events only show the resources created through the proxy
+	if len(p.labels) > 0 && isList(r.Method, r.URL.Path) {
+		if r.URL.RawQuery, err = addQueryFilters(r.URL.Query(), p.labels); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if !bytes.Equal(rewritten, body) {
+		r.Body = io.NopCloser(bytes.NewReader(rewritten))
+		r.ContentLength = int64(len(rewritten))
+		r.Header.Set("Content-Length", strconv.Itoa(len(rewritten)))
+	}
+
+	p.reverse.ServeHTTP(w, r)
+}
+
+// deny rejects a request with a Docker-style error and records it in the log
+func (p *Proxy) deny(w http.ResponseWriter, r *http.Request, reason string) {
+	entry := LogEntry{
+		Time:   time.Now().UTC(),
+		Action: ActionDeny,
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Reason: reason,
+	}
+	if data, err := json.Marshal(entry); err == nil {
+		p.logMu.Lock()
+		_, _ = p.log.Write(append(data, '\n'))
+		p.logMu.Unlock()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": "rig docker_access proxy: " + reason})
+}
+
+// Listen creates the proxy's unix socket, accessible to any user in the dev container
+func Listen(socketPath string) (net.Listener, error) {
+	_ = os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socketPath, 0666); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// isCreate reports whether a request path is a container create call
+func isCreate(urlPath string) bool {
+	return versionPrefix.ReplaceAllString(urlPath, "") == "/containers/create"
+}
+
+// isLabeledCreate reports whether a request path creates a resource that
+// takes labels: a container, network or volume
+func isLabeledCreate(urlPath string) bool {
+	switch versionPrefix.ReplaceAllString(urlPath, "") {
+	case "/containers/create", "/networks/create", "/volumes/create":
+		return true
+	}
+	return false
+}
+
+// isBuild reports whether a request path is an image build
+func isBuild(urlPath string) bool {
+	return versionPrefix.ReplaceAllString(urlPath, "") == "/build"
+}
+
+// isList reports whether a request lists containers, networks or volumes, or
+// streams events. Images are listed unfiltered: pulled images carry no labels.
+func isList(method, urlPath string) bool {
+	if method != http.MethodGet {
+		return false
+	}
+	switch versionPrefix.ReplaceAllString(urlPath, "") {
+	case "/containers/json", "/networks", "/volumes", "/events":
+		return true
+	}
+	return false
+}
+
+// addQueryFilters adds a label filter for each of labels to the JSON filters
+// parameter of a list query, keeping the client's own filters, and returns
+// the encoded query
+func addQueryFilters(query url.Values, labels map[string]string) (string, error) {
+	filters := map[string]json.RawMessage{}
+	if raw := query.Get("filters"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &filters); err != nil {
+			return "", fmt.Errorf("parsing filters: %w", err)
+		}
+		if filters == nil {
+			filters = map[string]json.RawMessage{}
+		}
+	}
+
+	// Filter values are lists, or maps to true in older API versions
+	var values []string
+	if raw, ok := filters["label"]; ok {
+		if err := json.Unmarshal(raw, &values); err != nil {
+			set := map[string]bool{}
+			if err := json.Unmarshal(raw, &set); err != nil {
+				return "", fmt.Errorf("parsing label filter: %w", err)
+			}
+			for v, ok := range set {
+				if ok {
+					values = append(values, v)
+				}
+			}
+		}
+	}
+	for k, v := range labels {
+		values = append(values, k+"="+v)
+	}
+	sort.Strings(values)
+
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	filters["label"] = raw
+	encoded, err := json.Marshal(filters)
+	if err != nil {
+		return "", err
+	}
+	query.Set("filters", string(encoded))
+	return query.Encode(), nil
+}
+
+// addQueryLabels sets labels in the JSON labels parameter of a build query,
+// keeping the client's own labels except reserved ones, and returns the
+// encoded query
+func addQueryLabels(query url.Values, labels map[string]string) (string, error) {
+	existing := map[string]string{}
+	if raw := query.Get("labels"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &existing); err != nil {
+			return "", fmt.Errorf("parsing labels: %w", err)
+		}
+		if existing == nil {
+			existing = map[string]string{}
+		}
+	}
+	mergeLabels(existing, labels)
+	raw, err := json.Marshal(existing)
+	if err != nil {
+		return "", err
+	}
+	query.Set("labels", string(raw))
+	return query.Encode(), nil
+}
+
+// addLabels sets labels in a create request body, keeping all other fields
+// and the client's own labels except reserved ones
+func addLabels(body []byte, labels map[string]string) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, fmt.Errorf("parsing create request: %w", err)
+		}
+	}
+	existing := map[string]string{}
+	if raw, ok := fields["Labels"]; ok {
+		if err := json.Unmarshal(raw, &existing); err != nil {
+			return nil, fmt.Errorf("parsing labels: %w", err)
+		}
+		if existing == nil {
+			existing = map[string]string{}
+		}
+	}
+	mergeLabels(existing, labels)
+	raw, err := json.Marshal(existing)
+	if err != nil {
+		return nil, err
+	}
+	fields["Labels"] = raw
+	return json.Marshal(fields)
+}
+
+// mergeLabels drops the client's reserved labels from existing and sets labels
+func mergeLabels(existing, labels map[string]string) {
+	for k := range existing {
+		if strings.HasPrefix(k, ReservedLabelPrefix) {
+			delete(existing, k)
+		}
+	}
+	for k, v := range labels {
+		existing[k] = v
+	}
+}
@@ -0,0 +1,33 @@
+// Command proxy is the filtering Docker socket proxy run in the rig-managed
+// sidecar container. It is built into the sidecar image from rig's embedded sources.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/wfaler/rig/internal/dockerproxy"
+)
+
+func main() {
+	filter := &dockerproxy.Filter{
+		Workspace: os.Getenv(dockerproxy.WorkspaceEnv),
+		Parent:    os.Getenv(dockerproxy.ParentEnv),
+		Network:   os.Getenv(dockerproxy.NetworkEnv),
+		Daemon:    dockerproxy.NewDaemon(dockerproxy.UpstreamSocket),
+	}
+	var labels map[string]string
+	if parent := filter.Parent; parent != "" {
+		labels = map[string]string{dockerproxy.ParentLabel: parent}
+	}
+	proxy := dockerproxy.NewProxy(dockerproxy.UpstreamSocket, filter, labels, os.Stdout)
+
+	listener, err := dockerproxy.Listen(dockerproxy.SocketPath)
+	if err != nil {
+		log.Fatalf("listening on %s: %v", dockerproxy.SocketPath, err)
+	}
+	if err := http.Serve(listener, proxy); err != nil {
+		log.Fatalf("serving docker proxy: %v", err)
+	}
+}
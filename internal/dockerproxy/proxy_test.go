@@ -0,0 +1,300 @@
+package dockerproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes from proxy handlers
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// serveUnix serves handler on a new unix socket in dir and returns its path
+func serveUnix(t *testing.T, dir, name string, handler http.Handler) string {
+	t.Helper()
+	socketPath := filepath.Join(dir, name)
+	listener, err := Listen(socketPath)
+	require.NoError(t, err)
+	server := &http.Server{Handler: handler}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return socketPath
+}
+
+// unixClient returns an HTTP client talking to a unix socket
+func unixClient(socketPath string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}}
+}
+
+func TestProxy(t *testing.T) {
+	// Unix socket paths are length-limited, so keep the directory short
+	dir, err := shortTempDir(t)
+	require.NoError(t, err)
+
+	// Stand-in Docker daemon recording the requests that reach it, other
+	// than the proxy's lookups. It has no volumes, and the container abc123
+	// belongs to another project.
+	var mu sync.Mutex
+	var received []string
+	daemon := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/volumes/"):
+			http.NotFound(w, r)
+			return
+		case r.Method == http.MethodGet && r.URL.Path == "/containers/abc123/json":
+			_, _ = io.WriteString(w, `{"Name":"/other","Config":{"Labels":{"rig.parent":"rig-otherproject"}}}`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r.Method+" "+r.URL.Path+" "+string(body))
+		mu.Unlock()
+		_, _ = io.WriteString(w, `{"Id":"abc123"}`)
+	})
+	upstream := serveUnix(t, dir, "daemon.sock", daemon)
+
+	log := &syncBuffer{}
+	filter := &Filter{
+		Workspace: "/home/user/myproject",
+		Parent:    "rig-myproject",
+		Network:   "rig-myproject-egress",
+		Daemon:    NewDaemon(upstream),
+	}
+	proxy := NewProxy(upstream, filter, nil, log)
+	client := unixClient(serveUnix(t, dir, "proxy.sock", proxy))
+
+	// Allowed create is forwarded onto the egress network
+	createBody := `{"Image":"postgres:16","HostConfig":{"Binds":["data:/data"]}}`
+	resp, err := client.Post("http://docker/v1.43/containers/create", "application/json", strings.NewReader(createBody))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	forwarded := `{"Image":"postgres:16","HostConfig":{"Binds":["data:/data"],"NetworkMode":"rig-myproject-egress"},"NetworkingConfig":{}}`
+
+	// Privileged create is denied before reaching the daemon
+	resp, err = client.Post("http://docker/v1.43/containers/create", "application/json",
+		strings.NewReader(`{"HostConfig":{"Privileged":true}}`))
+	require.NoError(t, err)
+	var denial map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&denial))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, denial["message"], "privileged")
+
+	// Unlisted endpoints are denied
+	resp, err = client.Post("http://docker/v1.43/swarm/init", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Other projects' containers are looked up and denied
+	resp, err = client.Post("http://docker/v1.43/containers/abc123/kill", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	mu.Lock()
+	if assert.Len(t, received, 1) {
+		method, body, _ := strings.Cut(received[0], " {")
+		assert.Equal(t, "POST /v1.43/containers/create", method)
+		assert.JSONEq(t, forwarded, "{"+body)
+	}
+	mu.Unlock()
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	require.Len(t, lines, 3)
+	var entry LogEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, ActionDeny, entry.Action)
+	assert.Equal(t, "/v1.43/containers/create", entry.Path)
+	assert.Contains(t, entry.Reason, "privileged")
+}
+
+// shortTempDir creates a temporary directory with a short path for unix sockets
+func shortTempDir(t *testing.T) (string, error) {
+	dir, err := os.MkdirTemp("", "rdp")
+	if err != nil {
+		return "", err
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir, nil
+}
+
+func TestProxyLabelsCreatedResources(t *testing.T) {
+	dir, err := shortTempDir(t)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	bodies := map[string]string{}
+	queries := map[string]url.Values{}
+	daemon := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.URL.Path] = string(body)
+		queries[r.URL.Path] = r.URL.Query()
+		mu.Unlock()
+		_, _ = io.WriteString(w, `{}`)
+	})
+	upstream := serveUnix(t, dir, "daemon.sock", daemon)
+
+	labels := map[string]string{ParentLabel: "rig-myproject"}
+	proxy := NewProxy(upstream, &Filter{Workspace: "/home/user/myproject"}, labels, &syncBuffer{})
+	client := unixClient(serveUnix(t, dir, "proxy.sock", proxy))
+
+	for _, path := range []string{"/v1.43/containers/create", "/v1.43/networks/create", "/v1.43/volumes/create", "/v1.43/images/create"} {
+		resp, err := client.Post("http://docker"+path, "application/json", strings.NewReader(`{"Name":"x"}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+	// Built images are labeled through the query
+	resp, err := client.Post(`http://docker/v1.43/build?t=myimage&labels={"app":"db"}`, "application/x-tar", strings.NewReader("tar"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	// Lists are filtered to the labeled resources
+	for _, path := range []string{"/v1.43/containers/json?all=1", "/v1.43/networks", "/v1.43/volumes", "/v1.43/images/json"} {
+		resp, err := client.Get("http://docker" + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "tar", bodies["/v1.43/build"])
+	assert.Equal(t, "myimage", queries["/v1.43/build"].Get("t"))
+	assert.JSONEq(t, `{"app":"db","rig.parent":"rig-myproject"}`, queries["/v1.43/build"].Get("labels"))
+	for _, path := range []string{"/v1.43/containers/create", "/v1.43/networks/create", "/v1.43/volumes/create"} {
+		assert.JSONEq(t, `{"Name":"x","Labels":{"rig.parent":"rig-myproject"}}`, bodies[path], path)
+	}
+	// Image pulls take no labels and are forwarded untouched
+	assert.Equal(t, `{"Name":"x"}`, bodies["/v1.43/images/create"])
+
+	assert.Equal(t, "1", queries["/v1.43/containers/json"].Get("all"))
+	for _, path := range []string{"/v1.43/containers/json", "/v1.43/networks", "/v1.43/volumes"} {
+		assert.JSONEq(t, `{"label":["rig.parent=rig-myproject"]}`, queries[path].Get("filters"), path)
+	}
+	assert.Empty(t, queries["/v1.43/images/json"].Get("filters"))
+}
+
+func TestAddLabels(t *testing.T) {
+	labels := map[string]string{ParentLabel: "rig-myproject"}
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"empty body", ``, `{"Labels":{"rig.parent":"rig-myproject"}}`},
+		{"no labels", `{"Image":"redis"}`, `{"Image":"redis","Labels":{"rig.parent":"rig-myproject"}}`},
+		{"null labels", `{"Labels":null}`, `{"Labels":{"rig.parent":"rig-myproject"}}`},
+		{"existing labels kept", `{"Labels":{"app":"db"}}`, `{"Labels":{"app":"db","rig.parent":"rig-myproject"}}`},
+		{"client cannot override", `{"Labels":{"rig.parent":"other"}}`, `{"Labels":{"rig.parent":"rig-myproject"}}`},
+		{"reserved labels dropped", `{"Labels":{"app":"db","rig.project":"other","rig.role":"workspace"}}`, `{"Labels":{"app":"db","rig.parent":"rig-myproject"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := addLabels([]byte(tt.body), labels)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+
+	_, err := addLabels([]byte(`not json`), labels)
+	assert.Error(t, err)
+}
+
+func TestAddQueryFilters(t *testing.T) {
+	labels := map[string]string{ParentLabel: "rig-myproject"}
+	tests := []struct {
+		name    string
+		filters string
+		want    string
+	}{
+		{"no filters", ``, `{"label":["rig.parent=rig-myproject"]}`},
+		{"other filters kept", `{"status":["running"]}`, `{"status":["running"],"label":["rig.parent=rig-myproject"]}`},
+		{"label list", `{"label":["app=db"]}`, `{"label":["app=db","rig.parent=rig-myproject"]}`},
+		{"label map", `{"label":{"app=db":true}}`, `{"label":["app=db","rig.parent=rig-myproject"]}`},
+		{"client parent kept alongside", `{"label":["rig.parent=other"]}`, `{"label":["rig.parent=other","rig.parent=rig-myproject"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"all": {"1"}}
+			if tt.filters != "" {
+				query.Set("filters", tt.filters)
+			}
+			got, err := addQueryFilters(query, labels)
+			require.NoError(t, err)
+			parsed, err := url.ParseQuery(got)
+			require.NoError(t, err)
+			assert.Equal(t, "1", parsed.Get("all"))
+			assert.JSONEq(t, tt.want, parsed.Get("filters"))
+		})
+	}
+
+	_, err := addQueryFilters(url.Values{"filters": {"not json"}}, labels)
+	assert.Error(t, err)
+}
+
+func TestAddQueryLabels(t *testing.T) {
+	labels := map[string]string{ParentLabel: "rig-myproject"}
+	tests := []struct {
+		name   string
+		labels string
+		want   string
+	}{
+		{"no labels", ``, `{"rig.parent":"rig-myproject"}`},
+		{"existing labels kept", `{"app":"db"}`, `{"app":"db","rig.parent":"rig-myproject"}`},
+		{"client cannot override", `{"rig.parent":"other"}`, `{"rig.parent":"rig-myproject"}`},
+		{"reserved labels dropped", `{"app":"db","rig.project":"other"}`, `{"app":"db","rig.parent":"rig-myproject"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"t": {"myimage"}}
+			if tt.labels != "" {
+				query.Set("labels", tt.labels)
+			}
+			got, err := addQueryLabels(query, labels)
+			require.NoError(t, err)
+			parsed, err := url.ParseQuery(got)
+			require.NoError(t, err)
+			assert.Equal(t, "myimage", parsed.Get("t"))
+			assert.JSONEq(t, tt.want, parsed.Get("labels"))
+		})
+	}
+
+	_, err := addQueryLabels(url.Values{"labels": {"not json"}}, labels)
+	assert.Error(t, err)
+}
@@ -0,0 +1,8 @@
+package dockerproxy
+
+import "embed"
+
+// Sources holds the proxy's Go sources, compiled into the sidecar image
+//
+//go:embed daemon.go filter.go proxy.go sources.go proxy/main.go
+var Sources embed.FS
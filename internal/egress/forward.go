@@ -0,0 +1,99 @@
+package egress
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ForwardHostEnv is the environment variable naming the container inbound ports are relayed to
+	ForwardHostEnv = "RIG_EGRESS_FORWARD_HOST"
+
+	// ForwardPortsEnv is the environment variable holding the comma-separated ports to relay
+	ForwardPortsEnv = "RIG_EGRESS_FORWARD_PORTS"
+)
+
+// Forward accepts TCP connections on listener and relays each to target.
+// The dev container sits on an internal network where Docker can't publish
+// ports, so the proxy publishes them and relays inbound traffic.
+func Forward(listener net.Listener, target string) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go relay(conn, target)
+	}
+}
+
+// relay copies data between a client connection and a new connection to target
+func relay(client net.Conn, target string) {
+	defer client.Close()
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, client)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// ParsePorts splits the comma-separated port list passed via ForwardPortsEnv
+func ParsePorts(value string) ([]int, error) {
+	var ports []int
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		port, err := parsePort(p)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// ParseForwardSpec parses a port forward given as "port" or
+// "host-port:container-port"
+func ParseForwardSpec(spec string) (int, int, error) {
+	hostPart, containerPart, found := strings.Cut(spec, ":")
+	if !found {
+		containerPart = hostPart
+	}
+
+	hostPort, err := parsePort(hostPart)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid host port: %w", err)
+	}
+	containerPort, err := parsePort(containerPart)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid container port: %w", err)
+	}
+	return hostPort, containerPort, nil
+}
+
+// parsePort parses a TCP port number, 1-65535
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("%d is out of range (1-65535)", port)
+	}
+	return port, nil
+}
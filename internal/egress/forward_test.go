@@ -0,0 +1,93 @@
+package egress
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForward(t *testing.T) {
+	// Stand-in for a service inside the dev container
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer upstream.Close()
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				_, _ = conn.Write([]byte("echo: " + line))
+			}()
+		}
+	}()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() { _ = Forward(listener, upstream.Addr().String()) }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "echo: hello\n", reply)
+}
+
+func TestParsePorts(t *testing.T) {
+	ports, err := ParsePorts("8080, 3000,")
+	require.NoError(t, err)
+	assert.Equal(t, []int{8080, 3000}, ports)
+
+	_, err = ParsePorts("http")
+	assert.Error(t, err)
+
+	_, err = ParsePorts("8080,70000")
+	assert.Error(t, err)
+}
+
+func TestParseForwardSpec(t *testing.T) {
+	tests := []struct {
+		spec          string
+		hostPort      int
+		containerPort int
+		wantErr       string
+	}{
+		{spec: "8080", hostPort: 8080, containerPort: 8080},
+		{spec: "9000:8080", hostPort: 9000, containerPort: 8080},
+		{spec: "1:65535", hostPort: 1, containerPort: 65535},
+		{spec: "http", wantErr: "invalid host port"},
+		{spec: "9000:", wantErr: "invalid container port"},
+		{spec: ":8080", wantErr: "invalid host port"},
+		{spec: "0", wantErr: "out of range"},
+		{spec: "-1:8080", wantErr: "out of range"},
+		{spec: "65536", wantErr: "out of range"},
+		{spec: "9000:70000", wantErr: "invalid container port: 70000 is out of range"},
+		{spec: "9000:8080:80", wantErr: "invalid container port"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			hostPort, containerPort, err := ParseForwardSpec(tt.spec)
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.hostPort, hostPort)
+			assert.Equal(t, tt.containerPort, containerPort)
+		})
+	}
+}
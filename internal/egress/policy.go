@@ -0,0 +1,106 @@
+package egress
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Policy decides which destinations the egress proxy may connect to.
+// Rules are domain names ("github.com"), wildcard domains ("*.npmjs.org",
+// matching any subdomain), IP addresses or CIDR ranges ("10.0.0.0/8").
+// CIDR rules only apply to destinations given as IP addresses.
+type Policy struct {
+	domains   map[string]bool
+	wildcards []string
+	networks  []*net.IPNet
+}
+
+// ParsePolicy builds a policy from a list of rules. An empty list denies everything.
+func ParsePolicy(rules []string) (*Policy, error) {
+	p := &Policy{domains: make(map[string]bool)}
+
+	for _, rule := range rules {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if err := ValidateRule(rule); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case strings.Contains(rule, "/"):
+			_, ipNet, _ := net.ParseCIDR(rule)
+			p.networks = append(p.networks, ipNet)
+		case net.ParseIP(rule) != nil:
+			ip := net.ParseIP(rule)
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			p.networks = append(p.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		case strings.HasPrefix(rule, "*."):
+			p.wildcards = append(p.wildcards, rule[1:]) // keep the leading dot
+		default:
+			p.domains[rule] = true
+		}
+	}
+
+	return p, nil
+}
+
+// ValidateRule checks that a rule is a domain, wildcard domain, IP address or CIDR
+func ValidateRule(rule string) error {
+	if rule == "" {
+		return fmt.Errorf("empty egress rule")
+	}
+	if strings.Contains(rule, "/") {
+		if _, _, err := net.ParseCIDR(rule); err != nil {
+			return fmt.Errorf("invalid CIDR %q", rule)
+		}
+		return nil
+	}
+	if net.ParseIP(rule) != nil {
+		return nil
+	}
+
+	domain := strings.TrimPrefix(rule, "*.")
+	if domain == "" || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return fmt.Errorf("invalid domain %q", rule)
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf("invalid domain %q", rule)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return fmt.Errorf("invalid domain %q", rule)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether the policy permits connecting to host
+// (a domain name or IP address, without port)
+func (p *Policy) Allows(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	if ip := net.ParseIP(host); ip != nil {
+		for _, n := range p.networks {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if p.domains[host] {
+		return true
+	}
+	for _, suffix := range p.wildcards {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
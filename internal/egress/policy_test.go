@@ -0,0 +1,89 @@
+package egress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyAllows(t *testing.T) {
+	policy, err := ParsePolicy([]string{
+		"github.com",
+		"*.npmjs.org",
+		"10.0.0.0/8",
+		"192.168.1.5",
+		"::1",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "github.com", want: true},
+		{host: "GitHub.com.", want: true},
+		{host: "api.github.com", want: false},
+		{host: "registry.npmjs.org", want: true},
+		{host: "a.b.npmjs.org", want: true},
+		{host: "npmjs.org", want: false},
+		{host: "evilnpmjs.org", want: false},
+		{host: "10.1.2.3", want: true},
+		{host: "11.1.2.3", want: false},
+		{host: "192.168.1.5", want: true},
+		{host: "192.168.1.6", want: false},
+		{host: "[::1]", want: true},
+		{host: "example.com", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.Allows(tt.host))
+		})
+	}
+}
+
+func TestEmptyPolicyDeniesEverything(t *testing.T) {
+	policy, err := ParsePolicy(nil)
+	require.NoError(t, err)
+
+	assert.False(t, policy.Allows("github.com"))
+	assert.False(t, policy.Allows("127.0.0.1"))
+}
+
+func TestValidateRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		wantErr bool
+	}{
+		{rule: "github.com"},
+		{rule: "*.github.com"},
+		{rule: "localhost"},
+		{rule: "10.0.0.0/8"},
+		{rule: "fd00::/8"},
+		{rule: "127.0.0.1"},
+		{rule: "", wantErr: true},
+		{rule: "*.", wantErr: true},
+		{rule: "github..com", wantErr: true},
+		{rule: "-github.com", wantErr: true},
+		{rule: "git_hub.com", wantErr: true},
+		{rule: "https://github.com", wantErr: true},
+		{rule: "10.0.0.0/33", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			err := ValidateRule(tt.rule)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseAllowList(t *testing.T) {
+	assert.Equal(t, []string{"github.com", "*.npmjs.org"}, ParseAllowList(" github.com, ,*.npmjs.org,"))
+	assert.Empty(t, ParseAllowList(""))
+}
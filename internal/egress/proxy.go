@@ -0,0 +1,195 @@
+package egress
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPort is the port the egress proxy listens on
+	DefaultPort = 3128
+
+	// AllowEnv is the environment variable holding the comma-separated allow-list
+	AllowEnv = "RIG_EGRESS_ALLOW"
+
+	// ActionDeny marks log entries for denied requests
+	ActionDeny = "deny"
+)
+
+// LogEntry is a JSON line written by the proxy for each denied request
+type LogEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Method string    `json:"method"`
+	Host   string    `json:"host"`
+	Client string    `json:"client"`
+}
+
+// Proxy is an HTTP forward proxy that only lets requests through to
+// destinations allowed by its policy. HTTPS is supported via CONNECT tunnels.
+type Proxy struct {
+	policy    *Policy
+	transport http.RoundTripper
+	dialer    net.Dialer
+
+	logMu sync.Mutex
+	log   io.Writer
+}
+
+// NewProxy creates a proxy enforcing policy, writing denial entries to log
+func NewProxy(policy *Policy, log io.Writer) *Proxy {
+	return &Proxy{
+		policy: policy,
+		transport: &http.Transport{
+			Proxy:               nil, // never chain to another proxy
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		dialer: net.Dialer{Timeout: 30 * time.Second},
+		log:    log,
+	}
+}
+
+// ServeHTTP handles CONNECT tunnels and plain HTTP proxy requests
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if r.Method != http.MethodConnect && r.URL.Host != "" {
+		host = r.URL.Host
+	}
+
+	if !p.policy.Allows(hostOnly(host)) {
+		p.deny(w, r, host)
+		return
+	}
+
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r, host)
+		return
+	}
+	p.forward(w, r)
+}
+
+// deny rejects a request and records it in the log
+func (p *Proxy) deny(w http.ResponseWriter, r *http.Request, host string) {
+	entry := LogEntry{
+		Time:   time.Now().UTC(),
+		Action: ActionDeny,
+		Method: r.Method,
+		Host:   host,
+		Client: r.RemoteAddr,
+	}
+	if data, err := json.Marshal(entry); err == nil {
+		p.logMu.Lock()
+		_, _ = p.log.Write(append(data, '\n'))
+		p.logMu.Unlock()
+	}
+	http.Error(w, "rig: egress to "+host+" is not allowed by network.egress policy", http.StatusForbidden)
+}
+
+// tunnel connects the client to the destination for CONNECT requests
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request, host string) {
+	if !strings.Contains(host, ":") {
+		host = net.JoinHostPort(host, "443")
+	}
+
+	upstream, err := p.dialer.DialContext(r.Context(), "tcp", host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	client, buf, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		// Forward anything the client sent after the CONNECT request
+		_, _ = io.Copy(upstream, buf)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// forward relays a plain HTTP request to its destination
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Scheme == "" || r.URL.Host == "" {
+		http.Error(w, "rig: not a proxy request", http.StatusBadRequest)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	for key, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// hopHeaders are removed when forwarding, as they only apply to a single connection
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// hostOnly strips the port from a host:port string
+func hostOnly(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return hostport
+}
+
+// ParseAllowList splits the comma-separated allow-list passed via AllowEnv
+func ParseAllowList(value string) []string {
+	var rules []string
+	for _, rule := range strings.Split(value, ",") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
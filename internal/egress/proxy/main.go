@@ -0,0 +1,43 @@
+// Command proxy is the egress filtering proxy run in the rig-managed proxy
+// container. It is built into its own image from rig's embedded sources.
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/wfaler/rig/internal/egress"
+)
+
+func main() {
+	policy, err := egress.ParsePolicy(egress.ParseAllowList(os.Getenv(egress.AllowEnv)))
+	if err != nil {
+		log.Fatalf("parsing egress policy: %v", err)
+	}
+
+	// Relay published ports to the dev container
+	ports, err := egress.ParsePorts(os.Getenv(egress.ForwardPortsEnv))
+	if err != nil {
+		log.Fatalf("parsing forwarded ports: %v", err)
+	}
+	target := os.Getenv(egress.ForwardHostEnv)
+	for _, port := range ports {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			log.Fatalf("listening on port %d: %v", port, err)
+		}
+		go func() {
+			log.Printf("forwarding port %d: %v", port, egress.Forward(listener, net.JoinHostPort(target, strconv.Itoa(port))))
+		}()
+	}
+
+	addr := fmt.Sprintf(":%d", egress.DefaultPort)
+	proxy := egress.NewProxy(policy, os.Stdout)
+	if err := http.ListenAndServe(addr, proxy); err != nil {
+		log.Fatalf("serving egress proxy: %v", err)
+	}
+}
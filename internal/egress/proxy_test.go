@@ -0,0 +1,134 @@
+package egress
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes from proxy handlers
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// newProxyClient starts a proxy with the given rules and returns an HTTP client using it
+func newProxyClient(t *testing.T, rules []string, upstream *httptest.Server) (*http.Client, *syncBuffer) {
+	t.Helper()
+
+	policy, err := ParsePolicy(rules)
+	require.NoError(t, err)
+
+	log := &syncBuffer{}
+	proxyServer := httptest.NewServer(NewProxy(policy, log))
+	t.Cleanup(proxyServer.Close)
+
+	proxyURL, err := url.Parse(proxyServer.URL)
+	require.NoError(t, err)
+
+	transport := &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+	if upstream.TLS != nil {
+		transport.TLSClientConfig = upstream.Client().Transport.(*http.Transport).TLSClientConfig
+	}
+	t.Cleanup(transport.CloseIdleConnections)
+
+	return &http.Client{Transport: transport}, log
+}
+
+func TestProxyForwardsAllowedHTTP(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", "yes")
+		_, _ = io.WriteString(w, "hello from upstream")
+	}))
+	defer upstream.Close()
+
+	client, log := newProxyClient(t, []string{"127.0.0.0/8"}, upstream)
+
+	resp, err := client.Get(upstream.URL + "/path")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "yes", resp.Header.Get("X-Upstream"))
+	assert.Equal(t, "hello from upstream", string(body))
+	assert.Empty(t, log.String())
+}
+
+func TestProxyDeniesHTTP(t *testing.T) {
+	var hits int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer upstream.Close()
+
+	client, log := newProxyClient(t, []string{"github.com"}, upstream)
+
+	resp, err := client.Get(upstream.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Zero(t, hits, "denied request must not reach upstream")
+
+	var entry LogEntry
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(log.String())), &entry))
+	assert.Equal(t, ActionDeny, entry.Action)
+	assert.Equal(t, http.MethodGet, entry.Method)
+	assert.Equal(t, strings.TrimPrefix(upstream.URL, "http://"), entry.Host)
+}
+
+func TestProxyTunnelsAllowedHTTPS(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "secure hello")
+	}))
+	defer upstream.Close()
+
+	client, log := newProxyClient(t, []string{"127.0.0.1"}, upstream)
+
+	resp, err := client.Get(upstream.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "secure hello", string(body))
+	assert.Empty(t, log.String())
+}
+
+func TestProxyDeniesHTTPS(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+
+	client, log := newProxyClient(t, nil, upstream)
+
+	_, err := client.Get(upstream.URL)
+	require.Error(t, err)
+
+	var entry LogEntry
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(log.String())), &entry))
+	assert.Equal(t, http.MethodConnect, entry.Method)
+	assert.Equal(t, strings.TrimPrefix(upstream.URL, "https://"), entry.Host)
+}
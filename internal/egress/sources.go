@@ -0,0 +1,8 @@
+package egress
+
+import "embed"
+
+// Sources holds the proxy's Go sources, compiled into the sidecar image
+//
+//go:embed policy.go proxy.go forward.go sources.go proxy/main.go
+var Sources embed.FS
@@ -0,0 +1,206 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// CheckpointRefPrefix is the hidden ref namespace holding checkpoints
+	CheckpointRefPrefix = "refs/rig/checkpoints/"
+
+	// CheckpointIDFormat is the time layout used for checkpoint IDs
+	CheckpointIDFormat = "20060102-150405"
+)
+
+// checkpointExclude keeps rig's own state (run transcripts, worktrees) out of checkpoints
+const checkpointExclude = ":(exclude).rig"
+
+// Checkpoint is a snapshot of a working tree stored as a commit on a hidden ref
+type Checkpoint struct {
+	ID      string
+	Commit  string
+	Created time.Time
+	Message string
+}
+
+// CreateCheckpoint records the working tree of dir, including untracked but
+// not ignored files, as a commit on a hidden ref. The current branch, index
+// and working tree are left untouched.
+func CreateCheckpoint(dir, message string, now time.Time) (*Checkpoint, error) {
+	env, cleanup, err := tempIndex()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// Start from HEAD so the snapshot only differs where the tree does
+	head, headErr := Run(dir, "rev-parse", "--verify", "--quiet", "HEAD")
+	if headErr == nil {
+		if _, err := RunEnv(dir, env, "read-tree", head); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := RunEnv(dir, env, "add", "--all", "--", ".", checkpointExclude); err != nil {
+		return nil, err
+	}
+	tree, err := RunEnv(dir, env, "write-tree")
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"commit-tree", tree, "-m", message}
+	if headErr == nil {
+		args = append(args, "-p", head)
+	}
+	commit, err := RunEnv(dir, checkpointAuthorEnv(now), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// IDs are timestamps, suffixed if several checkpoints share a second
+	base := now.Format(CheckpointIDFormat)
+	id := base
+	for n := 2; refExists(dir, CheckpointRefPrefix+id); n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	if _, err := Run(dir, "update-ref", CheckpointRefPrefix+id, commit); err != nil {
+		return nil, err
+	}
+
+	return &Checkpoint{ID: id, Commit: commit, Created: now, Message: message}, nil
+}
+
+// ListCheckpoints returns the checkpoints of the repository containing dir, newest first
+func ListCheckpoints(dir string) ([]Checkpoint, error) {
+	out, err := Run(dir, "for-each-ref",
+		"--format=%(refname)%00%(objectname)%00%(committerdate:unix)%00%(subject)", CheckpointRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := parseCheckpointList(out)
+	sortCheckpoints(checkpoints)
+	return checkpoints, nil
+}
+
+// sortCheckpoints orders checkpoints newest first. Those created within the
+// same second are ordered by their ID suffix, numerically, so -10 comes
+// before -9.
+func sortCheckpoints(checkpoints []Checkpoint) {
+	sort.SliceStable(checkpoints, func(i, j int) bool {
+		a, b := checkpoints[i], checkpoints[j]
+		if !a.Created.Equal(b.Created) {
+			return a.Created.After(b.Created)
+		}
+		return checkpointSeq(a.ID) > checkpointSeq(b.ID)
+	})
+}
+
+// checkpointSeq returns the suffix of a checkpoint ID, 1 if it has none
+func checkpointSeq(id string) int {
+	if len(id) <= len(CheckpointIDFormat) || id[len(CheckpointIDFormat)] != '-' {
+		return 1
+	}
+	n, err := strconv.Atoi(id[len(CheckpointIDFormat)+1:])
+	if err != nil {
+		return 1
+	}
+	return n
+}
+
+// RestoreCheckpoint makes the working tree of dir match a checkpoint: files
+// are restored to their checkpointed content and files created since are
+// removed. Ignored files, the index and the current branch are untouched.
+// The current state is saved as a new checkpoint first, which is returned.
+func RestoreCheckpoint(dir, id string, now time.Time) (*Checkpoint, error) {
+	target, err := Run(dir, "rev-parse", "--verify", "--quiet", CheckpointRefPrefix+id+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint %s not found", id)
+	}
+
+	backup, err := CreateCheckpoint(dir, "rig checkpoint before restoring "+id, now)
+	if err != nil {
+		return nil, fmt.Errorf("saving current state: %w", err)
+	}
+
+	// Remove files that didn't exist at the checkpoint
+	added, err := Run(dir, "diff-tree", "-r", "-z", "--relative", "--name-only", "--no-renames", "--diff-filter=A", target, backup.Commit)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range strings.Split(added, "\x00") {
+		if path == "" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(path))); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("removing %s: %w", path, err)
+		}
+	}
+
+	// Write the checkpoint's files to the working tree only
+	if _, err := Run(dir, "restore", "--source="+target, "--worktree", "--", ".", checkpointExclude); err != nil {
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+// DeleteCheckpoint removes a checkpoint's ref
+func DeleteCheckpoint(dir, id string) error {
+	if !refExists(dir, CheckpointRefPrefix+id) {
+		return fmt.Errorf("checkpoint %s not found", id)
+	}
+	_, err := Run(dir, "update-ref", "-d", CheckpointRefPrefix+id)
+	return err
+}
+
+// parseCheckpointList parses NUL-separated for-each-ref output lines
+func parseCheckpointList(out string) []Checkpoint {
+	var checkpoints []Checkpoint
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		unix, _ := strconv.ParseInt(fields[2], 10, 64)
+		checkpoints = append(checkpoints, Checkpoint{
+			ID:      strings.TrimPrefix(fields[0], CheckpointRefPrefix),
+			Commit:  fields[1],
+			Created: time.Unix(unix, 0),
+			Message: fields[3],
+		})
+	}
+	return checkpoints
+}
+
+// tempIndex returns environment pointing git at a fresh index file, so the
+// real index is never modified, and a function removing it
+func tempIndex() ([]string, func(), error) {
+	tmpDir, err := os.MkdirTemp("", "rig-index-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating temporary index: %w", err)
+	}
+	// git creates the index file itself; an empty file is not a valid index
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(tmpDir, "index")}
+	return env, func() { os.RemoveAll(tmpDir) }, nil
+}
+
+// checkpointAuthorEnv sets a fixed identity so checkpoints work without git user config
+func checkpointAuthorEnv(now time.Time) []string {
+	date := now.Format(time.RFC3339)
+	return []string{
+		"GIT_AUTHOR_NAME=rig", "GIT_AUTHOR_EMAIL=rig@localhost", "GIT_AUTHOR_DATE=" + date,
+		"GIT_COMMITTER_NAME=rig", "GIT_COMMITTER_EMAIL=rig@localhost", "GIT_COMMITTER_DATE=" + date,
+	}
+}
+
+// refExists checks if a ref exists
+func refExists(dir, ref string) bool {
+	_, err := Run(dir, "rev-parse", "--verify", "--quiet", ref)
+	return err == nil
+}
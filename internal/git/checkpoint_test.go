@@ -0,0 +1,163 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRepo creates a repository with one commit containing tracked.txt
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	run := func(args ...string) {
+		_, err := RunEnv(dir, checkpointAuthorEnv(time.Now()), args...)
+		require.NoError(t, err)
+	}
+	run("init", "--quiet", "--initial-branch=main")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tracked.txt"), []byte("original\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("ignored.txt\n"), 0644))
+	run("add", ".")
+	run("commit", "--quiet", "-m", "initial")
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	dir := newTestRepo(t)
+	head, err := Run(dir, "rev-parse", "HEAD")
+	require.NoError(t, err)
+
+	// Uncommitted and untracked work, plus a staged change that must survive
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tracked.txt"), []byte("work in progress\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "untracked.txt"), []byte("new file\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ignored.txt"), []byte("ignored\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".rig", "runs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".rig", "runs", "log"), []byte("log\n"), 0644))
+	indexBefore, err := Run(dir, "diff", "--cached", "--name-only")
+	require.NoError(t, err)
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	cp, err := CreateCheckpoint(dir, "rig checkpoint before rig up", created)
+	require.NoError(t, err)
+	assert.Equal(t, "20260102-030405", cp.ID)
+
+	// Branch, index and status are untouched
+	headAfter, err := Run(dir, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, head, headAfter)
+	indexAfter, err := Run(dir, "diff", "--cached", "--name-only")
+	require.NoError(t, err)
+	assert.Equal(t, indexBefore, indexAfter)
+
+	// The checkpoint has untracked files but not ignored files or rig state
+	files, err := Run(dir, "ls-tree", "-r", "--name-only", cp.Commit)
+	require.NoError(t, err)
+	assert.Equal(t, ".gitignore\ntracked.txt\nuntracked.txt", files)
+
+	// A second checkpoint in the same second gets a distinct ID
+	second, err := CreateCheckpoint(dir, "again", created)
+	require.NoError(t, err)
+	assert.Equal(t, "20260102-030405-2", second.ID)
+
+	checkpoints, err := ListCheckpoints(dir)
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 2)
+	assert.Equal(t, "20260102-030405-2", checkpoints[0].ID)
+	assert.Equal(t, "20260102-030405", checkpoints[1].ID)
+	assert.Equal(t, "rig checkpoint before rig up", checkpoints[1].Message)
+	assert.True(t, created.Equal(checkpoints[1].Created))
+
+	// The agent makes a mess
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tracked.txt"), []byte("broken\n"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(dir, "untracked.txt")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "junk.txt"), []byte("junk\n"), 0644))
+
+	backup, err := RestoreCheckpoint(dir, cp.ID, created.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "20260102-040405", backup.ID)
+
+	assert.Equal(t, "work in progress\n", readFile(t, filepath.Join(dir, "tracked.txt")))
+	assert.Equal(t, "new file\n", readFile(t, filepath.Join(dir, "untracked.txt")))
+	assert.Equal(t, "ignored\n", readFile(t, filepath.Join(dir, "ignored.txt")))
+	assert.NoFileExists(t, filepath.Join(dir, "junk.txt"))
+	assert.FileExists(t, filepath.Join(dir, ".rig", "runs", "log"))
+
+	headAfter, err = Run(dir, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, head, headAfter)
+	indexAfter, err = Run(dir, "diff", "--cached", "--name-only")
+	require.NoError(t, err)
+	assert.Equal(t, indexBefore, indexAfter)
+
+	// The backup holds the messy state
+	junk, err := Run(dir, "show", backup.Commit+":junk.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "junk", junk)
+
+	require.NoError(t, DeleteCheckpoint(dir, second.ID))
+	checkpoints, err = ListCheckpoints(dir)
+	require.NoError(t, err)
+	assert.Len(t, checkpoints, 2)
+	assert.Error(t, DeleteCheckpoint(dir, second.ID))
+
+	_, err = RestoreCheckpoint(dir, "missing", created)
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestListCheckpointsOrder(t *testing.T) {
+	dir := newTestRepo(t)
+
+	// Eleven checkpoints in one second, then one a second later
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 11; i++ {
+		_, err := CreateCheckpoint(dir, "same second", created)
+		require.NoError(t, err)
+	}
+	_, err := CreateCheckpoint(dir, "later", created.Add(time.Second))
+	require.NoError(t, err)
+
+	checkpoints, err := ListCheckpoints(dir)
+	require.NoError(t, err)
+	ids := make([]string, 0, len(checkpoints))
+	for _, cp := range checkpoints {
+		ids = append(ids, cp.ID)
+	}
+	assert.Equal(t, []string{
+		"20260102-030406",
+		"20260102-030405-11", "20260102-030405-10", "20260102-030405-9", "20260102-030405-8",
+		"20260102-030405-7", "20260102-030405-6", "20260102-030405-5", "20260102-030405-4",
+		"20260102-030405-3", "20260102-030405-2", "20260102-030405",
+	}, ids)
+}
+
+func TestCheckpointSeq(t *testing.T) {
+	assert.Equal(t, 1, checkpointSeq("20260102-030405"))
+	assert.Equal(t, 2, checkpointSeq("20260102-030405-2"))
+	assert.Equal(t, 10, checkpointSeq("20260102-030405-10"))
+	assert.Equal(t, 1, checkpointSeq("manual"))
+}
+
+func TestCheckpointWithoutCommits(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Run(dir, "init", "--quiet")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0644))
+
+	cp, err := CreateCheckpoint(dir, "first", time.Now())
+	require.NoError(t, err)
+
+	files, err := Run(dir, "ls-tree", "-r", "--name-only", cp.Commit)
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt", files)
+}
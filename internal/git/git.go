@@ -0,0 +1,135 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Worktree describes a git worktree as reported by `git worktree list`
+type Worktree struct {
+	Path   string // Absolute path of the worktree
+	Head   string // Commit checked out in the worktree
+	Branch string // Short branch name, empty when detached
+}
+
+// Run executes git with the given arguments in dir and returns trimmed stdout
+func Run(dir string, args ...string) (string, error) {
+	return RunEnv(dir, nil, args...)
+}
+
+// RunEnv is like Run with extra environment variables ("KEY=value") for git
+func RunEnv(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// CommonDir returns the absolute path of the repository's shared .git directory
+func CommonDir(dir string) (string, error) {
+	return Run(dir, "rev-parse", "--path-format=absolute", "--git-common-dir")
+}
+
+// BranchExists checks if a local branch exists
+func BranchExists(dir, branch string) bool {
+	_, err := Run(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// AddWorktree creates a worktree at path checking out branch, creating the
+// branch from HEAD if it doesn't exist yet
+func AddWorktree(dir, path, branch string) error {
+	args := []string{"worktree", "add"}
+	if BranchExists(dir, branch) {
+		args = append(args, path, branch)
+	} else {
+		args = append(args, "-b", branch, path)
+	}
+	_, err := Run(dir, args...)
+	return err
+}
+
+// RemoveWorktree removes the worktree at path
+func RemoveWorktree(dir, path string, force bool) error {
+	args := []string{"worktree", "remove"}
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, path)
+	_, err := Run(dir, args...)
+	return err
+}
+
+// ListWorktrees returns all worktrees of the repository containing dir
+func ListWorktrees(dir string) ([]Worktree, error) {
+	out, err := Run(dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	return parseWorktreeList(out), nil
+}
+
+// parseWorktreeList parses the output of `git worktree list --porcelain`
+func parseWorktreeList(out string) []Worktree {
+	var worktrees []Worktree
+	var current *Worktree
+
+	for _, line := range strings.Split(out, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "worktree":
+			worktrees = append(worktrees, Worktree{Path: value})
+			current = &worktrees[len(worktrees)-1]
+		case "HEAD":
+			if current != nil {
+				current.Head = value
+			}
+		case "branch":
+			if current != nil {
+				current.Branch = strings.TrimPrefix(value, "refs/heads/")
+			}
+		}
+	}
+	return worktrees
+}
+
+// DiffNoIndex returns a unified diff between two files outside any repository.
+// Either path may be /dev/null for added or deleted files.
+func DiffNoIndex(oldPath, newPath string) (string, error) {
+	cmd := exec.Command("git", "diff", "--no-index", "--no-prefix", "--", oldPath, newPath)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Exit status 1 without an error message means the files differ
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	differ := errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stderr.Len() == 0
+	if err != nil && !differ {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git diff --no-index: %s", msg)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,68 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorktreeList(t *testing.T) {
+	out := `worktree /home/user/myproject
+HEAD 1234567890abcdef1234567890abcdef12345678
+branch refs/heads/main
+
+worktree /home/user/myproject/.rig/worktrees/feature-login
+HEAD abcdef1234567890abcdef1234567890abcdef12
+branch refs/heads/feature/login
+
+worktree /home/user/myproject/.rig/worktrees/detached
+HEAD 0000000000000000000000000000000000000000
+detached
+`
+
+	want := []Worktree{
+		{
+			Path:   "/home/user/myproject",
+			Head:   "1234567890abcdef1234567890abcdef12345678",
+			Branch: "main",
+		},
+		{
+			Path:   "/home/user/myproject/.rig/worktrees/feature-login",
+			Head:   "abcdef1234567890abcdef1234567890abcdef12",
+			Branch: "feature/login",
+		},
+		{
+			Path: "/home/user/myproject/.rig/worktrees/detached",
+			Head: "0000000000000000000000000000000000000000",
+		},
+	}
+
+	assert.Equal(t, want, parseWorktreeList(out))
+}
+
+func TestParseWorktreeListEmpty(t *testing.T) {
+	assert.Empty(t, parseWorktreeList(""))
+}
+
+func TestDiffNoIndex(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.txt")
+	newPath := filepath.Join(dir, "new.txt")
+	require.NoError(t, os.WriteFile(oldPath, []byte("hello\n"), 0644))
+	require.NoError(t, os.WriteFile(newPath, []byte("hello world\n"), 0644))
+
+	diff, err := DiffNoIndex(oldPath, newPath)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "-hello\n")
+	assert.Contains(t, diff, "+hello world\n")
+
+	diff, err = DiffNoIndex(oldPath, oldPath)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	_, err = DiffNoIndex(oldPath, filepath.Join(dir, "missing.txt"))
+	assert.Error(t, err)
+}
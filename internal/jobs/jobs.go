@@ -0,0 +1,184 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dir is the container directory holding one subdirectory per job. It lives
+// under /tmp so jobs also work with a read-only root filesystem.
+const Dir = "/tmp/rig/jobs"
+
+// idLength is the number of hex characters in a job ID
+const idLength = 6
+
+// validID matches job IDs, which are interpolated into container paths
+var validID = regexp.MustCompile(fmt.Sprintf(`^[0-9a-f]{%d}$`, idLength))
+
+// Job describes a command started in the background
+type Job struct {
+	ID         string    `json:"id"`
+	Command    []string  `json:"command"`
+	WorkingDir string    `json:"working_dir"`
+	Started    time.Time `json:"started"`
+}
+
+// State is the state of a job
+type State string
+
+const (
+	Running State = "running"
+	Exited  State = "exited"
+	// Lost jobs stopped without recording an exit code, e.g. because the container stopped
+	Lost State = "lost"
+)
+
+// Status is a job and its current state
+type Status struct {
+	Job
+	State    State
+	ExitCode int // Valid when State is Exited
+}
+
+// NewID returns a random job ID
+func NewID() (string, error) {
+	b := make([]byte, idLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating job id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ValidID checks if a string is a well-formed job ID
+func ValidID(id string) bool {
+	return validID.MatchString(id)
+}
+
+// LogPath returns the container path of a job's output log
+func LogPath(id string) string {
+	return path.Join(Dir, id, "output.log")
+}
+
+// startScript records the job, runs the command with its output in the log
+// and saves the exit code. $$ stays alive while the command runs, so its pid
+// tells whether the job is still running.
+const startScript = `dir="$1/$2"
+mkdir -p "$dir" || exit 1
+printf '%s\n' "$3" > "$dir/job.json"
+shift 3
+echo $$ > "$dir/pid"
+"$@" > "$dir/output.log" 2>&1 < /dev/null
+echo $? > "$dir/exit_code"`
+
+// StartCommand returns the command that runs a job in the container
+func StartCommand(job Job) ([]string, error) {
+	if !ValidID(job.ID) {
+		return nil, fmt.Errorf("invalid job id: %s", job.ID)
+	}
+	meta, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("encoding job: %w", err)
+	}
+	return append([]string{"sh", "-c", startScript, "rig-job", Dir, job.ID, string(meta)}, job.Command...), nil
+}
+
+// listScript prints one line per job: ID, exit code (empty while running
+// or when lost), 1 if its process is alive, and its metadata
+const listScript = `cd "$1" 2>/dev/null || exit 0
+for d in */; do
+  [ -f "$d/job.json" ] || continue
+  id=${d%/}
+  alive=0
+  pid=$(cat "$id/pid" 2>/dev/null)
+  [ -n "$pid" ] && kill -0 "$pid" 2>/dev/null && alive=1
+  printf '%s\t%s\t%s\t%s\n' "$id" "$(cat "$id/exit_code" 2>/dev/null)" "$alive" "$(cat "$id/job.json")"
+done`
+
+// ListCommand returns the command that lists jobs in the container; parse
+// its output with ParseList
+func ListCommand() []string {
+	return []string{"sh", "-c", listScript, "rig-jobs", Dir}
+}
+
+// ParseList parses the output of ListCommand into job statuses, oldest first
+func ParseList(output string) ([]Status, error) {
+	var statuses []Status
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected job listing: %q", line)
+		}
+		var s Status
+		if err := json.Unmarshal([]byte(fields[3]), &s.Job); err != nil {
+			return nil, fmt.Errorf("parsing job %s: %w", fields[0], err)
+		}
+		s.ID = fields[0]
+		switch {
+		case fields[1] != "":
+			code, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("parsing exit code of job %s: %w", s.ID, err)
+			}
+			s.State, s.ExitCode = Exited, code
+		case fields[2] == "1":
+			s.State = Running
+		default:
+			s.State = Lost
+		}
+		statuses = append(statuses, s)
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Started.Before(statuses[j].Started)
+	})
+	return statuses, nil
+}
+
+// followScript prints a job's output as it is written until the job's process
+// exits. A job started just before may not have written its pid or log yet.
+const followScript = `i=0
+while [ ! -s "$1/pid" ] && [ $i -lt 50 ]; do sleep 0.1; i=$((i+1)); done
+exec tail -n +1 -F --pid="$(cat "$1/pid")" "$1/output.log" 2>/dev/null`
+
+// LogsCommand returns the command that prints a job's output. With follow,
+// it keeps printing new output until the job finishes.
+func LogsCommand(id string, follow bool) []string {
+	if !follow {
+		return []string{"cat", LogPath(id)}
+	}
+	return []string{"sh", "-c", followScript, "rig-logs", path.Join(Dir, id)}
+}
+
+// waitScript blocks while the job's process is alive, then prints its exit code
+const waitScript = `pid=$(cat "$1/pid") || exit 1
+while kill -0 "$pid" 2>/dev/null; do sleep 1; done
+cat "$1/exit_code" 2>/dev/null || true`
+
+// WaitCommand returns the command that waits for a job in the container;
+// parse its output with ParseExitCode
+func WaitCommand(id string) []string {
+	return []string{"sh", "-c", waitScript, "rig-wait", path.Join(Dir, id)}
+}
+
+// ParseExitCode parses the output of WaitCommand
+func ParseExitCode(output string) (int, error) {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return 0, fmt.Errorf("job stopped without an exit code (was the container stopped?)")
+	}
+	code, err := strconv.Atoi(output)
+	if err != nil {
+		return 0, fmt.Errorf("parsing exit code %q: %w", output, err)
+	}
+	return code, nil
+}
@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewID(t *testing.T) {
+	id, err := NewID()
+	require.NoError(t, err)
+	assert.True(t, ValidID(id), id)
+	assert.False(t, ValidID("../etc"))
+	assert.False(t, ValidID("ABCDEF"))
+}
+
+func TestStartCommand(t *testing.T) {
+	job := Job{ID: "a1b2c3", Command: []string{"go", "test", "./..."}, WorkingDir: "/workspace"}
+	cmd, err := StartCommand(job)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sh", "-c", startScript, "rig-job", Dir, "a1b2c3"}, cmd[:6])
+	assert.Equal(t, []string{"go", "test", "./..."}, cmd[7:])
+
+	var meta Job
+	require.NoError(t, json.Unmarshal([]byte(cmd[6]), &meta))
+	assert.Equal(t, job, meta)
+
+	_, err = StartCommand(Job{ID: "x; rm -rf /"})
+	assert.Error(t, err)
+}
+
+func TestParseList(t *testing.T) {
+	output := "b2\t\t1\t{\"id\":\"b2\",\"command\":[\"sleep\",\"60\"],\"started\":\"2026-01-02T10:00:00Z\"}\n" +
+		"a1\t3\t0\t{\"id\":\"a1\",\"command\":[\"make\"],\"started\":\"2026-01-02T09:00:00Z\"}\n" +
+		"c3\t\t0\t{\"id\":\"c3\",\"command\":[\"npm\",\"test\"],\"started\":\"2026-01-02T11:00:00Z\"}\n"
+
+	statuses, err := ParseList(output)
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.Equal(t, "a1", statuses[0].ID)
+	assert.Equal(t, Exited, statuses[0].State)
+	assert.Equal(t, 3, statuses[0].ExitCode)
+	assert.Equal(t, Running, statuses[1].State)
+	assert.Equal(t, []string{"sleep", "60"}, statuses[1].Command)
+	assert.Equal(t, Lost, statuses[2].State)
+
+	statuses, err = ParseList("")
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+
+	_, err = ParseList("a1\tgarbage")
+	assert.Error(t, err)
+}
+
+func TestParseExitCode(t *testing.T) {
+	code, err := ParseExitCode("42\n")
+	require.NoError(t, err)
+	assert.Equal(t, 42, code)
+
+	_, err = ParseExitCode("")
+	assert.ErrorContains(t, err, "without an exit code")
+}
+
+// TestScripts runs the job scripts with the local shell against a temporary
+// jobs directory
+func TestScripts(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	sh := func(script string, args ...string) string {
+		out, err := exec.Command("sh", append([]string{"-c", script, "test"}, args...)...).Output()
+		require.NoError(t, err)
+		return string(out)
+	}
+
+	meta, err := json.Marshal(Job{ID: "a1b2c3", Command: []string{"sh", "-c", "echo out; echo err >&2; exit 3"}, Started: time.Now().UTC()})
+	require.NoError(t, err)
+	sh(startScript, dir, "a1b2c3", string(meta), "sh", "-c", "echo out; echo err >&2; exit 3")
+
+	log, err := os.ReadFile(filepath.Join(dir, "a1b2c3", "output.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "out\nerr\n", string(log))
+
+	statuses, err := ParseList(sh(listScript, dir))
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, Exited, statuses[0].State)
+	assert.Equal(t, 3, statuses[0].ExitCode)
+
+	// Following a finished job prints its output and returns
+	assert.Equal(t, "out\nerr\n", sh(followScript, filepath.Join(dir, "a1b2c3")))
+
+	code, err := ParseExitCode(sh(waitScript, filepath.Join(dir, "a1b2c3")))
+	require.NoError(t, err)
+	assert.Equal(t, 3, code)
+
+	// An empty or missing jobs directory lists nothing
+	assert.Empty(t, sh(listScript, filepath.Join(dir, "missing")))
+}
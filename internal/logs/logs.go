@@ -0,0 +1,102 @@
+package logs
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// sinceLayouts are the absolute times accepted by ParseSince, besides RFC 3339.
+// They are read in the local time zone.
+var sinceLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02"}
+
+// ParseSince parses a --since value: a duration before now such as "10m" or
+// "2h30m", or a time such as "2026-10-18T15:04:05Z", "2026-10-18T15:04:05"
+// or "2026-10-18"
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("invalid since %q: duration must not be negative", value)
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range sinceLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q (use a duration like 10m or a time like 2006-01-02T15:04:05)", value)
+}
+
+// sinceWriter drops lines until one stamped at or after since is written
+type sinceWriter struct {
+	w       io.Writer
+	layout  string
+	since   time.Time
+	passing bool
+	partial []byte // Start of a line whose end wasn't written yet
+}
+
+// Since returns a writer that passes output to w from the first line stamped
+// at or after since on. Lines start with their time in layout, followed by a
+// space; unstamped lines are kept or dropped along with the line before them.
+func Since(w io.Writer, layout string, since time.Time) io.Writer {
+	return &sinceWriter{w: w, layout: layout, since: since}
+}
+
+func (s *sinceWriter) Write(p []byte) (int, error) {
+	if s.passing {
+		return s.w.Write(p)
+	}
+	data := append(s.partial, p...)
+	for {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			s.partial = append([]byte(nil), data...)
+			return len(p), nil
+		}
+		if s.stampedSince(data[:end]) {
+			s.passing, s.partial = true, nil
+			if _, err := s.w.Write(data); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}
+		data = data[end+1:]
+	}
+}
+
+// stampedSince reports whether a line is stamped at or after the cutoff
+func (s *sinceWriter) stampedSince(line []byte) bool {
+	stamp, _, found := bytes.Cut(line, []byte(" "))
+	if !found {
+		return false
+	}
+	t, err := time.Parse(s.layout, string(stamp))
+	return err == nil && !t.Before(s.since)
+}
+
+// CopyFile writes the content of the file in a tar archive of a single file,
+// as returned by the Docker API for a file path in a container, to w
+func CopyFile(w io.Writer, archive io.Reader) error {
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("archive holds no file")
+		}
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			_, err := io.Copy(w, tr)
+			return err
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package logs
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "10m", want: now.Add(-10 * time.Minute)},
+		{value: "2h30m", want: now.Add(-150 * time.Minute)},
+		{value: "2026-10-18T14:00:00Z", want: time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC)},
+		{value: "2026-10-18T14:00:00+02:00", want: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)},
+		{value: "2026-10-18T14:00:00", want: time.Date(2026, 10, 18, 14, 0, 0, 0, time.Local)},
+		{value: "2026-10-18T14:30", want: time.Date(2026, 10, 18, 14, 30, 0, 0, time.Local)},
+		{value: "2026-10-18", want: time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)},
+		{value: "-5m", wantErr: true},
+		{value: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSince(tt.value, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "got %v, want %v", got, tt.want)
+		})
+	}
+}
+
+func TestSince(t *testing.T) {
+	layout := "2006-01-02T15:04:05-0700"
+	since := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	w := Since(&buf, layout, since)
+
+	// Lines arrive split across writes
+	for _, chunk := range []string{
+		"2026-10-18T14:59:59+0000 old\n",
+		"unstamped old\n2026-10-18T15:",
+		"00:00+0000 new\nunstamped new\n",
+		"not a stamp at all\n",
+	} {
+		n, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+	assert.Equal(t, "2026-10-18T15:00:00+0000 new\nunstamped new\nnot a stamp at all\n", buf.String())
+}
+
+func TestCopyFile(t *testing.T) {
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	content := "line one\nline two\n"
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "output.log", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+	_, err := tw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	var out bytes.Buffer
+	require.NoError(t, CopyFile(&out, &archive))
+	assert.Equal(t, content, out.String())
+
+	var empty bytes.Buffer
+	require.NoError(t, tar.NewWriter(&empty).Close())
+	assert.Error(t, CopyFile(&out, &empty))
+}
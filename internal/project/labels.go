@@ -0,0 +1,74 @@
+package project
+
+// Labels set on Docker resources rig creates. Discovery uses these rather
+// than resource names, so unrelated rig-* containers are never touched.
+const (
+	// ProjectLabel records the project name
+	ProjectLabel = "rig.project"
+
+	// PathLabel records the project directory on the host
+	PathLabel = "rig.project.path"
+
+	// WorkdirLabel records the host directory mounted as the workspace,
+	// which differs from the project directory for worktrees
+	WorkdirLabel = "rig.workdir"
+
+	// ConfigHashLabel records the hash of the .rig.yml a resource was created for
+	ConfigHashLabel = "rig.config-hash"
+
+	// ConfigLabel records the .rig.yml an image was built from, so the fields
+	// changed since can be told
+	ConfigLabel = "rig.config"
+
+	// VersionLabel records the rig version that created a resource
+	VersionLabel = "rig.version"
+
+	// ProfileLabel records the security profile
+	ProfileLabel = "rig.profile"
+
+	// RoleLabel tells a project's containers apart
+	RoleLabel = "rig.role"
+)
+
+// Container roles
+const (
+	RoleWorkspace   = "workspace"    // The container users and agents work in
+	RoleEgressProxy = "egress-proxy" // Egress allow-list proxy sidecar
+	RoleDockerProxy = "docker-proxy" // Docker socket proxy sidecar
+)
+
+// Labels describes the project a Docker resource belongs to
+type Labels struct {
+	Project    string
+	ProjectDir string
+	WorkDir    string
+	ConfigHash string
+	Version    string
+	Profile    string
+	Config     string // .rig.yml content; set for images only
+}
+
+// Map returns the labels for a resource. Containers pass their role; images,
+// volumes and networks pass an empty role.
+func (l Labels) Map(role string) map[string]string {
+	m := map[string]string{
+		ProjectLabel:    l.Project,
+		PathLabel:       l.ProjectDir,
+		WorkdirLabel:    l.WorkDir,
+		ConfigHashLabel: l.ConfigHash,
+		VersionLabel:    l.Version,
+		ProfileLabel:    l.Profile,
+	}
+	if role != "" {
+		m[RoleLabel] = role
+	}
+	if l.Config != "" {
+		m[ConfigLabel] = l.Config
+	}
+	return m
+}
+
+// Filter returns a label filter ("key=value") matching a label's value
+func Filter(label, value string) string {
+	return label + "=" + value
+}
@@ -0,0 +1,63 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelsMap(t *testing.T) {
+	labels := Labels{
+		Project:    "api-1a2b3c4d",
+		ProjectDir: "/home/user/api",
+		WorkDir:    "/home/user/api/.rig/worktrees/feature",
+		ConfigHash: "abc123def456",
+		Version:    "1.2.0",
+		Profile:    "hardened",
+	}
+
+	tests := []struct {
+		name string
+		role string
+		want map[string]string
+	}{
+		{
+			name: "container",
+			role: RoleWorkspace,
+			want: map[string]string{
+				"rig.project":      "api-1a2b3c4d",
+				"rig.project.path": "/home/user/api",
+				"rig.workdir":      "/home/user/api/.rig/worktrees/feature",
+				"rig.config-hash":  "abc123def456",
+				"rig.version":      "1.2.0",
+				"rig.profile":      "hardened",
+				"rig.role":         "workspace",
+			},
+		},
+		{
+			name: "volume",
+			want: map[string]string{
+				"rig.project":      "api-1a2b3c4d",
+				"rig.project.path": "/home/user/api",
+				"rig.workdir":      "/home/user/api/.rig/worktrees/feature",
+				"rig.config-hash":  "abc123def456",
+				"rig.version":      "1.2.0",
+				"rig.profile":      "hardened",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, labels.Map(tt.role))
+		})
+	}
+}
+
+func TestLabelsMapConfig(t *testing.T) {
+	labels := Labels{Project: "api-1a2b3c4d", Config: "languages:\n  - go\n"}
+	assert.Equal(t, "languages:\n  - go\n", labels.Map("")[ConfigLabel])
+}
+
+func TestFilter(t *testing.T) {
+	assert.Equal(t, "rig.project=api-1a2b3c4d", Filter(ProjectLabel, "api-1a2b3c4d"))
+}
@@ -3,9 +3,12 @@ package project
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 )
 
 const (
@@ -14,32 +17,121 @@ const (
 
 	// HashLength is the number of characters to use from the SHA256 hash for image tags
 	HashLength = 12
+
+	// WorktreesDir is the directory (relative to the project) holding rig-managed git worktrees
+	WorktreesDir = ".rig/worktrees"
+
+	// PathHashLength is the number of hash characters identifying a project directory
+	PathHashLength = 8
+
+	// ImageVersion identifies the layout of the images rig generates. It is
+	// part of every config hash: bump it when the Dockerfile template or the
+	// scripts baked into images change, so existing images are rebuilt.
+	ImageVersion = "2"
 )
 
-// GetProjectName returns the directory name for use in image/container naming
-func GetProjectName(dir string) string {
+// invalidNameChars matches characters not allowed in Docker container names
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// pathHashPattern matches the path hash suffix of generated project names
+var pathHashPattern = regexp.MustCompile(fmt.Sprintf(`^[0-9a-f]{%d}$`, PathHashLength))
+
+// GetProjectName returns the name used for the project's images and containers:
+// the name configured in .rig.yml if set, otherwise the directory's base name
+// and a short hash of its absolute path, so that ~/work/api and ~/oss/api
+// don't share a container
+func GetProjectName(dir, configuredName string) string {
+	if configuredName != "" {
+		return configuredName
+	}
+	return SanitizeName(filepath.Base(dir)) + "-" + PathHash(dir)
+}
+
+// MatchesBaseName reports whether a project name is the given name plus a
+// path hash, e.g. "api-1a2b3c4d" for "api"
+func MatchesBaseName(projectName, name string) bool {
+	hash, ok := strings.CutPrefix(projectName, name+"-")
+	return ok && pathHashPattern.MatchString(hash)
+}
+
+// LegacyProjectName returns the name rig used before names included a path
+// hash, for detecting containers created by older versions
+func LegacyProjectName(dir string) string {
 	return filepath.Base(dir)
 }
 
+// PathHash returns a short hash of a directory's absolute path
+func PathHash(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return ComputeHash([]byte(filepath.Clean(dir)))[:PathHashLength]
+}
+
+// SanitizeName converts a directory name into a string valid in image and
+// container names, which must be lowercase
+func SanitizeName(name string) string {
+	name = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-._")
+	if name == "" {
+		return "project"
+	}
+	return name
+}
+
 // ConfigPath returns the full path to the config file in the given directory
 func ConfigPath(dir string) string {
 	return filepath.Join(dir, ConfigFileName)
 }
 
+// ErrNoConfig is returned by FindRoot when no config file is found
+var ErrNoConfig = errors.New("no " + ConfigFileName + " found")
+
+// FindRoot returns the nearest directory at or above dir containing a config
+// file. The search stops at the root of the enclosing git repository (a
+// directory containing .git) and at the filesystem root, so nested configs in
+// a monorepo are separate projects and unrelated parents are never picked up.
+func FindRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	start := dir
+	for {
+		if ConfigExists(dir) {
+			return dir, nil
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return "", fmt.Errorf("%w in %s or its parents (run 'rig init')", ErrNoConfig, start)
+}
+
 // ConfigExists checks if .assistant.yml exists in the directory
 func ConfigExists(dir string) bool {
 	_, err := os.Stat(ConfigPath(dir))
 	return err == nil
 }
 
-// ComputeConfigHash generates a truncated SHA256 hash of the config file content
+// ComputeConfigHash generates the config hash of a config file
 func ComputeConfigHash(configPath string) (string, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return "", fmt.Errorf("reading config for hash: %w", err)
 	}
 
-	return ComputeHash(data), nil
+	return ConfigHash(data), nil
+}
+
+// ConfigHash returns the hash identifying the image built from a .rig.yml
+// with the given content
+func ConfigHash(data []byte) string {
+	return ComputeHash(append([]byte("image-version: "+ImageVersion+"\n"), data...))
 }
 
 // ComputeHash generates a truncated SHA256 hash from bytes
@@ -64,7 +156,59 @@ func ContainerName(projectName string) string {
 	return fmt.Sprintf("rig-%s", projectName)
 }
 
+// SanitizeBranch converts a branch name into a string safe for container names and directories
+func SanitizeBranch(branch string) string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(branch, "-"), "-.")
+}
+
+// WorktreeName returns the name of a branch's worktree directory and
+// container. Branches that had to be sanitized get a short hash of the
+// branch appended, so feature/x and feature-x don't share a worktree.
+func WorktreeName(branch string) string {
+	name := SanitizeBranch(branch)
+	if name == branch {
+		return name
+	}
+	hash := sha256.Sum256([]byte(branch))
+	return name + "-" + hex.EncodeToString(hash[:])[:6]
+}
+
+// WorktreeContainerName returns the container name for a project worktree
+func WorktreeContainerName(projectName, branch string) string {
+	return fmt.Sprintf("rig-%s-wt-%s", projectName, WorktreeName(branch))
+}
+
+// WorktreeDir returns the directory of the rig-managed worktree for a branch
+func WorktreeDir(projectDir, branch string) string {
+	return filepath.Join(projectDir, WorktreesDir, WorktreeName(branch))
+}
+
+// EgressNetworkName returns the internal network a container joins when egress is restricted
+func EgressNetworkName(containerName string) string {
+	return containerName + "-egress"
+}
+
+// EgressProxyName returns the name of the egress proxy container serving a container
+func EgressProxyName(containerName string) string {
+	return containerName + "-egress-proxy"
+}
+
 // GetCurrentDirectory returns the current working directory
 func GetCurrentDirectory() (string, error) {
 	return os.Getwd()
 }
+
+// DockerProxyName returns the name of the Docker socket proxy container serving a container
+func DockerProxyName(containerName string) string {
+	return containerName + "-docker-proxy"
+}
+
+// DockerProxyVolumeName returns the volume sharing the Docker proxy socket with a container
+func DockerProxyVolumeName(containerName string) string {
+	return containerName + "-docker-sock"
+}
+
+// WorkspaceVolumeName returns the volume holding a container's isolated workspace copy
+func WorkspaceVolumeName(containerName string) string {
+	return containerName + "-workspace"
+}
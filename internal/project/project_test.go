@@ -11,35 +11,64 @@ import (
 
 func TestGetProjectName(t *testing.T) {
 	tests := []struct {
-		name string
-		dir  string
-		want string
+		name       string
+		dir        string
+		configured string
+		want       string
 	}{
 		{
 			name: "simple directory",
 			dir:  "/home/user/myproject",
-			want: "myproject",
+			want: "myproject-" + PathHash("/home/user/myproject"),
 		},
 		{
 			name: "nested directory",
 			dir:  "/home/user/work/projects/myapp",
-			want: "myapp",
+			want: "myapp-" + PathHash("/home/user/work/projects/myapp"),
+		},
+		{
+			name: "uppercase and spaces",
+			dir:  "/home/user/My App",
+			want: "my-app-" + PathHash("/home/user/My App"),
 		},
 		{
 			name: "root directory",
 			dir:  "/",
-			want: "/",
+			want: "project-" + PathHash("/"),
+		},
+		{
+			name:       "configured name",
+			dir:        "/home/user/myproject",
+			configured: "billing",
+			want:       "billing",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := GetProjectName(tt.dir)
+			got := GetProjectName(tt.dir, tt.configured)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
+func TestGetProjectNameDistinguishesPaths(t *testing.T) {
+	work := GetProjectName("/home/user/work/api", "")
+	oss := GetProjectName("/home/user/oss/api", "")
+	assert.NotEqual(t, work, oss)
+	assert.Regexp(t, `^api-[0-9a-f]{8}$`, work)
+	assert.Equal(t, "api", LegacyProjectName("/home/user/work/api"))
+}
+
+func TestPathHash(t *testing.T) {
+	assert.Len(t, PathHash("/home/user/api"), PathHashLength)
+	assert.Equal(t, PathHash("/home/user/api"), PathHash("/home/user/api/"))
+
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, PathHash(cwd), PathHash("."))
+}
+
 func TestConfigPath(t *testing.T) {
 	dir := "/home/user/myproject"
 	want := "/home/user/myproject/.rig.yml"
@@ -108,8 +137,17 @@ func TestComputeConfigHash(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, hash, HashLength)
 
-	// Verify it matches direct hash computation
-	assert.Equal(t, ComputeHash(content), hash)
+	// Verify it matches the hash of the content and the image version
+	assert.Equal(t, ConfigHash(content), hash)
+	assert.NotEqual(t, ComputeHash(content), hash)
+}
+
+func TestConfigHash(t *testing.T) {
+	content := []byte("languages:\n  node:\n    version: lts\n")
+	versioned := append([]byte("image-version: "+ImageVersion+"\n"), content...)
+
+	assert.Equal(t, ComputeHash(versioned), ConfigHash(content))
+	assert.NotEqual(t, ConfigHash(content), ConfigHash([]byte("languages: {}\n")))
 }
 
 func TestComputeConfigHash_FileNotFound(t *testing.T) {
@@ -155,3 +193,127 @@ func TestContainerName(t *testing.T) {
 	got := ContainerName("myproject")
 	assert.Equal(t, "rig-myproject", got)
 }
+
+func TestSanitizeBranch(t *testing.T) {
+	tests := []struct {
+		name   string
+		branch string
+		want   string
+	}{
+		{name: "simple branch", branch: "main", want: "main"},
+		{name: "branch with slash", branch: "feature/login", want: "feature-login"},
+		{name: "branch with invalid characters", branch: "fix/#42 bug", want: "fix-42-bug"},
+		{name: "leading and trailing separators", branch: "/wip/", want: "wip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SanitizeBranch(tt.branch))
+		})
+	}
+}
+
+func TestWorktreeName(t *testing.T) {
+	tests := []struct {
+		name   string
+		branch string
+		want   string
+	}{
+		{name: "simple branch", branch: "feature-login", want: "feature-login"},
+		{name: "dots kept", branch: "release-1.2", want: "release-1.2"},
+		{name: "branch with slash", branch: "feature/login", want: "feature-login-df7c7a"},
+		{name: "branch with invalid characters", branch: "fix/#42 bug", want: "fix-42-bug-40c1cd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, WorktreeName(tt.branch))
+		})
+	}
+}
+
+func TestWorktreeContainerName(t *testing.T) {
+	assert.Equal(t, "rig-myproject-wt-feature-login", WorktreeContainerName("myproject", "feature-login"))
+	assert.Equal(t, "rig-myproject-wt-feature-login-df7c7a", WorktreeContainerName("myproject", "feature/login"))
+}
+
+func TestWorktreeDir(t *testing.T) {
+	assert.Equal(t, "/home/user/myproject/.rig/worktrees/feature-login", WorktreeDir("/home/user/myproject", "feature-login"))
+	assert.Equal(t, "/home/user/myproject/.rig/worktrees/feature-login-df7c7a", WorktreeDir("/home/user/myproject", "feature/login"))
+}
+
+func TestEgressNames(t *testing.T) {
+	assert.Equal(t, "rig-myproject-egress", EgressNetworkName("rig-myproject"))
+	assert.Equal(t, "rig-myproject-egress-proxy", EgressProxyName("rig-myproject"))
+}
+
+func TestDockerProxyNames(t *testing.T) {
+	assert.Equal(t, "rig-myproject-docker-proxy", DockerProxyName("rig-myproject"))
+	assert.Equal(t, "rig-myproject-docker-sock", DockerProxyVolumeName("rig-myproject"))
+}
+
+func TestWorkspaceVolumeName(t *testing.T) {
+	assert.Equal(t, "rig-myproject-workspace", WorkspaceVolumeName("rig-myproject"))
+	assert.Equal(t, "rig-myproject-wt-feature-workspace", WorkspaceVolumeName("rig-myproject-wt-feature"))
+}
+
+func TestFindRoot(t *testing.T) {
+	// outer/.rig.yml is outside the repository and must not be found
+	outer := t.TempDir()
+	repo := filepath.Join(outer, "repo")
+	for _, dir := range []string{
+		"repo/.git",
+		"repo/services/api/internal",
+		"repo/services/web/src",
+		"repo/tools",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(outer, dir), 0755))
+	}
+	for _, dir := range []string{"", "repo", "repo/services/api"} {
+		require.NoError(t, os.WriteFile(filepath.Join(outer, dir, ConfigFileName), []byte("shell: bash\n"), 0644))
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{name: "config in dir", dir: "repo", want: "repo"},
+		{name: "nearest parent", dir: "repo/services/web/src", want: "repo"},
+		{name: "nested project", dir: "repo/services/api/internal", want: "repo/services/api"},
+		{name: "nested project root", dir: "repo/services/api", want: "repo/services/api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindRoot(filepath.Join(outer, tt.dir))
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(outer, tt.want), got)
+		})
+	}
+
+	// Without a config in the repository, the search stops at the git root
+	require.NoError(t, os.Remove(filepath.Join(repo, ConfigFileName)))
+	_, err := FindRoot(filepath.Join(repo, "tools"))
+	assert.ErrorIs(t, err, ErrNoConfig)
+}
+
+func TestMatchesBaseName(t *testing.T) {
+	tests := []struct {
+		projectName string
+		name        string
+		want        bool
+	}{
+		{"api-1a2b3c4d", "api", true},
+		{"my-api-1a2b3c4d", "my-api", true},
+		{"my-api-1a2b3c4d", "my", false},
+		{"api", "api", false},
+		{"api-1a2b3c4", "api", false},
+		{"api-1A2B3C4D", "api", false},
+		{"api-staging", "api", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.projectName+"/"+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchesBaseName(tt.projectName, tt.name))
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// Dir is the directory (relative to the project) where recordings are saved
+	Dir = ".rig/recordings"
+
+	// Extension is the file extension of asciicast recordings
+	Extension = ".cast"
+
+	// nameFormat is the timestamp layout that starts recording file names
+	nameFormat = "20060102-150405"
+)
+
+// Header is the first line of an asciicast v2 file
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Path returns the file for a recording of name started at the given time
+func Path(projectDir, name string, started time.Time) string {
+	return filepath.Join(projectDir, Dir, started.Format(nameFormat)+"-"+name+Extension)
+}
+
+// Recorder writes terminal output and resize events as an asciicast v2
+// recording. It is safe for concurrent use. Write never fails, so a recorder
+// can share an io.MultiWriter with the terminal; the first error is returned
+// by Close instead.
+type Recorder struct {
+	mu      sync.Mutex
+	w       io.Writer
+	closer  io.Closer
+	now     func() time.Time
+	start   time.Time
+	width   int
+	height  int
+	pending []byte // Incomplete UTF-8 sequence held back from the last write
+	err     error
+}
+
+// New starts a recording of a terminal of the given size on w
+func New(w io.Writer, width, height int, title string) (*Recorder, error) {
+	return newRecorder(w, Header{Width: width, Height: height, Title: title, Env: env()}, time.Now)
+}
+
+// Create starts a recording in a new file at path, creating its directory
+func Create(path string, width, height int, title string) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating recordings directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating recording: %w", err)
+	}
+	r, err := New(f, width, height, title)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+func newRecorder(w io.Writer, header Header, now func() time.Time) (*Recorder, error) {
+	start := now()
+	header.Version = 2
+	header.Timestamp = start.Unix()
+	line, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("encoding recording header: %w", err)
+	}
+	if _, err := w.Write(append(line, '\n')); err != nil {
+		return nil, fmt.Errorf("writing recording header: %w", err)
+	}
+	return &Recorder{w: w, now: now, start: start, width: header.Width, height: header.Height}, nil
+}
+
+// env returns the terminal settings players use to pick a font and colors
+func env() map[string]string {
+	e := map[string]string{}
+	for _, key := range []string{"SHELL", "TERM"} {
+		if v := os.Getenv(key); v != "" {
+			e[key] = v
+		}
+	}
+	return e
+}
+
+// Write records output. A multi-byte character split across writes is held
+// back until it is complete, since events must be valid UTF-8.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := append(r.pending, p...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	r.pending = append([]byte(nil), data[cut:]...)
+	if cut > 0 {
+		r.event("o", string(data[:cut]))
+	}
+	return len(p), nil
+}
+
+// Resize records a change of the terminal size
+func (r *Recorder) Resize(width, height int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if width == r.width && height == r.height {
+		return
+	}
+	r.width, r.height = width, height
+	r.event("r", fmt.Sprintf("%dx%d", width, height))
+}
+
+// Close flushes held back output and closes the file of a created recording
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending) > 0 {
+		r.event("o", string(r.pending))
+		r.pending = nil
+	}
+	if r.closer != nil {
+		if err := r.closer.Close(); err != nil && r.err == nil {
+			r.err = fmt.Errorf("closing recording: %w", err)
+		}
+	}
+	return r.err
+}
+
+// event writes an event line; the caller holds the lock
+func (r *Recorder) event(code, data string) {
+	if r.err != nil {
+		return
+	}
+	elapsed := math.Round(r.now().Sub(r.start).Seconds()*1e6) / 1e6
+	line, err := json.Marshal([]any{elapsed, code, data})
+	if err == nil {
+		_, err = r.w.Write(append(line, '\n'))
+	}
+	if err != nil {
+		r.err = fmt.Errorf("writing recording: %w", err)
+	}
+}
+
+// CRLF returns a writer that turns "\n" into "\r\n" before writing to w, for
+// recording output that didn't go through a TTY
+func CRLF(w io.Writer) io.Writer {
+	return crlfWriter{w}
+}
+
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (c crlfWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(c.w, strings.ReplaceAll(string(p), "\n", "\r\n")); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// PlayOptions control the playback of a recording
+type PlayOptions struct {
+	Speed   float64       // Playback speed factor (default: 1)
+	MaxIdle time.Duration // Pauses are shortened to this (0: kept as recorded)
+}
+
+// Play writes a recording's output to w with its original timing
+func Play(r io.Reader, w io.Writer, opts PlayOptions) error {
+	return play(r, w, opts, time.Sleep)
+}
+
+func play(r io.Reader, w io.Writer, opts PlayOptions, sleep func(time.Duration)) error {
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	br := bufio.NewReader(r)
+	line, err := br.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading recording: %w", err)
+	}
+	var header Header
+	if err := json.Unmarshal(line, &header); err != nil {
+		return fmt.Errorf("not an asciicast recording: %w", err)
+	}
+	if header.Version != 2 {
+		return fmt.Errorf("unsupported asciicast version %d (supported: 2)", header.Version)
+	}
+
+	var last float64
+	for n := 2; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var event []any
+			if err := json.Unmarshal(line, &event); err != nil || len(event) != 3 {
+				return fmt.Errorf("line %d: invalid event", n)
+			}
+			at, okTime := event[0].(float64)
+			code, okCode := event[1].(string)
+			data, okData := event[2].(string)
+			if !okTime || !okCode || !okData {
+				return fmt.Errorf("line %d: invalid event", n)
+			}
+			if code == "o" {
+				pause := time.Duration((at - last) * float64(time.Second))
+				if opts.MaxIdle > 0 && pause > opts.MaxIdle {
+					pause = opts.MaxIdle
+				}
+				if pause > 0 {
+					sleep(time.Duration(float64(pause) / speed))
+				}
+				last = at
+				if _, err := io.WriteString(w, data); err != nil {
+					return err
+				}
+			}
+			// Input, marker and resize events don't change the output
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading recording: %w", err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package recording
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock returns a fake time source that advances by the given steps
+func clock(start time.Time, steps ...time.Duration) func() time.Time {
+	now := start
+	return func() time.Time {
+		if len(steps) > 0 {
+			now = now.Add(steps[0])
+			steps = steps[1:]
+		}
+		return now
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	start := time.Unix(1700000000, 0)
+	rec, err := newRecorder(&buf, Header{Width: 80, Height: 24, Title: "rig up"},
+		clock(start, 0, 500*time.Millisecond, time.Second, 0, 250*time.Millisecond))
+	require.NoError(t, err)
+
+	_, _ = rec.Write([]byte("$ ls\r\n"))
+	rec.Resize(80, 24) // Unchanged: not recorded
+	rec.Resize(120, 40)
+	// "é" split across two writes is recorded once complete
+	_, _ = rec.Write([]byte("caf\xc3"))
+	_, _ = rec.Write([]byte("\xa9\r\n"))
+	require.NoError(t, rec.Close())
+
+	assert.Equal(t, `{"version":2,"width":80,"height":24,"timestamp":1700000000,"title":"rig up"}
+[0.5,"o","$ ls\r\n"]
+[1.5,"r","120x40"]
+[1.5,"o","caf"]
+[1.75,"o","é\r\n"]
+`, buf.String())
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	path := Path(dir, "session-1", time.Date(2026, 10, 18, 15, 4, 5, 0, time.UTC))
+	assert.Equal(t, filepath.Join(dir, ".rig", "recordings", "20261018-150405-session-1.cast"), path)
+
+	rec, err := Create(path, 100, 30, "")
+	require.NoError(t, err)
+	_, _ = rec.Write([]byte("hello"))
+	require.NoError(t, rec.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"width":100,"height":30`)
+	assert.Contains(t, lines[1], `"o","hello"]`)
+}
+
+func TestCRLF(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := CRLF(&buf).Write([]byte("a\nb\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, "a\r\nb\r\n", buf.String())
+}
+
+func TestPlay(t *testing.T) {
+	recording := `{"version":2,"width":80,"height":24}
+[0.5,"o","one "]
+[1.0,"r","100x30"]
+[3.0,"o","two "]
+[3.1,"i","x"]
+[13.0,"o","three"]
+`
+	tests := []struct {
+		name       string
+		opts       PlayOptions
+		wantPauses []time.Duration
+	}{
+		{name: "real time", wantPauses: []time.Duration{500 * time.Millisecond, 2500 * time.Millisecond, 10 * time.Second}},
+		{name: "double speed", opts: PlayOptions{Speed: 2}, wantPauses: []time.Duration{250 * time.Millisecond, 1250 * time.Millisecond, 5 * time.Second}},
+		{name: "idle limit", opts: PlayOptions{MaxIdle: 2 * time.Second}, wantPauses: []time.Duration{500 * time.Millisecond, 2 * time.Second, 2 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var pauses []time.Duration
+			err := play(strings.NewReader(recording), &out, tt.opts, func(d time.Duration) { pauses = append(pauses, d) })
+			require.NoError(t, err)
+			assert.Equal(t, "one two three", out.String())
+			assert.Equal(t, tt.wantPauses, pauses)
+		})
+	}
+}
+
+func TestPlayInvalid(t *testing.T) {
+	sleep := func(time.Duration) {}
+	err := play(strings.NewReader("not json\n"), &bytes.Buffer{}, PlayOptions{}, sleep)
+	assert.ErrorContains(t, err, "not an asciicast recording")
+
+	err = play(strings.NewReader(`{"version":1,"width":80,"height":24}`), &bytes.Buffer{}, PlayOptions{}, sleep)
+	assert.ErrorContains(t, err, "unsupported asciicast version 1")
+
+	err = play(strings.NewReader("{\"version\":2}\n[1.0,\"o\"]\n"), &bytes.Buffer{}, PlayOptions{}, sleep)
+	assert.ErrorContains(t, err, "line 2: invalid event")
+}
@@ -0,0 +1,160 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wfaler/rig/internal/project"
+)
+
+// FileName is the name of the registry file in rig's state directory
+const FileName = "projects.json"
+
+// Entry records where a project lives and when it was last used
+type Entry struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	LastUsed   time.Time `json:"last_used"`
+	ConfigHash string    `json:"config_hash,omitempty"` // Hash of the .rig.yml the image was built from
+}
+
+// Registry is the set of projects rig has been used with, keyed by directory
+type Registry struct {
+	path     string
+	Projects []Entry `json:"projects"`
+}
+
+// DefaultPath returns the registry location: $XDG_STATE_HOME/rig/projects.json,
+// or ~/.local/state/rig/projects.json
+func DefaultPath() (string, error) {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("locating home directory: %w", err)
+		}
+		stateDir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateDir, "rig", FileName), nil
+}
+
+// Load reads the registry at path. A missing file is an empty registry.
+func Load(path string) (*Registry, error) {
+	r := &Registry{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading project registry: %w", err)
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("parsing project registry %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Save writes the registry, replacing the file atomically so concurrent rig
+// invocations never see a partial write
+func (r *Registry) Save() error {
+	sort.Slice(r.Projects, func(i, j int) bool {
+		return r.Projects[i].Name < r.Projects[j].Name
+	})
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding project registry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), FileName+".*")
+	if err != nil {
+		return fmt.Errorf("writing project registry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing project registry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing project registry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("writing project registry: %w", err)
+	}
+	return nil
+}
+
+// Touch records a project as used now, replacing any entry for its directory.
+// An empty config hash keeps the previously recorded one.
+func (r *Registry) Touch(entry Entry, now time.Time) {
+	entry.LastUsed = now
+	for i, e := range r.Projects {
+		if e.Path == entry.Path {
+			if entry.ConfigHash == "" {
+				entry.ConfigHash = e.ConfigHash
+			}
+			r.Projects[i] = entry
+			return
+		}
+	}
+	r.Projects = append(r.Projects, entry)
+}
+
+// Prune removes and returns the entries whose directories no longer exist
+func (r *Registry) Prune() []Entry {
+	var kept, pruned []Entry
+	for _, e := range r.Projects {
+		if info, err := os.Stat(e.Path); err == nil && info.IsDir() {
+			kept = append(kept, e)
+		} else {
+			pruned = append(pruned, e)
+		}
+	}
+	r.Projects = kept
+	return pruned
+}
+
+// Find returns the project with the given name. Like 'rig down', a name may
+// be given without its path hash as long as only one such project exists.
+func (r *Registry) Find(name string) (Entry, error) {
+	var matches []Entry
+	for _, e := range r.Projects {
+		if e.Name == name {
+			return e, nil
+		}
+		if project.MatchesBaseName(e.Name, name) {
+			matches = append(matches, e)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Entry{}, fmt.Errorf("no project named %s (see 'rig projects')", name)
+	case 1:
+		return matches[0], nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s matches several projects; use the full name:", name)
+	for _, m := range matches {
+		fmt.Fprintf(&b, "\n  %s  (%s)", m.Name, m.Path)
+	}
+	return Entry{}, errors.New(b.String())
+}
+
+// Update loads the registry at path, prunes entries whose directories are
+// gone, applies fn and saves the result
+func Update(path string, fn func(r *Registry)) error {
+	r, err := Load(path)
+	if err != nil {
+		return err
+	}
+	r.Prune()
+	fn(r)
+	return r.Save()
+}
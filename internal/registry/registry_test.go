@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/state")
+	path, err := DefaultPath()
+	require.NoError(t, err)
+	assert.Equal(t, "/state/rig/projects.json", path)
+
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/dev")
+	path, err = DefaultPath()
+	require.NoError(t, err)
+	assert.Equal(t, "/home/dev/.local/state/rig/projects.json", path)
+}
+
+func TestLoadMissing(t *testing.T) {
+	r, err := Load(filepath.Join(t.TempDir(), FileName))
+	require.NoError(t, err)
+	assert.Empty(t, r.Projects)
+}
+
+func TestTouchAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rig", FileName)
+	first := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	later := first.Add(time.Hour)
+
+	r, err := Load(path)
+	require.NoError(t, err)
+	r.Touch(Entry{Name: "web-1a2b3c4d", Path: "/src/web", ConfigHash: "aaaaaaaaaaaa"}, first)
+	r.Touch(Entry{Name: "api-5e6f7a8b", Path: "/src/api"}, first)
+	// Same directory: the entry is replaced, keeping the hash when none is given
+	r.Touch(Entry{Name: "web", Path: "/src/web"}, later)
+	require.NoError(t, r.Save())
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Name: "api-5e6f7a8b", Path: "/src/api", LastUsed: first},
+		{Name: "web", Path: "/src/web", LastUsed: later, ConfigHash: "aaaaaaaaaaaa"},
+	}, loaded.Projects)
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	gone := filepath.Join(dir, "gone")
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0644))
+
+	r := &Registry{Projects: []Entry{
+		{Name: "here", Path: dir},
+		{Name: "gone", Path: gone},
+		{Name: "file", Path: file},
+	}}
+	pruned := r.Prune()
+	assert.Equal(t, []Entry{{Name: "here", Path: dir}}, r.Projects)
+	assert.Equal(t, []Entry{{Name: "gone", Path: gone}, {Name: "file", Path: file}}, pruned)
+}
+
+func TestFind(t *testing.T) {
+	r := &Registry{Projects: []Entry{
+		{Name: "api-1a2b3c4d", Path: "/work/api"},
+		{Name: "api-5e6f7a8b", Path: "/oss/api"},
+		{Name: "web-9c0d1e2f", Path: "/work/web"},
+		{Name: "billing", Path: "/work/billing-service"},
+	}}
+
+	tests := []struct {
+		name     string
+		query    string
+		wantPath string
+		wantErr  string
+	}{
+		{name: "exact", query: "api-5e6f7a8b", wantPath: "/oss/api"},
+		{name: "configured name", query: "billing", wantPath: "/work/billing-service"},
+		{name: "unique base name", query: "web", wantPath: "/work/web"},
+		{name: "ambiguous base name", query: "api", wantErr: "matches several projects"},
+		{name: "unknown", query: "docs", wantErr: "no project named docs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := r.Find(tt.query)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPath, entry.Path)
+		})
+	}
+}
+
+func TestUpdatePrunes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	r := &Registry{path: path, Projects: []Entry{{Name: "gone", Path: filepath.Join(dir, "gone")}}}
+	require.NoError(t, r.Save())
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, Update(path, func(r *Registry) {
+		r.Touch(Entry{Name: "here", Path: dir}, now)
+	}))
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{{Name: "here", Path: dir, LastUsed: now}}, loaded.Projects)
+}
@@ -0,0 +1,341 @@
+package review
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChangeKind describes how a file differs between the host tree and the copy
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "added"
+	Modified ChangeKind = "modified"
+	Deleted  ChangeKind = "deleted"
+)
+
+// Change is a file that differs between the host tree and the copy
+type Change struct {
+	Path string // Slash-separated path relative to the workspace root
+	Kind ChangeKind
+}
+
+// excluded lists top-level directories that are never copied or reviewed:
+// rig's own state (run transcripts, worktrees) and the git directory, whose
+// history is not part of the working tree review
+var excluded = map[string]bool{
+	".git": true,
+	".rig": true,
+}
+
+// isExcluded reports whether a relative path lies in an excluded directory
+func isExcluded(rel string) bool {
+	top, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	return excluded[top]
+}
+
+// Archive writes a tar of dir to w, for seeding an isolated workspace copy.
+// The git directory is included so git works inside the copy; rig's own
+// state directory is not.
+func Archive(dir string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if top, _, _ := strings.Cut(filepath.ToSlash(rel), "/"); top == ".rig" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return writeEntry(tw, dir, filepath.ToSlash(rel))
+	})
+	if err != nil {
+		return fmt.Errorf("archiving %s: %w", dir, err)
+	}
+	return tw.Close()
+}
+
+// ArchiveFiles writes a tar of the given files under dir to w, including
+// their parent directories, for restoring selected files in a copy
+func ArchiveFiles(dir string, paths []string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	written := make(map[string]bool)
+	for _, rel := range paths {
+		var parents []string
+		for parent := pathpkg.Dir(rel); parent != "."; parent = pathpkg.Dir(parent) {
+			parents = append([]string{parent}, parents...)
+		}
+		for _, entry := range append(parents, rel) {
+			if written[entry] {
+				continue
+			}
+			if err := writeEntry(tw, dir, entry); err != nil {
+				return fmt.Errorf("archiving %s: %w", entry, err)
+			}
+			written[entry] = true
+		}
+	}
+	return tw.Close()
+}
+
+// writeEntry adds the file, directory or symlink at the slash-separated
+// path rel under dir to a tar. Other file types are skipped.
+func writeEntry(tw *tar.Writer, dir, rel string) error {
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	link := ""
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	} else if !info.Mode().IsRegular() && !info.IsDir() {
+		return nil // sockets, devices and pipes can't be copied
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = rel
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Extract unpacks a tar of a directory, as returned by the Docker API for
+// a container path, into dest. The archive's top-level directory is stripped.
+func Extract(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+
+		_, rel, _ := strings.Cut(strings.TrimPrefix(header.Name, "./"), "/")
+		rel = strings.TrimSuffix(rel, "/")
+		if rel == "" || isExcluded(rel) {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(rel))
+		if !strings.HasPrefix(target, filepath.Clean(dest)+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %q escapes the destination", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(header.Mode)&fs.ModePerm)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Compare lists the files that differ between the host tree and the copy,
+// sorted by path. Directories themselves are not reported.
+func Compare(hostDir, copyDir string) ([]Change, error) {
+	hostFiles, err := listFiles(hostDir)
+	if err != nil {
+		return nil, err
+	}
+	copyFiles, err := listFiles(copyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for rel := range copyFiles {
+		if _, ok := hostFiles[rel]; !ok {
+			changes = append(changes, Change{Path: rel, Kind: Added})
+			continue
+		}
+		same, err := sameFile(filepath.Join(hostDir, rel), filepath.Join(copyDir, rel))
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			changes = append(changes, Change{Path: rel, Kind: Modified})
+		}
+	}
+	for rel := range hostFiles {
+		if _, ok := copyFiles[rel]; !ok {
+			changes = append(changes, Change{Path: rel, Kind: Deleted})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Apply copies changed files from the copy into the host tree and removes
+// files deleted in the copy
+func Apply(hostDir, copyDir string, changes []Change) error {
+	for _, c := range changes {
+		hostPath := filepath.Join(hostDir, filepath.FromSlash(c.Path))
+		if c.Kind == Deleted {
+			if err := os.Remove(hostPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("removing %s: %w", c.Path, err)
+			}
+			continue
+		}
+		if err := copyFile(filepath.Join(copyDir, filepath.FromSlash(c.Path)), hostPath); err != nil {
+			return fmt.Errorf("applying %s: %w", c.Path, err)
+		}
+	}
+	return nil
+}
+
+// listFiles returns the regular files and symlinks under dir, keyed by
+// slash-separated relative path
+func listFiles(dir string) (map[string]fs.FileMode, error) {
+	files := make(map[string]fs.FileMode)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if isExcluded(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() || d.Type()&fs.ModeSymlink != 0 {
+			files[filepath.ToSlash(rel)] = d.Type()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// sameFile compares two files by type, executable bit, and content or link target
+func sameFile(a, b string) (bool, error) {
+	infoA, err := os.Lstat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Lstat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Mode().Type() != infoB.Mode().Type() {
+		return false, nil
+	}
+
+	if infoA.Mode()&fs.ModeSymlink != 0 {
+		linkA, err := os.Readlink(a)
+		if err != nil {
+			return false, err
+		}
+		linkB, err := os.Readlink(b)
+		if err != nil {
+			return false, err
+		}
+		return linkA == linkB, nil
+	}
+
+	if infoA.Size() != infoB.Size() || infoA.Mode()&0111 != infoB.Mode()&0111 {
+		return false, nil
+	}
+	dataA, err := os.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	dataB, err := os.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(dataA, dataB), nil
+}
+
+// copyFile replaces dst with src, preserving symlinks and permissions
+func copyFile(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, dst)
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}
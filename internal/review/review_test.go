@@ -0,0 +1,209 @@
+package review
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles creates files under dir from a map of relative path to content
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestCompare(t *testing.T) {
+	hostDir := t.TempDir()
+	copyDir := t.TempDir()
+
+	writeFiles(t, hostDir, map[string]string{
+		"main.go":          "package main",
+		"README.md":        "readme",
+		"old/removed.txt":  "bye",
+		".git/HEAD":        "ref: refs/heads/main",
+		".rig/runs/x.log":  "transcript",
+		"same/unchanged.c": "int x;",
+	})
+	writeFiles(t, copyDir, map[string]string{
+		"main.go":          "package main // edited",
+		"README.md":        "readme",
+		"new/added.txt":    "hi",
+		".git/HEAD":        "ref: refs/heads/agent",
+		"same/unchanged.c": "int x;",
+	})
+
+	changes, err := Compare(hostDir, copyDir)
+	require.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Path: "main.go", Kind: Modified},
+		{Path: "new/added.txt", Kind: Added},
+		{Path: "old/removed.txt", Kind: Deleted},
+	}, changes)
+}
+
+func TestCompareExecutableBit(t *testing.T) {
+	hostDir := t.TempDir()
+	copyDir := t.TempDir()
+	writeFiles(t, hostDir, map[string]string{"run.sh": "echo hi"})
+	writeFiles(t, copyDir, map[string]string{"run.sh": "echo hi"})
+	require.NoError(t, os.Chmod(filepath.Join(copyDir, "run.sh"), 0755))
+
+	changes, err := Compare(hostDir, copyDir)
+	require.NoError(t, err)
+	assert.Equal(t, []Change{{Path: "run.sh", Kind: Modified}}, changes)
+}
+
+func TestApply(t *testing.T) {
+	hostDir := t.TempDir()
+	copyDir := t.TempDir()
+
+	writeFiles(t, hostDir, map[string]string{
+		"main.go":         "package main",
+		"old/removed.txt": "bye",
+		"keep.txt":        "host",
+	})
+	writeFiles(t, copyDir, map[string]string{
+		"main.go":       "package main // edited",
+		"new/added.txt": "hi",
+		"keep.txt":      "agent",
+	})
+	require.NoError(t, os.Symlink("main.go", filepath.Join(copyDir, "link.go")))
+
+	err := Apply(hostDir, copyDir, []Change{
+		{Path: "main.go", Kind: Modified},
+		{Path: "new/added.txt", Kind: Added},
+		{Path: "old/removed.txt", Kind: Deleted},
+		{Path: "link.go", Kind: Added},
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(hostDir, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main // edited", string(data))
+
+	data, err = os.ReadFile(filepath.Join(hostDir, "new", "added.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hi", string(data))
+
+	assert.NoFileExists(t, filepath.Join(hostDir, "old", "removed.txt"))
+
+	link, err := os.Readlink(filepath.Join(hostDir, "link.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "main.go", link)
+
+	// Files that weren't selected are left alone
+	data, err = os.ReadFile(filepath.Join(hostDir, "keep.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "host", string(data))
+}
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":          "package main",
+		".git/HEAD":        "ref: refs/heads/main",
+		".rig/runs/x.log":  "transcript",
+		"pkg/lib/lib.go":   "package lib",
+		".rig.yml":         "shell: bash",
+		"docs/guide/a.txt": "a",
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, Archive(dir, &buf))
+
+	var names []string
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+	assert.Contains(t, names, "main.go")
+	assert.Contains(t, names, ".git/HEAD")
+	assert.Contains(t, names, ".rig.yml")
+	assert.Contains(t, names, "pkg/lib/lib.go")
+	assert.NotContains(t, names, ".rig/runs/x.log")
+	assert.NotContains(t, names, ".rig/")
+}
+
+func TestExtract(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	entries := []struct {
+		header  tar.Header
+		content string
+	}{
+		{header: tar.Header{Name: "workspace/", Typeflag: tar.TypeDir, Mode: 0755}},
+		{header: tar.Header{Name: "workspace/main.go", Typeflag: tar.TypeReg, Mode: 0644}, content: "package main"},
+		{header: tar.Header{Name: "workspace/bin/run.sh", Typeflag: tar.TypeReg, Mode: 0755}, content: "echo"},
+		{header: tar.Header{Name: "workspace/link", Typeflag: tar.TypeSymlink, Linkname: "main.go"}},
+		{header: tar.Header{Name: "workspace/.git/HEAD", Typeflag: tar.TypeReg, Mode: 0644}, content: "ref"},
+	}
+	for _, e := range entries {
+		e.header.Size = int64(len(e.content))
+		require.NoError(t, tw.WriteHeader(&e.header))
+		_, err := tw.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	dest := t.TempDir()
+	require.NoError(t, Extract(&buf, dest))
+
+	data, err := os.ReadFile(filepath.Join(dest, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main", string(data))
+
+	info, err := os.Stat(filepath.Join(dest, "bin", "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	link, err := os.Readlink(filepath.Join(dest, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "main.go", link)
+
+	assert.NoDirExists(t, filepath.Join(dest, ".git"))
+}
+
+func TestExtractRejectsEscapingPaths(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "workspace/../../evil", Typeflag: tar.TypeReg, Mode: 0644}))
+	require.NoError(t, tw.Close())
+
+	assert.Error(t, Extract(&buf, t.TempDir()))
+}
+
+func TestArchiveFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a/b/one.txt": "one",
+		"a/two.txt":   "two",
+		"other.txt":   "other",
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, ArchiveFiles(dir, []string{"a/b/one.txt", "a/two.txt"}, &buf))
+
+	var names []string
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+	assert.Equal(t, []string{"a/", "a/b/", "a/b/one.txt", "a/two.txt"}, names)
+}
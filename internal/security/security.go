@@ -0,0 +1,101 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wfaler/rig/internal/config"
+)
+
+// KeptCapabilities are the only capabilities a hardened container retains.
+// They cover file ownership fixes, signalling processes and binding low ports.
+var KeptCapabilities = []string{
+	"CHOWN",
+	"DAC_OVERRIDE",
+	"FOWNER",
+	"FSETID",
+	"KILL",
+	"NET_BIND_SERVICE",
+	"SETGID",
+	"SETUID",
+}
+
+// TmpfsOptions are the mount options for /tmp on a read-only root filesystem.
+// exec is kept because compilers and test runners execute binaries from /tmp.
+const TmpfsOptions = "rw,exec,nosuid,nodev"
+
+// Profile holds the container settings that implement a security profile
+type Profile struct {
+	CapDrop        []string
+	CapAdd         []string
+	SecurityOpt    []string
+	ReadOnlyRootfs bool
+	Tmpfs          map[string]string
+
+	seccomp      string // Description of the applied seccomp profile
+	dockerAccess string // Docker access mode the container runs with
+}
+
+// Hardened returns the hardened profile for a config, for a container with
+// the given Docker access mode. A custom seccomp profile path is resolved
+// relative to projectDir.
+func Hardened(cfg *config.SecurityConfig, dockerAccess, projectDir string) (*Profile, error) {
+	p := &Profile{
+		CapDrop:      []string{"ALL"},
+		CapAdd:       KeptCapabilities,
+		SecurityOpt:  []string{"no-new-privileges:true"},
+		seccomp:      "Docker default profile",
+		dockerAccess: dockerAccess,
+	}
+
+	if cfg.Seccomp != "" {
+		path := cfg.Seccomp
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading seccomp profile: %w", err)
+		}
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("seccomp profile %s is not valid JSON", cfg.Seccomp)
+		}
+		// The Docker API takes the profile inline, as the docker CLI does with --security-opt
+		p.SecurityOpt = append(p.SecurityOpt, "seccomp="+string(data))
+		p.seccomp = cfg.Seccomp
+	}
+
+	if cfg.ReadOnly {
+		p.ReadOnlyRootfs = true
+		p.Tmpfs = map[string]string{"/tmp": TmpfsOptions}
+	}
+
+	return p, nil
+}
+
+// Protections describes the active protections, one per line, for reporting
+func (p *Profile) Protections() []string {
+	protections := []string{
+		"capabilities: all dropped except " + strings.Join(p.CapAdd, ", "),
+		"no-new-privileges: on",
+		"sudo: disabled",
+		"seccomp: " + p.seccomp,
+	}
+	if p.ReadOnlyRootfs {
+		protections = append(protections, "root filesystem: read-only (tmpfs on /tmp)")
+	} else {
+		protections = append(protections, "root filesystem: writable")
+	}
+	switch p.dockerAccess {
+	case "full":
+		protections = append(protections, "docker access: full (host socket, not restricted by this profile)")
+	case "proxy":
+		protections = append(protections, "docker access: proxy (filtering socket proxy)")
+	default:
+		protections = append(protections, "docker access: "+p.dockerAccess)
+	}
+	return protections
+}
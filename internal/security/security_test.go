@@ -0,0 +1,70 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wfaler/rig/internal/config"
+)
+
+func TestHardened(t *testing.T) {
+	p, err := Hardened(&config.SecurityConfig{Profile: "hardened"}, "proxy", t.TempDir())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"ALL"}, p.CapDrop)
+	assert.Equal(t, KeptCapabilities, p.CapAdd)
+	assert.Equal(t, []string{"no-new-privileges:true"}, p.SecurityOpt)
+	assert.False(t, p.ReadOnlyRootfs)
+	assert.Empty(t, p.Tmpfs)
+	assert.Contains(t, p.Protections(), "seccomp: Docker default profile")
+	assert.Contains(t, p.Protections(), "root filesystem: writable")
+	assert.Contains(t, p.Protections(), "docker access: proxy (filtering socket proxy)")
+}
+
+func TestHardenedDockerAccess(t *testing.T) {
+	tests := []struct {
+		access string
+		want   string
+	}{
+		{"full", "docker access: full (host socket, not restricted by this profile)"},
+		{"proxy", "docker access: proxy (filtering socket proxy)"},
+		{"none", "docker access: none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.access, func(t *testing.T) {
+			p, err := Hardened(&config.SecurityConfig{Profile: "hardened"}, tt.access, t.TempDir())
+			require.NoError(t, err)
+			assert.Contains(t, p.Protections(), tt.want)
+		})
+	}
+}
+
+func TestHardenedReadOnly(t *testing.T) {
+	p, err := Hardened(&config.SecurityConfig{Profile: "hardened", ReadOnly: true}, "proxy", t.TempDir())
+	require.NoError(t, err)
+
+	assert.True(t, p.ReadOnlyRootfs)
+	assert.Equal(t, map[string]string{"/tmp": TmpfsOptions}, p.Tmpfs)
+	assert.Contains(t, p.Protections(), "root filesystem: read-only (tmpfs on /tmp)")
+}
+
+func TestHardenedSeccomp(t *testing.T) {
+	dir := t.TempDir()
+	profile := `{"defaultAction":"SCMP_ACT_ERRNO","syscalls":[]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "seccomp.json"), []byte(profile), 0644))
+
+	p, err := Hardened(&config.SecurityConfig{Profile: "hardened", Seccomp: "seccomp.json"}, "proxy", dir)
+	require.NoError(t, err)
+	assert.Contains(t, p.SecurityOpt, "seccomp="+profile)
+	assert.Contains(t, p.Protections(), "seccomp: seccomp.json")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644))
+	_, err = Hardened(&config.SecurityConfig{Profile: "hardened", Seccomp: "broken.json"}, "proxy", dir)
+	assert.ErrorContains(t, err, "not valid JSON")
+
+	_, err = Hardened(&config.SecurityConfig{Profile: "hardened", Seccomp: "missing.json"}, "proxy", dir)
+	assert.ErrorContains(t, err, "reading seccomp profile")
+}
@@ -0,0 +1,120 @@
+package sessions
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Socket names the tmux server that holds rig's interactive sessions, apart
+// from any tmux the user runs in the container
+const Socket = "rig"
+
+// DetachSequence is sent to the tmux client when rig reads the detach keys.
+// It is bound to detach-client, so only the client that sent it detaches.
+const DetachSequence = "\x1b[9999~"
+
+// validName matches session names; tmux reserves '.' and ':' in targets
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// Session is a tmux session in the container
+type Session struct {
+	Name    string
+	Created time.Time
+	Clients int    // Terminals attached to it
+	Command string // Command running in its active pane
+}
+
+// ValidName checks if a string can be used as a session name
+func ValidName(name string) bool {
+	return validName.MatchString(name)
+}
+
+// tmux returns a tmux command line on rig's server. The server options come
+// first so they are in place whichever command starts the server: no status
+// line or prefix key, so the session looks like a plain terminal.
+func tmux(args ...string) []string {
+	cmd := []string{"tmux", "-L", Socket,
+		"set", "-s", "user-keys[0]", DetachSequence, ";",
+		"set", "-s", "escape-time", "10", ";",
+		"bind", "-n", "User0", "detach-client", ";",
+		"set", "-g", "status", "off", ";",
+		"set", "-g", "prefix", "None", ";",
+	}
+	return append(cmd, args...)
+}
+
+// NewCommand returns the command that runs command in the named session,
+// starting in dir, or attaches to the session if it exists already
+func NewCommand(name, dir string, command []string) []string {
+	args := []string{"new-session", "-A", "-s", name, "-c", dir}
+	return tmux(append(args, command...)...)
+}
+
+// AttachCommand returns the command that attaches to an existing session,
+// or to the most recently used one if name is empty
+func AttachCommand(name string) []string {
+	if name == "" {
+		return tmux("attach-session")
+	}
+	return tmux("attach-session", "-t", "="+name)
+}
+
+// ObserveCommand returns the command that attaches to a session as a
+// read-only client, whose terminal size doesn't affect the session
+func ObserveCommand(name string) []string {
+	return tmux("attach-session", "-f", "read-only,ignore-size", "-t", "="+name)
+}
+
+// ListCommand returns the command that prints one line per session. It
+// prints nothing when the server isn't running, i.e. there are no sessions.
+func ListCommand() []string {
+	format := "#{session_name}\t#{session_created}\t#{session_attached}\t#{pane_current_command}"
+	return []string{"sh", "-c", fmt.Sprintf("tmux -L %s list-sessions -F '%s' 2>/dev/null || true", Socket, format)}
+}
+
+// ParseList parses the output of ListCommand, sorted by creation time
+func ParseList(output string) ([]Session, error) {
+	var sessions []Session
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected session line: %q", line)
+		}
+		created, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing creation time of session %s: %w", fields[0], err)
+		}
+		clients, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("parsing clients of session %s: %w", fields[0], err)
+		}
+		sessions = append(sessions, Session{
+			Name:    fields[0],
+			Created: time.Unix(created, 0),
+			Clients: clients,
+			Command: fields[3],
+		})
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Created.Before(sessions[j].Created) })
+	return sessions, nil
+}
+
+// NextName returns the lowest number not yet used as a session name
+func NextName(existing []Session) string {
+	used := make(map[string]bool, len(existing))
+	for _, s := range existing {
+		used[s.Name] = true
+	}
+	for n := 1; ; n++ {
+		if name := strconv.Itoa(n); !used[name] {
+			return name
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package sessions
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"1":        true,
+		"main":     true,
+		"claude_2": true,
+		"wt-fix":   true,
+		"":         false,
+		"-x":       false,
+		"a.b":      false,
+		"a:b":      false,
+	} {
+		assert.Equal(t, want, ValidName(name), name)
+	}
+}
+
+func TestNewCommand(t *testing.T) {
+	cmd := NewCommand("main", "/workspace/api", []string{"/bin/zsh"})
+	assert.Equal(t, []string{"tmux", "-L", Socket}, cmd[:3])
+	assert.Equal(t, []string{"new-session", "-A", "-s", "main", "-c", "/workspace/api", "/bin/zsh"}, cmd[len(cmd)-7:])
+	assert.Contains(t, cmd, DetachSequence)
+
+	attach := AttachCommand("main")
+	assert.Equal(t, []string{"attach-session", "-t", "=main"}, attach[len(attach)-3:])
+	attach = AttachCommand("")
+	assert.Equal(t, "attach-session", attach[len(attach)-1])
+
+	observe := ObserveCommand("main")
+	assert.Equal(t, []string{"attach-session", "-f", "read-only,ignore-size", "-t", "=main"}, observe[len(observe)-5:])
+}
+
+func TestParseList(t *testing.T) {
+	sessions, err := ParseList("2\t1700000100\t0\tclaude\n1\t1700000000\t2\tzsh\n")
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, Session{Name: "1", Created: time.Unix(1700000000, 0), Clients: 2, Command: "zsh"}, sessions[0])
+	assert.Equal(t, "2", sessions[1].Name)
+
+	sessions, err = ParseList("")
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	_, err = ParseList("main\tyesterday\t0\tzsh")
+	assert.Error(t, err)
+}
+
+func TestNextName(t *testing.T) {
+	assert.Equal(t, "1", NextName(nil))
+	assert.Equal(t, "2", NextName([]Session{{Name: "1"}, {Name: "main"}, {Name: "3"}}))
+}
+
+// TestCommands runs the tmux command lines against a private server
+func TestCommands(t *testing.T) {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux not available")
+	}
+	socket := fmt.Sprintf("rig-test-%d", os.Getpid())
+	withSocket := func(cmd []string) []string {
+		out := make([]string, len(cmd))
+		for i, arg := range cmd {
+			if arg == Socket {
+				arg = socket
+			}
+			out[i] = arg
+		}
+		return out
+	}
+	run := func(cmd []string) string {
+		out, err := exec.Command(cmd[0], cmd[1:]...).Output()
+		require.NoError(t, err)
+		return string(out)
+	}
+	t.Cleanup(func() { _ = exec.Command("tmux", "-L", socket, "kill-server").Run() })
+
+	// The server options are accepted by the tmux in use
+	run(withSocket(tmux("new-session", "-d", "-s", "build", "sleep", "30")))
+
+	list := ListCommand()
+	list[2] = strings.Replace(list[2], "-L "+Socket+" ", "-L "+socket+" ", 1)
+	sessions, err := ParseList(run(list))
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "build", sessions[0].Name)
+	assert.Equal(t, 0, sessions[0].Clients)
+	assert.Equal(t, "sleep", sessions[0].Command)
+
+	status := run([]string{"tmux", "-L", socket, "show", "-gv", "status"})
+	assert.Equal(t, "off\n", status)
+}
@@ -0,0 +1,94 @@
+package sidecar
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"sort"
+
+	"github.com/wfaler/rig/internal/dockerproxy"
+	"github.com/wfaler/rig/internal/egress"
+)
+
+const (
+	// ImageName is the name of the helper image shared by all projects
+	ImageName = "rig-sidecar"
+
+	// EgressProxyBinary is the egress filtering proxy inside the image
+	EgressProxyBinary = "/egress-proxy"
+
+	// DockerProxyBinary is the Docker socket filtering proxy inside the image
+	DockerProxyBinary = "/docker-proxy"
+)
+
+// dockerfile builds the helpers from source and ships them in an empty image,
+// so the image works regardless of the host's OS and architecture
+const dockerfile = `FROM golang:1.23-alpine AS build
+WORKDIR /src
+COPY . .
+RUN CGO_ENABLED=0 go build -o /egress-proxy ./internal/egress/proxy \
+    && CGO_ENABLED=0 go build -o /docker-proxy ./internal/dockerproxy/proxy
+
+FROM scratch
+COPY --from=build /egress-proxy /egress-proxy
+COPY --from=build /docker-proxy /docker-proxy
+`
+
+// goMod is the module file for the build context
+const goMod = "module github.com/wfaler/rig\n\ngo 1.23\n"
+
+// packages maps embedded sources to their directory in the build context
+var packages = map[string]embed.FS{
+	"internal/egress":      egress.Sources,
+	"internal/dockerproxy": dockerproxy.Sources,
+}
+
+// BuildContext returns the files needed to build the sidecar image, keyed by path
+func BuildContext() (map[string][]byte, error) {
+	files := map[string][]byte{
+		"Dockerfile": []byte(dockerfile),
+		"go.mod":     []byte(goMod),
+	}
+
+	for dir, sources := range packages {
+		err := fs.WalkDir(sources, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := sources.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files[dir+"/"+path] = data
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("reading %s sources: %w", dir, err)
+		}
+	}
+
+	return files, nil
+}
+
+// ImageRef returns the sidecar image reference, tagged by a hash of its build context
+func ImageRef() (string, error) {
+	files, err := BuildContext()
+	if err != nil {
+		return "", err
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	for _, path := range paths {
+		hash.Write([]byte(path))
+		hash.Write(files[path])
+	}
+	return fmt.Sprintf("%s:%s", ImageName, hex.EncodeToString(hash.Sum(nil))[:12]), nil
+}
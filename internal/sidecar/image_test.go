@@ -0,0 +1,40 @@
+package sidecar
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildContext(t *testing.T) {
+	files, err := BuildContext()
+	require.NoError(t, err)
+
+	for _, path := range []string{
+		"Dockerfile",
+		"go.mod",
+		"internal/egress/proxy/main.go",
+		"internal/egress/policy.go",
+		"internal/dockerproxy/proxy/main.go",
+		"internal/dockerproxy/filter.go",
+	} {
+		assert.Contains(t, files, path)
+	}
+
+	// Tests are not part of the image
+	for path := range files {
+		assert.False(t, strings.HasSuffix(path, "_test.go"), "unexpected test file %s", path)
+	}
+}
+
+func TestImageRef(t *testing.T) {
+	ref, err := ImageRef()
+	require.NoError(t, err)
+	assert.Regexp(t, `^rig-sidecar:[0-9a-f]{12}$`, ref)
+
+	again, err := ImageRef()
+	require.NoError(t, err)
+	assert.Equal(t, ref, again)
+}
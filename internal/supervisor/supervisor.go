@@ -0,0 +1,338 @@
+package supervisor
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wfaler/rig/internal/config"
+)
+
+const (
+	// ScriptPath is where the supervisor script is installed in the image
+	ScriptPath = "/usr/local/bin/rig-supervise"
+
+	// DefinitionsDir holds one directory per process, with its run script and
+	// restart policy, baked into the image
+	DefinitionsDir = "/etc/rig/processes"
+
+	// StateDir holds each process's pid files, restart count, exit code and log
+	StateDir = "/tmp/rig/processes"
+
+	// PreStopPath is the script of shutdown.pre_stop hooks baked into the image
+	PreStopPath = "/etc/rig/pre-stop"
+
+	// TimeLayout is the timestamp format that starts each line of a process log
+	TimeLayout = "2006-01-02T15:04:05-0700"
+)
+
+// Process is a long-running command supervised inside the container
+type Process struct {
+	Name       string
+	Command    string
+	WorkingDir string
+	Env        map[string]string
+	Restart    string
+}
+
+// Processes returns the processes to supervise for a config, sorted by name:
+// the configured ones and code-server when enabled
+func Processes(cfg *config.Config) []Process {
+	var procs []Process
+	for name, p := range cfg.Processes {
+		procs = append(procs, Process{
+			Name:       name,
+			Command:    p.Command,
+			WorkingDir: p.WorkingDir,
+			Env:        p.Env,
+			Restart:    p.GetRestart(),
+		})
+	}
+	if cfg.IsCodeServerEnabled() {
+		procs = append(procs, Process{
+			Name:    config.CodeServerProcess,
+			Command: fmt.Sprintf(`code-server --bind-addr 0.0.0.0:%d --auth none ${CODE_SERVER_WORKSPACE:+"$CODE_SERVER_WORKSPACE"}`, cfg.GetCodeServerPort()),
+			Restart: "always",
+		})
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].Name < procs[j].Name })
+	return procs
+}
+
+// RunScript returns the script that runs a process. The supervisor starts it
+// in the container's working directory, the workspace.
+func RunScript(p Process) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/bash\n")
+	if p.WorkingDir != "" {
+		fmt.Fprintf(&b, "cd %s || exit 1\n", quote(p.WorkingDir))
+	}
+	keys := make([]string, 0, len(p.Env))
+	for k := range p.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "export %s=%s\n", k, quote(p.Env[k]))
+	}
+	fmt.Fprintf(&b, "exec bash -c %s\n", quote(p.Command))
+	return b.String()
+}
+
+// PreStopScript returns the script that runs pre_stop hooks in order,
+// stopping at the first that fails
+func PreStopScript(hooks []string) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/bash\n")
+	for _, hook := range hooks {
+		fmt.Fprintf(&b, "bash -c %s || exit\n", quote(hook))
+	}
+	return b.String()
+}
+
+// PreStopCommand returns the command that runs the image's pre_stop hooks,
+// succeeding when it has none
+func PreStopCommand() []string {
+	return []string{"bash", "-c", fmt.Sprintf("[ ! -x %[1]s ] || %[1]s", PreStopPath)}
+}
+
+// quote quotes a string for bash
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Script is the supervisor installed at ScriptPath. Each process runs in its
+// own session so a restart signals its whole process group, and crash loops
+// back off exponentially up to 30 seconds. Log lines are prefixed with the
+// time they were written, in TimeLayout. The entrypoint runs 'watch' next
+// to the container's shell, which init signals when the container stops.
+const Script = `#!/bin/bash
+# rig-supervise: runs the processes defined in ` + DefinitionsDir + `
+#   rig-supervise boot            start all processes (run by the entrypoint)
+#   rig-supervise run <name>      supervise one process in the foreground
+#   rig-supervise restart <name>  restart a process, starting it if it exited
+#   rig-supervise list            print name, state, pid, restarts and exit code
+#   rig-supervise stop [signal]   signal all processes (default TERM) and wait for them
+#   rig-supervise watch           forward a stop signal to the processes (run by the entrypoint)
+defs=` + DefinitionsDir + `
+state=` + StateDir + `
+
+alive() { [ -s "$1" ] && kill -0 "$(cat "$1")" 2>/dev/null; }
+
+stamp() {
+  while IFS= read -r line || [ -n "$line" ]; do
+    printf '%(%Y-%m-%dT%H:%M:%S%z)T %s\n' -1 "$line"
+  done
+}
+
+start() {
+  mkdir -p "$state/$1"
+  setsid "$0" run "$1" > /dev/null 2>&1 < /dev/null &
+}
+
+run() {
+  name=$1 dir=$state/$1
+  policy=$(cat "$defs/$name/restart" 2>/dev/null || echo on-failure)
+  mkdir -p "$dir"
+  echo $$ > "$dir/supervisor.pid"
+  rm -f "$dir/exit_code" "$dir/restarts" "$dir/stop"
+  trap 'rm -f "$dir/supervisor.pid"; exit 0' TERM
+  # Output of every run, and the supervisor's notes, goes through one stamp
+  # so lines stay in order
+  exec > >(stamp >> "$dir/output.log") 2>&1
+  restarts=0 delay=1
+  while true; do
+    started=$(date +%s)
+    setsid "$defs/$name/run" < /dev/null &
+    echo $! > "$dir/child.pid"
+    wait $!
+    code=$?
+    rm -f "$dir/child.pid"
+    echo $code > "$dir/exit_code"
+    echo "[rig] $name exited with code $code"
+    [ -f "$dir/stop" ] && break
+    if [ -f "$dir/restart" ]; then
+      rm -f "$dir/restart"
+    else
+      case $policy in
+        never) break ;;
+        on-failure) [ "$code" -eq 0 ] && break ;;
+      esac
+      [ $(( $(date +%s) - started )) -ge 10 ] && delay=1
+      # In the background so a stop doesn't wait for the delay to end
+      sleep $delay & wait $!
+      delay=$(( delay * 2 > 30 ? 30 : delay * 2 ))
+    fi
+    restarts=$((restarts + 1))
+    echo $restarts > "$dir/restarts"
+  done
+  rm -f "$dir/supervisor.pid"
+}
+
+restart() {
+  [ -d "$defs/$1" ] || { echo "unknown process: $1" >&2; exit 1; }
+  dir=$state/$1
+  if ! alive "$dir/supervisor.pid"; then
+    rm -f "$dir/restart"
+    start "$1"
+  elif alive "$dir/child.pid"; then
+    touch "$dir/restart"
+    pid=$(cat "$dir/child.pid")
+    kill -TERM -- "-$pid" 2>/dev/null || kill -TERM "$pid"
+  fi
+  # Otherwise the supervisor is waiting to restart it already
+}
+
+boot() {
+  # Pid files from before a container restart are stale
+  rm -f "$state"/*/*.pid "$state"/*/restart
+  for d in "$defs"/*/; do
+    [ -d "$d" ] || continue
+    name=$(basename "$d")
+    start "$name"
+    echo "Started $name"
+  done
+}
+
+stop() {
+  sig=${1:-TERM}
+  for d in "$defs"/*/; do
+    [ -d "$d" ] || continue
+    dir=$state/$(basename "$d")
+    alive "$dir/supervisor.pid" || continue
+    touch "$dir/stop"
+    if alive "$dir/child.pid"; then
+      pid=$(cat "$dir/child.pid")
+      kill -"$sig" -- "-$pid" 2>/dev/null || kill -"$sig" "$pid"
+    else
+      # Waiting to restart: end the supervisor itself
+      kill "$(cat "$dir/supervisor.pid")"
+    fi
+  done
+  # Processes that outlast the container's stop timeout are killed with it
+  for d in "$defs"/*/; do
+    while alive "$state/$(basename "$d")/supervisor.pid"; do sleep 0.1; done
+  done
+}
+
+watch() {
+  # Init forwards the stop signal to the shell's process group, this one
+  # included. The entrypoint has the shell ignore it, so once the processes
+  # have stopped the shell is killed and the container exits without
+  # waiting out its stop timeout.
+  for sig in TERM INT QUIT HUP USR1 USR2; do
+    trap "stop $sig; kill -KILL $PPID 2>/dev/null; exit 0" $sig
+  done
+  while true; do sleep 3600 & wait $!; done
+}
+
+list() {
+  for d in "$defs"/*/; do
+    [ -d "$d" ] || continue
+    name=$(basename "$d") dir=$state/$(basename "$d") st=stopped pid=
+    if alive "$dir/child.pid"; then
+      st=running pid=$(cat "$dir/child.pid")
+    elif alive "$dir/supervisor.pid"; then
+      st=restarting
+    elif [ -f "$dir/exit_code" ]; then
+      st=exited
+    fi
+    printf '%s\t%s\t%s\t%s\t%s\n' "$name" "$st" "$pid" "$(cat "$dir/restarts" 2>/dev/null)" "$(cat "$dir/exit_code" 2>/dev/null)"
+  done
+}
+
+case $1 in
+  boot) boot ;;
+  run) run "$2" ;;
+  restart) restart "$2" ;;
+  list) list ;;
+  stop) stop "$2" ;;
+  watch) watch ;;
+  *) echo "usage: rig-supervise boot|run <name>|restart <name>|list|stop [signal]|watch" >&2; exit 2 ;;
+esac
+`
+
+// State is the state of a supervised process
+type State string
+
+const (
+	Running State = "running"
+	// Restarting processes have exited and are waiting for their next start
+	Restarting State = "restarting"
+	// Exited processes were not restarted, per their policy
+	Exited State = "exited"
+	// Stopped processes were never started, e.g. after a container restart
+	Stopped State = "stopped"
+)
+
+// Status is the state of a supervised process
+type Status struct {
+	Name     string
+	State    State
+	PID      int // Set while running
+	Restarts int
+	ExitCode *int // Of the last run, if it has exited
+}
+
+// ListCommand returns the command listing process statuses in the container;
+// parse its output with ParseList
+func ListCommand() []string {
+	return []string{ScriptPath, "list"}
+}
+
+// ParseList parses the output of ListCommand
+func ParseList(output string) ([]Status, error) {
+	var statuses []Status
+	// Only newlines are trimmed: trailing fields may be empty
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected process listing: %q", line)
+		}
+		s := Status{Name: fields[0], State: State(fields[1])}
+		var err error
+		if fields[2] != "" {
+			if s.PID, err = strconv.Atoi(fields[2]); err != nil {
+				return nil, fmt.Errorf("parsing pid of %s: %w", s.Name, err)
+			}
+		}
+		if fields[3] != "" {
+			if s.Restarts, err = strconv.Atoi(fields[3]); err != nil {
+				return nil, fmt.Errorf("parsing restarts of %s: %w", s.Name, err)
+			}
+		}
+		if fields[4] != "" {
+			code, err := strconv.Atoi(fields[4])
+			if err != nil {
+				return nil, fmt.Errorf("parsing exit code of %s: %w", s.Name, err)
+			}
+			s.ExitCode = &code
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// RestartCommand returns the command restarting a process in the container
+func RestartCommand(name string) []string {
+	return []string{ScriptPath, "restart", name}
+}
+
+// LogPath returns the container path of a process's log
+func LogPath(name string) string {
+	return path.Join(StateDir, name, "output.log")
+}
+
+// LogsCommand returns the command printing a process's log; with follow, it
+// keeps printing new output until interrupted
+func LogsCommand(name string, follow bool) []string {
+	if !follow {
+		return []string{"cat", LogPath(name)}
+	}
+	return []string{"tail", "-n", "+1", "-F", LogPath(name)}
+}
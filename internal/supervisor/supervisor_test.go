@@ -0,0 +1,177 @@
+package supervisor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wfaler/rig/internal/config"
+)
+
+func TestProcesses(t *testing.T) {
+	cfg := &config.Config{
+		Processes: map[string]config.ProcessConfig{
+			"worker": {Command: "go run ./cmd/worker", Restart: "always"},
+			"api":    {Command: "npm start", WorkingDir: "api"},
+		},
+	}
+	procs := Processes(cfg)
+	require.Len(t, procs, 2)
+	assert.Equal(t, "api", procs[0].Name)
+	assert.Equal(t, "on-failure", procs[0].Restart)
+	assert.Equal(t, "worker", procs[1].Name)
+
+	cfg.CodeServer = &config.CodeServerConfig{Enabled: true, Port: 9000}
+	procs = Processes(cfg)
+	require.Len(t, procs, 3)
+	assert.Equal(t, config.CodeServerProcess, procs[1].Name)
+	assert.Equal(t, "always", procs[1].Restart)
+	assert.Contains(t, procs[1].Command, "--bind-addr 0.0.0.0:9000")
+	assert.Contains(t, procs[1].Command, `${CODE_SERVER_WORKSPACE:+"$CODE_SERVER_WORKSPACE"}`)
+}
+
+func TestRunScript(t *testing.T) {
+	script := RunScript(Process{
+		Name:       "web",
+		Command:    "npm run dev -- --host 0.0.0.0 && echo 'done'",
+		WorkingDir: "frontend app",
+		Env:        map[string]string{"PORT": "3000", "GREETING": "it's"},
+	})
+	assert.Equal(t, `#!/bin/bash
+cd 'frontend app' || exit 1
+export GREETING='it'\''s'
+export PORT='3000'
+exec bash -c 'npm run dev -- --host 0.0.0.0 && echo '\''done'\'''
+`, script)
+}
+
+func TestPreStopScript(t *testing.T) {
+	assert.Equal(t, `#!/bin/bash
+bash -c './scripts/flush.sh' || exit
+bash -c 'pg_ctl stop -m '\''fast'\''' || exit
+`, PreStopScript([]string{"./scripts/flush.sh", "pg_ctl stop -m 'fast'"}))
+}
+
+func TestParseList(t *testing.T) {
+	statuses, err := ParseList("code-server\trunning\t42\t\t\nweb\trestarting\t\t3\t1\ntask\texited\t\t\t0\n")
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.Equal(t, Status{Name: "code-server", State: Running, PID: 42}, statuses[0])
+	assert.Equal(t, Restarting, statuses[1].State)
+	assert.Equal(t, 3, statuses[1].Restarts)
+	require.NotNil(t, statuses[1].ExitCode)
+	assert.Equal(t, 1, *statuses[1].ExitCode)
+	assert.Equal(t, Exited, statuses[2].State)
+
+	_, err = ParseList("web\trunning")
+	assert.Error(t, err)
+}
+
+// TestScript runs the supervisor with the local bash against temporary
+// definition and state directories
+func TestScript(t *testing.T) {
+	for _, tool := range []string{"bash", "setsid"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+	dir := t.TempDir()
+	defs, state := filepath.Join(dir, "defs"), filepath.Join(dir, "state")
+	script := filepath.Join(dir, "rig-supervise")
+	content := strings.ReplaceAll(strings.ReplaceAll(Script, DefinitionsDir, defs), StateDir, state)
+	require.NoError(t, os.WriteFile(script, []byte(content), 0755))
+
+	for _, p := range []Process{
+		{Name: "web", Command: "echo serving; sleep 30", Restart: "always"},
+		{Name: "once", Command: "echo done", Restart: "on-failure"},
+		{Name: "broken", Command: "exit 3", Restart: "never"},
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(defs, p.Name), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(defs, p.Name, "run"), []byte(RunScript(p)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(defs, p.Name, "restart"), []byte(p.Restart+"\n"), 0644))
+	}
+
+	supervise := func(args ...string) string {
+		out, err := exec.Command(script, args...).Output()
+		require.NoError(t, err)
+		return string(out)
+	}
+	statuses := func() map[string]Status {
+		list, err := ParseList(supervise("list"))
+		require.NoError(t, err)
+		byName := make(map[string]Status)
+		for _, s := range list {
+			byName[s.Name] = s
+		}
+		return byName
+	}
+	t.Cleanup(func() {
+		for _, name := range []string{"web", "once", "broken"} {
+			for _, file := range []string{"supervisor.pid", "child.pid"} {
+				if data, err := os.ReadFile(filepath.Join(state, name, file)); err == nil {
+					if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+						_ = syscall.Kill(-pid, syscall.SIGKILL)
+					}
+				}
+			}
+		}
+	})
+
+	assert.Equal(t, "Started broken\nStarted once\nStarted web\n", supervise("boot"))
+
+	require.Eventually(t, func() bool {
+		s := statuses()
+		return s["web"].State == Running && s["once"].State == Exited && s["broken"].State == Exited
+	}, 5*time.Second, 50*time.Millisecond)
+
+	s := statuses()
+	require.NotNil(t, s["broken"].ExitCode)
+	assert.Equal(t, 3, *s["broken"].ExitCode)
+	assert.Equal(t, 0, *s["once"].ExitCode)
+	// Lines are stamped in order, the supervisor's note included
+	var log []byte
+	require.Eventually(t, func() bool {
+		log, _ = os.ReadFile(filepath.Join(state, "once", "output.log"))
+		return strings.Count(string(log), "\n") == 2
+	}, 5*time.Second, 50*time.Millisecond)
+	lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+	for i, want := range []string{"done", "[rig] once exited with code 0"} {
+		stamp, text, found := strings.Cut(lines[i], " ")
+		require.True(t, found)
+		_, err := time.Parse(TimeLayout, stamp)
+		assert.NoError(t, err)
+		assert.Equal(t, want, text)
+	}
+
+	// Restarting a running process replaces it without counting against its policy
+	firstPID := s["web"].PID
+	supervise("restart", "web")
+	require.Eventually(t, func() bool {
+		web := statuses()["web"]
+		return web.State == Running && web.PID != firstPID && web.Restarts == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Restarting an exited process starts a new supervisor
+	supervise("restart", "broken")
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(filepath.Join(state, "broken", "output.log"))
+		return strings.Count(string(data), "exited with code 3") == 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	_, err := exec.Command(script, "restart", "missing").Output()
+	assert.Error(t, err)
+
+	// Stopping waits for the processes and keeps them from being restarted
+	supervise("stop")
+	web := statuses()["web"]
+	assert.Equal(t, Exited, web.State)
+	require.NotNil(t, web.ExitCode)
+	assert.Equal(t, 143, *web.ExitCode)
+}
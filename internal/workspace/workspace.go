@@ -0,0 +1,112 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/wfaler/rig/internal/config"
+)
+
+const (
+	// CodeWorkspacePath is where the generated multi-root workspace file is
+	// mounted in the container
+	CodeWorkspacePath = "/home/developer/.config/rig/rig.code-workspace"
+
+	// CodeWorkspaceEnv tells the entrypoint which workspace file code-server opens
+	CodeWorkspaceEnv = "CODE_SERVER_WORKSPACE"
+
+	// codeWorkspacesDir holds generated workspace files, relative to the project
+	codeWorkspacesDir = ".rig/code-workspaces"
+)
+
+// Folder is an extra workspace folder resolved for a container
+type Folder struct {
+	Name          string
+	HostPath      string // Absolute path on the host
+	ContainerPath string // Mount point in the container
+}
+
+// Folders resolves the extra workspace folders of a config. Relative paths
+// are resolved against projectDir, the directory containing .rig.yml, and
+// must exist. Folders are mounted under containerWorkspace, or at their host
+// path when the workspace is mirrored.
+func Folders(cfg *config.Config, projectDir, containerWorkspace string) ([]Folder, error) {
+	folders := make([]Folder, 0, len(cfg.WorkspaceFolders))
+	for _, f := range cfg.WorkspaceFolders {
+		hostPath := f.Path
+		if !filepath.IsAbs(hostPath) {
+			hostPath = filepath.Join(projectDir, hostPath)
+		}
+		hostPath = filepath.Clean(hostPath)
+
+		info, err := os.Stat(hostPath)
+		if err != nil {
+			return nil, fmt.Errorf("workspace folder %s: %w", f.Path, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("workspace folder %s is not a directory", f.Path)
+		}
+
+		containerPath := path.Join(containerWorkspace, f.GetName())
+		if cfg.IsWorkspaceMirrored() {
+			containerPath = filepath.ToSlash(hostPath)
+		}
+		folders = append(folders, Folder{Name: f.GetName(), HostPath: hostPath, ContainerPath: containerPath})
+	}
+	return folders, nil
+}
+
+// Binds returns the bind mounts for folders, read-only if readOnly is set
+// so that review isolation can't be bypassed through them
+func Binds(folders []Folder, readOnly bool) []string {
+	mode := "rw"
+	if readOnly {
+		mode = "ro"
+	}
+	binds := make([]string, 0, len(folders))
+	for _, f := range folders {
+		binds = append(binds, fmt.Sprintf("%s:%s:%s", f.HostPath, f.ContainerPath, mode))
+	}
+	return binds
+}
+
+// codeWorkspace is the subset of the VS Code .code-workspace format rig writes
+type codeWorkspace struct {
+	Folders []codeWorkspaceFolder `json:"folders"`
+}
+
+type codeWorkspaceFolder struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path"`
+}
+
+// CodeWorkspace returns a multi-root workspace file opening the project at
+// root followed by the extra folders
+func CodeWorkspace(root string, folders []Folder) ([]byte, error) {
+	ws := codeWorkspace{Folders: []codeWorkspaceFolder{{Path: root}}}
+	for _, f := range folders {
+		ws.Folders = append(ws.Folders, codeWorkspaceFolder{Name: f.Name, Path: f.ContainerPath})
+	}
+	data, err := json.MarshalIndent(ws, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// WriteCodeWorkspace writes a container's workspace file under the project's
+// .rig directory and returns its host path
+func WriteCodeWorkspace(projectDir, containerName string, data []byte) (string, error) {
+	dir := filepath.Join(projectDir, codeWorkspacesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating %s: %w", dir, err)
+	}
+	file := filepath.Join(dir, containerName+".code-workspace")
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return "", fmt.Errorf("writing workspace file: %w", err)
+	}
+	return file, nil
+}
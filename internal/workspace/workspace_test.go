@@ -0,0 +1,80 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wfaler/rig/internal/config"
+)
+
+func TestFolders(t *testing.T) {
+	root := t.TempDir()
+	projectDir := filepath.Join(root, "api")
+	for _, dir := range []string{"api", "shared-lib", "proto"} {
+		require.NoError(t, os.Mkdir(filepath.Join(root, dir), 0755))
+	}
+	folders := []config.WorkspaceFolder{
+		{Path: "../shared-lib"},
+		{Path: filepath.Join(root, "proto"), Name: "schemas"},
+	}
+
+	tests := []struct {
+		name          string
+		workspacePath string
+		want          []Folder
+	}{
+		{
+			name: "under workspace",
+			want: []Folder{
+				{Name: "shared-lib", HostPath: filepath.Join(root, "shared-lib"), ContainerPath: "/workspace/shared-lib"},
+				{Name: "schemas", HostPath: filepath.Join(root, "proto"), ContainerPath: "/workspace/schemas"},
+			},
+		},
+		{
+			name:          "mirrored",
+			workspacePath: "mirror",
+			want: []Folder{
+				{Name: "shared-lib", HostPath: filepath.Join(root, "shared-lib"), ContainerPath: filepath.Join(root, "shared-lib")},
+				{Name: "schemas", HostPath: filepath.Join(root, "proto"), ContainerPath: filepath.Join(root, "proto")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{WorkspaceFolders: folders, WorkspacePath: tt.workspacePath}
+			got, err := Folders(cfg, projectDir, cfg.ContainerWorkspace(projectDir))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFoldersMissing(t *testing.T) {
+	cfg := &config.Config{WorkspaceFolders: []config.WorkspaceFolder{{Path: "../missing"}}}
+	_, err := Folders(cfg, t.TempDir(), config.DefaultWorkspaceDir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "workspace folder ../missing")
+}
+
+func TestBinds(t *testing.T) {
+	folders := []Folder{{Name: "lib", HostPath: "/src/lib", ContainerPath: "/workspace/lib"}}
+	assert.Equal(t, []string{"/src/lib:/workspace/lib:rw"}, Binds(folders, false))
+	assert.Equal(t, []string{"/src/lib:/workspace/lib:ro"}, Binds(folders, true))
+}
+
+func TestCodeWorkspace(t *testing.T) {
+	data, err := CodeWorkspace("/workspace", []Folder{{Name: "lib", HostPath: "/src/lib", ContainerPath: "/workspace/lib"}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"folders":[{"path":"/workspace"},{"name":"lib","path":"/workspace/lib"}]}`, string(data))
+
+	projectDir := t.TempDir()
+	file, err := WriteCodeWorkspace(projectDir, "rig-api", data)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(projectDir, ".rig", "code-workspaces", "rig-api.code-workspace"), file)
+	written, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, data, written)
+}